@@ -0,0 +1,231 @@
+// Command jtop-messages writes the template of the catalogs translating the
+// messages of jtop, in the PO format of gettext, from its source code:
+//
+//	jtop-messages pkg/ui cmd/jtop > jtop.pot
+//	msginit -i jtop.pot -l de -o de.po
+//
+// The messages are the string constants passed to tr, Translate and exitf,
+// and the ones of the variables translated where they're displayed, like the
+// descriptions of the keys of the help screen and the help bar.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// translateFuncs are the functions whose first argument is a message.
+var translateFuncs = map[string]bool{"tr": true, "Translate": true, "exitf": true}
+
+// translatedVars are the variables of string literals translated where
+// they're displayed, by the index of the translated field of their composite
+// elements, or -1 for strings and map values.
+var translatedVars = map[string]int{
+	"KeysHelp":         1,
+	"ScreenReaderHelp": -1,
+	"meminfoLabels":    -1,
+}
+
+// message is a message and the places of the source code it appears at.
+type message struct {
+	id  string
+	pos []string
+}
+
+type extractor struct {
+	fset     *token.FileSet
+	consts   map[string]string
+	messages map[string]*message
+	order    []*message
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: jtop-messages <package directory>...")
+		os.Exit(2)
+	}
+	e := &extractor{fset: token.NewFileSet(), consts: make(map[string]string), messages: make(map[string]*message)}
+	var files []*ast.File
+	for _, dir := range os.Args[1:] {
+		pkgs, err := parser.ParseDir(e.fset, dir, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "jtop-messages:", err)
+			os.Exit(1)
+		}
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return e.fset.File(files[i].Pos()).Name() < e.fset.File(files[j].Pos()).Name()
+	})
+
+	// The constants are collected first, since they can be used before
+	// they're declared.
+	for _, file := range files {
+		e.collectConsts(file)
+	}
+	for _, file := range files {
+		ast.Inspect(file, e.inspect)
+	}
+	e.write()
+}
+
+func (e *extractor) collectConsts(file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			for i, name := range value.Names {
+				if i < len(value.Values) {
+					if s, ok := stringLit(value.Values[i]); ok {
+						e.consts[name.Name] = s
+					}
+				}
+			}
+		}
+	}
+}
+
+func (e *extractor) inspect(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.CallExpr:
+		if len(node.Args) > 0 && translateFuncs[funcName(node.Fun)] {
+			e.addExpr(node.Args[0])
+		}
+	case *ast.CompositeLit:
+		// The descriptions of the keys are translated where they're
+		// displayed.
+		if ident, ok := node.Type.(*ast.Ident); ok && ident.Name == "KeyHelp" && len(node.Elts) == 2 {
+			e.addExpr(node.Elts[1])
+		}
+	case *ast.ValueSpec:
+		for i, name := range node.Names {
+			if field, ok := translatedVars[name.Name]; ok && i < len(node.Values) {
+				if lit, ok := node.Values[i].(*ast.CompositeLit); ok {
+					e.addElements(lit, field)
+				}
+			}
+		}
+	}
+	return true
+}
+
+// addElements adds the translated field of the elements of a composite
+// literal.
+func (e *extractor) addElements(lit *ast.CompositeLit, field int) {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = kv.Value
+		}
+		if field < 0 {
+			e.addExpr(elt)
+			continue
+		}
+		if c, ok := elt.(*ast.CompositeLit); ok && field < len(c.Elts) {
+			e.addExpr(c.Elts[field])
+		}
+	}
+}
+
+// addExpr adds a message if an expression is a string literal or constant.
+func (e *extractor) addExpr(expr ast.Expr) {
+	s, ok := stringLit(expr)
+	if ident, isIdent := expr.(*ast.Ident); isIdent {
+		s, ok = e.consts[ident.Name]
+	}
+	if !ok || !hasWords(s) {
+		return
+	}
+	pos := e.fset.Position(expr.Pos())
+	where := filepath.ToSlash(pos.Filename) + ":" + strconv.Itoa(pos.Line)
+	if m, ok := e.messages[s]; ok {
+		m.pos = append(m.pos, where)
+		return
+	}
+	m := &message{id: s, pos: []string{where}}
+	e.messages[s] = m
+	e.order = append(e.order, m)
+}
+
+func (e *extractor) write() {
+	fmt.Println(`msgid ""`)
+	fmt.Println(`msgstr ""`)
+	fmt.Println(`"Content-Type: text/plain; charset=UTF-8\n"`)
+	for _, m := range e.order {
+		fmt.Println()
+		fmt.Println("#: " + strings.Join(m.pos, " "))
+		if strings.Contains(m.id, "%") {
+			fmt.Println("#, c-format")
+		}
+		writeString("msgid", m.id)
+		fmt.Println(`msgstr ""`)
+	}
+}
+
+// writeString writes a field of an entry, splitting it after its newlines
+// like gettext does.
+func writeString(field, s string) {
+	if !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") {
+		fmt.Println(field + " " + quote(s))
+		return
+	}
+	fmt.Println(field + ` ""`)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			fmt.Println(quote(line))
+		}
+	}
+}
+
+// quote quotes a string like gettext, which keeps the characters that aren't
+// ASCII as they are unlike strconv.Quote.
+func quote(s string) string {
+	return `"` + quoteReplacer.Replace(s) + `"`
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// verb matches the verbs of the format strings.
+var verb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// hasWords returns whether or not a message has something to translate
+// besides the verbs of a format string, unlike "%s: %s".
+func hasWords(s string) bool {
+	return strings.IndexFunc(verb.ReplaceAllString(s, ""), unicode.IsLetter) >= 0
+}
+
+func funcName(fun ast.Expr) string {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return ""
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
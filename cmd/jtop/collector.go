@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/mayhewj/gtop/pkg/ui"
+)
+
+// remoteCommand is the command used to run jtop on remote machines, so it
+// must be in the PATH there.
+const remoteCommand = "jtop"
+
+// restrictionsTime is how long the notice of what can't be read of /proc is
+// displayed for, see proc.Monitor.Restrictions.
+const restrictionsTime = 10 * time.Second
+
+// collector updates the Monitor, either from the proc filesystem, from a
+// recording, or from remote machines, and passes every update on to the
+// enabled outputs.
+type collector struct {
+	monitor *proc.Monitor
+	player  *proc.Player
+
+	// sampler updates the local Monitor in the background, whose Snapshots
+	// the displayed Monitor is loaded with, so slow updates don't block
+	// the event loop. It's nil when replaying or monitoring remote
+	// machines, which are updated at every tick of ticker instead.
+	sampler *proc.Sampler
+	cancel  context.CancelFunc
+	ticker  *time.Ticker
+	// updates receives the Snapshots of the sampler, or nil at every tick.
+	updates <-chan *proc.Snapshot
+
+	// remotes are the remote machines being monitored, the Monitor
+	// displayed is the one of remotes[current].
+	remotes []*proc.Remote
+	current int
+
+	// history is sampled from the displayed Monitor at every update, for
+	// the history graphs.
+	history *proc.SystemHistory
+
+	recorder *proc.Recorder
+	log      *proc.SnapshotLog
+	exporter *proc.Exporter
+	agent    *proc.Agent
+	web      *ui.WebServer
+	alerter  *proc.Alerter
+
+	// err is the error of the last update of the local Monitor, if any.
+	// The processes that could be read are still displayed.
+	err error
+
+	// restrictions is the notice of what can't be read of /proc, which is
+	// only displayed after the first update it's noticed at, until
+	// restrictionsUntil.
+	restrictions      string
+	restrictionsUntil time.Time
+}
+
+func newCollector() *collector {
+	c := &collector{monitor: newMonitor(), history: proc.NewSystemHistory()}
+	c.monitor.Pids = pidWhitelist
+	c.monitor.Users = userWhitelist
+	c.monitor.KernelThreads = kernelFlag
+	c.monitor.Container = containerFlag
+	c.monitor.Namespace = namespace
+	c.monitor.ExcludeUsers = excludeUsers
+	c.monitor.ExcludeCommand = excludeCommand
+	c.monitor.NetTraffic = netFlag
+	c.monitor.GPU = gpuFlag
+	c.monitor.Delays = latencyFlag
+	c.monitor.Numa = numaFlag
+	c.monitor.Sensors = true
+	c.monitor.Power = true
+
+	if replayFlag != "" {
+		var err error
+		if c.player, err = proc.NewPlayer(c.monitor, replayFlag); err != nil {
+			exitf("%s", err)
+		}
+		c.history.Add(c.monitor)
+		ui.HeaderSections = append([]*ui.HeaderSection{
+			{Name: "Replay", Enabled: true, Lines: c.replayLines},
+		}, ui.HeaderSections...)
+		c.startTicker()
+		return c
+	}
+
+	if remoteFlag != "" {
+		remote, err := proc.DialSSH(remoteFlag, remoteArgs())
+		if err != nil {
+			exitf("%s", err)
+		}
+		c.remotes = append(c.remotes, remote)
+	}
+	if connectFlag != "" {
+		for _, addr := range strings.Split(connectFlag, ",") {
+			c.remotes = append(c.remotes, proc.DialAgent(addr, delayFlag))
+		}
+	}
+	if len(c.remotes) > 0 {
+		for _, remote := range c.remotes {
+			remote.Monitor.KernelThreads = kernelFlag
+			remote.Monitor.Tree = treeFlag
+			remote.Monitor.SortKey = sortKey
+			remote.Monitor.Reverse = reverseFlag
+		}
+		c.monitor = c.remotes[0].Monitor
+		ui.HeaderSections = append([]*ui.HeaderSection{
+			{Name: "Remote", Enabled: true, Lines: c.remoteLines},
+		}, ui.HeaderSections...)
+	}
+
+	if recordFlag != "" {
+		var err error
+		if c.recorder, err = proc.NewRecorder(recordFlag); err != nil {
+			exitf("%s", err)
+		}
+	}
+	if logDirFlag != "" {
+		var err error
+		if c.log, err = proc.NewSnapshotLog(logDirFlag, logKeepFlag); err != nil {
+			exitf("%s", err)
+		}
+	}
+
+	if listenFlag != "" {
+		listener, err := net.Listen("tcp", listenFlag)
+		if err != nil {
+			exitf("%s", err)
+		}
+		c.exporter = &proc.Exporter{}
+		go http.Serve(listener, c.exporter)
+	}
+
+	if webFlag != "" {
+		listener, err := net.Listen("tcp", webFlag)
+		if err != nil {
+			exitf("%s", err)
+		}
+		c.web = ui.NewWebServer()
+		if verboseFlag {
+			c.web.Command = ui.CommandLine
+		}
+		go http.Serve(listener, c.web)
+	}
+
+	if agentFlag != "" {
+		var err error
+		if c.agent, err = proc.ListenAgent(agentFlag); err != nil {
+			exitf("%s", err)
+		}
+	}
+
+	if len(alertRules) > 0 {
+		c.alerter = proc.NewAlerter(alertRules)
+		if guardLog != nil {
+			c.alerter.Guard = guardLog
+		}
+	}
+
+	if len(c.remotes) > 0 {
+		c.startTicker()
+		c.Update(nil)
+	} else {
+		c.startSampler()
+		// The first update is displayed before the event loop starts.
+		c.Update(<-c.updates)
+	}
+	return c
+}
+
+// startTicker sends nil to updates at every tick, for replaying or
+// monitoring remote machines.
+func (c *collector) startTicker() {
+	c.ticker = time.NewTicker(delayFlag)
+	updates := make(chan *proc.Snapshot)
+	go func() {
+		for range c.ticker.C {
+			updates <- nil
+		}
+	}()
+	c.updates = updates
+}
+
+// startSampler starts updating the local Monitor in the background, with
+// the same options as the displayed one.
+func (c *collector) startSampler() {
+	m := proc.NewMonitor()
+	m.Pids = c.monitor.Pids
+	m.Users = c.monitor.Users
+	m.KernelThreads = c.monitor.KernelThreads
+	m.Container = c.monitor.Container
+	m.Namespace = c.monitor.Namespace
+	m.ExcludeUsers = c.monitor.ExcludeUsers
+	m.ExcludeCommand = c.monitor.ExcludeCommand
+
+	c.sampler = &proc.Sampler{Monitor: m, Interval: delayFlag, Hook: ui.UpdateColumnProviders}
+	c.sampler.Fields = c.fields()
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	c.sampler.Start(ctx)
+	c.updates = c.sampler.Snapshots()
+}
+
+// fields returns the optional data the sampler collects.
+func (c *collector) fields() proc.Fields {
+	// Sensors and batteries are cheap to read, so they're always read for
+	// S and B to show them.
+	fields := proc.FieldSensors | proc.FieldPower
+	if netFlag {
+		fields |= proc.FieldNetTraffic
+	}
+	if gpuFlag {
+		fields |= proc.FieldGPU
+	}
+	if latencyFlag {
+		fields |= proc.FieldDelays
+	}
+	if numaFlag {
+		fields |= proc.FieldNuma
+	}
+	// Listing the file descriptors of every process is only worth it when
+	// they're displayed.
+	if ui.ColumnVisible(ui.FDColumn) || c.monitor.SortKey == proc.SortByFDs {
+		fields |= proc.FieldFDs
+	}
+	if c.monitor.IRQs {
+		fields |= proc.FieldInterrupts
+	}
+	return fields
+}
+
+// SyncFields passes the options of the displayed Monitor that change what is
+// collected, such as the interrupts screen being opened, on to the sampler.
+func (c *collector) SyncFields() {
+	if c.sampler != nil {
+		c.sampler.SetFields(c.fields())
+	}
+}
+
+// Updates returns the channel to receive from before calling Update.
+func (c *collector) Updates() <-chan *proc.Snapshot {
+	return c.updates
+}
+
+// Update loads a Snapshot of the local Monitor received from Updates, or
+// advances the recording or loads the latest Snapshots of the remote
+// machines if it's nil.
+func (c *collector) Update(s *proc.Snapshot) {
+	switch {
+	case s != nil:
+		c.monitor.Load(s)
+		c.err = c.sampler.Err()
+		if c.restrictions == "" {
+			if c.restrictions = c.monitor.Restrictions(); c.restrictions != "" {
+				c.restrictionsUntil = time.Now().Add(restrictionsTime)
+			}
+		}
+		c.SyncFields()
+		c.history.Add(c.monitor)
+	case c.player != nil:
+		current, _ := c.player.Position()
+		c.player.Tick()
+		if next, _ := c.player.Position(); next != current {
+			c.history.Add(c.monitor)
+		}
+		return
+	default:
+		for i, remote := range c.remotes {
+			if s := remote.Latest(); s != nil {
+				remote.Monitor.Load(s)
+				if i == c.current {
+					c.history.Add(c.monitor)
+				}
+			}
+		}
+	}
+
+	if c.recorder != nil {
+		if err := c.recorder.Record(c.monitor); err != nil {
+			exitf("%s", err)
+		}
+	}
+	if c.log != nil {
+		// Unlike --record, the log is kept in the background, so it
+		// failing, e.g. because the disk is full, doesn't stop jtop.
+		if err := c.log.Record(c.monitor); err != nil && c.err == nil {
+			c.err = fmt.Errorf("--log-dir: %s", err)
+		}
+	}
+	if c.exporter != nil {
+		c.exporter.Update(c.monitor)
+	}
+	if c.agent != nil {
+		c.agent.Update(c.monitor)
+	}
+	if c.web != nil {
+		c.web.Update(c.monitor)
+	}
+	if c.alerter != nil {
+		c.alerter.Check(c.monitor, time.Now())
+	}
+}
+
+// Status returns the error of the last update, or "" if there was none.
+func (c *collector) Status() string {
+	if c.err == nil {
+		if time.Now().Before(c.restrictionsUntil) {
+			return c.restrictions
+		}
+		return ""
+	}
+	return c.err.Error()
+}
+
+// NextRemote switches to the Monitor of the next remote machine.
+func (c *collector) NextRemote() {
+	if len(c.remotes) == 0 {
+		return
+	}
+	c.SetRemote((c.current + 1) % len(c.remotes))
+}
+
+// SetDelay changes the delay between updates.
+func (c *collector) SetDelay(delay time.Duration) {
+	if c.sampler != nil {
+		c.sampler.SetInterval(delay)
+	} else {
+		c.ticker.Reset(delay)
+	}
+}
+
+// SetRemote switches to the Monitor of remotes[i].
+func (c *collector) SetRemote(i int) {
+	c.current = i
+	c.monitor = c.remotes[i].Monitor
+	c.history.Reset()
+}
+
+// newMonitor returns a Monitor with the display options set from the flags.
+func newMonitor() *proc.Monitor {
+	m := proc.NewMonitor()
+	m.Tree = treeFlag
+	m.SortKey = sortKey
+	m.Reverse = reverseFlag
+	return m
+}
+
+// remoteArgs returns the command run on the machine passed to --remote. The
+// filtering options are passed on so only the relevant processes are sent.
+func remoteArgs() []string {
+	args := []string{remoteCommand, "--headless", "--record", "-", "--delay", delayFlag.String()}
+	if kernelFlag {
+		args = append(args, "--kernel")
+	}
+	if pidsFlag != "" {
+		args = append(args, "--pids", pidsFlag)
+	}
+	if usersFlag != "" {
+		args = append(args, "--users", usersFlag)
+	}
+	if containerFlag != "" {
+		args = append(args, "--container", containerFlag)
+	}
+	if namespaceFlag != "" {
+		args = append(args, "--namespace", namespaceFlag)
+	}
+	if excludeUsersFlag != "" {
+		args = append(args, "--exclude-users", excludeUsersFlag)
+	}
+	if excludeCommandFlag != "" {
+		args = append(args, "--exclude-command", excludeCommandFlag)
+	}
+	if netFlag {
+		args = append(args, "--net")
+	}
+	if gpuFlag {
+		args = append(args, "--gpu")
+	}
+	if latencyFlag {
+		args = append(args, "--latency")
+	}
+	return args
+}
+
+func (c *collector) replayLines(m *proc.Monitor) []string {
+	state := "playing"
+	if c.player.Paused {
+		state = "paused"
+	}
+	current, total := c.player.Position()
+	return []string{fmt.Sprintf("Replay %d/%d  %s  %s  (space: play/pause, ,/.: step)",
+		current+1, total, c.player.Current().Time.Format("2006-01-02 15:04:05"), state)}
+}
+
+func (c *collector) remoteLines(m *proc.Monitor) []string {
+	remote := c.remotes[c.current]
+	line := fmt.Sprintf(ui.Translate("Remote %s  %s"), remote.Name, ui.RemoteStatus(remote))
+	if len(c.remotes) > 1 {
+		line += fmt.Sprintf(ui.Translate("  (%d/%d, H: next host, M: all hosts)"), c.current+1, len(c.remotes))
+	}
+	return []string{line}
+}
+
+func (c *collector) Close() {
+	if c.sampler != nil {
+		// The local Monitor is closed once the sampler stopped updating
+		// it, when its channel is closed.
+		c.cancel()
+		for range c.updates {
+		}
+		c.sampler.Monitor.Close()
+	}
+	if c.recorder != nil {
+		c.recorder.Close()
+	}
+	if c.log != nil {
+		c.log.Close()
+	}
+	c.monitor.Close()
+}
@@ -0,0 +1,924 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/mayhewj/gtop/pkg/ui"
+	"github.com/nsf/termbox-go"
+)
+
+const usage = `Usage: jtop [options]
+
+Options:
+      --agent      serve snapshots to --connect on the specified address
+      --aggregate  group the processes with the same name (a toggles)
+      --aggregate-units
+                   group the processes by systemd unit (A toggles)
+      --alerts     check processes against the rules of the specified file
+  -c, --command    filter by command line (regular expression, c toggles)
+      --columns    comma-separated list of columns to display
+      --connect    monitor agents (comma-separated list of addresses)
+      --container  filter by container name or ID
+  -d, --delay      set delay between updates
+      --exclude-command
+                   hide the processes whose command line matches a regexp
+      --exclude-users
+                   hide the processes of users (comma-separated list)
+      --filter     only show the processes matching an expression (\ edits)
+      --format     process table format for --headless and e (csv or tsv)
+      --gpu        show the GPU usage of each process and GPU
+      --guard      kill processes with the kill rules of --alerts, appending
+                   what's done to the specified file
+      --header     header sections to display (comma-separated list of Memory,
+                   Network, Disk, GPU, Sensors, Battery, Pressure and NUMA)
+      --headless   no UI (use with --agent, --format, --listen, --log-dir,
+                   --record or --web)
+      --hide-idle  hide the idle processes (r toggles)
+      --idle-cpu   %CPU below which processes are idle (default 0.5)
+      --idle-io    disk I/O per second up to which processes are idle, like
+                   64K (default 0)
+      --keep-columns
+                   scroll the columns that don't fit in the terminal rather
+                   than dropping the least important ones
+  -k, --kernel     show kernel threads
+      --keys       bind keys with the specified file (default ~/.config/jtop/keys)
+      --lang       language of the messages (default from the locale), see
+                   ~/.config/jtop/locale or /usr/share/jtop/locale
+      --latency    show how long each process waits for a CPU and for disk I/O
+      --listen     serve Prometheus metrics on the specified address
+      --log-dir    append a compressed snapshot to a file of the specified
+                   directory at every update, a file per hour (--replay
+                   replays the directory)
+      --log-keep   how long --log-dir keeps the files for (default 24h)
+      --meminfo    fields of /proc/meminfo displayed in the header, R toggles
+                   (comma-separated list, default MemAvailable,AnonPages,
+                   Cached,Shmem,HugePages_Total,HugePages_Free)
+      --namespace  filter by namespace inode number (e.g. net:[4026531840])
+      --net        show the network traffic of each process (requires root)
+      --numa       show the NUMA node holding the memory of each process
+  -p, --pids       filter by PID (comma-separated list)
+      --plugins    add a column for each executable of the specified directory
+                   (default ~/.config/jtop/columns)
+  -P, --profile    start with the options of the specified profile of
+                   ~/.config/jtop/profiles (Ctrl-O switches profiles)
+      --profile-time
+                   how long p profiles the selected process for (default 5s)
+      --record     record every update to the specified file (- for stdout)
+      --remote     monitor user@host over ssh (jtop must be in the PATH there)
+      --replay     replay a file written by --record
+  -r, --reverse    reverse the sort order
+      --screen-reader
+                   describe the system and the processes as labeled lines of
+                   text, answering commands typed at a prompt (help lists
+                   them), for terminal screen readers
+      --sensors    show temperatures and fan speeds (S toggles)
+  -s, --sort       sort by the specified column
+      --temp-crit  temperature in °C displayed as critical (default 90)
+      --temp-warn  temperature in °C displayed as a warning (default 70)
+      --terminal   terminal library (termbox, or tcell by default when built in)
+      --theme      color theme (default, solarized or monochrome)
+      --thousands  separate the thousands of PIDs and counters like the locale
+      --tracer     command s runs with the selected PID (default strace -f -p)
+  -t, --tree       display process list as tree
+      --units      units of sizes (short, binary for KiB or decimal for kB)
+  -u, --users      filter by User (comma-separated list)
+      --verbose    show full command line with arguments
+      --watch      pin the PIDs and commands of the specified file above the
+                   table, saving them there (default ~/.config/jtop/watch)
+      --web        serve a live view of the process table to browsers on the
+                   specified address (e.g. :8080)
+`
+
+var (
+	agentFlag          string
+	aggregateFlag      bool
+	aggregateUnitsFlag bool
+	alertsFlag         string
+	columnsFlag        string
+	commandFlag        string
+	connectFlag        string
+	containerFlag      string
+	delayFlag          time.Duration
+	excludeCommandFlag string
+	excludeUsersFlag   string
+	filterFlag         string
+	formatFlag         string
+	gpuFlag            bool
+	guardFlag          string
+	headerFlag         string
+	headlessFlag       bool
+	hideIdleFlag       bool
+	idleCPUFlag        float64
+	idleIOFlag         string
+	keepColumnsFlag    bool
+	kernelFlag         bool
+	keysFlag           string
+	langFlag           string
+	latencyFlag        bool
+	listenFlag         string
+	logDirFlag         string
+	logKeepFlag        time.Duration
+	meminfoFlag        string
+	namespaceFlag      string
+	netFlag            bool
+	numaFlag           bool
+	pidsFlag           string
+	pluginsFlag        string
+	profileFlag        string
+	profileTimeFlag    time.Duration
+	recordFlag         string
+	remoteFlag         string
+	replayFlag         string
+	reverseFlag        bool
+	screenReaderFlag   bool
+	sensorsFlag        bool
+	sortFlag           string
+	tempCritFlag       float64
+	tempWarnFlag       float64
+	terminalFlag       string
+	themeFlag          string
+	thousandsFlag      bool
+	tracerFlag         string
+	treeFlag           bool
+	unitsFlag          string
+	usersFlag          string
+	verboseFlag        bool
+	watchFlag          string
+	webFlag            string
+)
+
+// The following are parsed from the flags by validateFlags.
+var (
+	pidWhitelist   []uint64
+	userWhitelist  []*user.User
+	alertRules     []*proc.AlertRule
+	guardLog       *os.File
+	namespace      uint64
+	excludeCommand *regexp.Regexp
+	excludeUsers   []*user.User
+	sortKey        proc.SortKey
+)
+
+// terminal is the Terminal the UI is drawn on, once it's initialized.
+var terminal ui.Terminal
+
+// minDelay is the shortest delay between updates the - key can set.
+const minDelay = 100 * time.Millisecond
+
+// defaultConfigPath returns the path of a file of the configuration
+// directory of jtop, ~/.config/jtop by default.
+func defaultConfigPath(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "jtop", name)
+}
+
+func exitf(format string, a ...interface{}) {
+	if terminal != nil {
+		terminal.Close()
+	}
+	fmt.Fprintf(os.Stderr, "jtop: "+ui.Translate(format)+"\n", a...)
+	os.Exit(1)
+}
+
+// shutdownSignals are the signals jtop quits on after restoring the terminal
+// and closing the recording and the log, like for SIGHUP when the SSH session
+// drops, rather than dying with the terminal in raw mode and the last
+// snapshots lost in the buffers.
+var shutdownSignals = []os.Signal{syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT}
+
+// shutdown receives the shutdownSignals, which the loop of every mode waits
+// for along with the updates.
+var shutdown = make(chan os.Signal, 1)
+
+// exitSignal quits after a shutdown signal, with the exit status of a
+// process killed by it.
+func exitSignal(collector *collector, sig os.Signal) {
+	if terminal != nil {
+		terminal.Close()
+	}
+	collector.Close()
+	os.Exit(128 + int(sig.(syscall.Signal)))
+}
+
+func validateAlertsFlag() {
+	if alertsFlag == "" {
+		return
+	}
+	if replayFlag != "" {
+		exitf("--alerts and --replay can't be used together")
+	}
+	f, err := os.Open(alertsFlag)
+	if err != nil {
+		exitf("%s", err)
+	}
+	defer f.Close()
+	if alertRules, err = proc.ParseAlertRules(f); err != nil {
+		exitf("%s: %s", alertsFlag, err)
+	}
+}
+
+func validateGuardFlag() {
+	if guardFlag == "" {
+		return
+	}
+	if alertsFlag == "" {
+		exitf("--guard requires --alerts")
+	}
+	if remoteFlag != "" || connectFlag != "" {
+		exitf("--guard can't be used with --remote or --connect")
+	}
+	f, err := os.OpenFile(guardFlag, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		exitf("%s", err)
+	}
+	guardLog = f
+}
+
+// shortFlags are the long names of the flags that have a short name.
+var shortFlags = map[string]string{
+	"c": "command",
+	"d": "delay",
+	"k": "kernel",
+	"p": "pids",
+	"P": "profile",
+	"r": "reverse",
+	"s": "sort",
+	"t": "tree",
+	"u": "users",
+}
+
+// validateProfileFlag reads the profiles file, and sets the flags of the
+// profile to start with that aren't on the command line.
+func validateProfileFlag() {
+	path := defaultConfigPath("profiles")
+	if _, err := os.Stat(path); err == nil {
+		profiles, err := ui.ReadProfiles(path)
+		if err != nil {
+			exitf("%s", err)
+		}
+		for _, p := range profiles {
+			for _, option := range p.Options {
+				if _, ok := shortFlags[option.Name]; ok || option.Name == "profile" || flag.Lookup(option.Name) == nil {
+					exitf("%s: profile %s: unknown option %s", path, p.Name, option.Name)
+				}
+			}
+		}
+		ui.Profiles = profiles
+	}
+	if profileFlag == "" {
+		return
+	}
+
+	p := ui.ProfileByName(profileFlag)
+	if p == nil {
+		var names []string
+		for _, p := range ui.Profiles {
+			names = append(names, p.Name)
+		}
+		exitf("unknown profile %s (%s in %s)", profileFlag, strings.Join(names, ", "), path)
+	}
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		if name, ok := shortFlags[f.Name]; ok {
+			explicit[name] = true
+		}
+		explicit[f.Name] = true
+	})
+	for _, option := range p.Options {
+		if explicit[option.Name] {
+			continue
+		}
+		if err := flag.Set(option.Name, option.Value); err != nil {
+			exitf("profile %s: invalid value %q for --%s: %s", p.Name, option.Value, option.Name, err)
+		}
+	}
+}
+
+func validateProfileTimeFlag() {
+	if profileTimeFlag <= 0 {
+		exitf("--profile-time (%s) must be positive", profileTimeFlag)
+	}
+	ui.ProfileDuration = profileTimeFlag
+}
+
+func validatePluginsFlag() {
+	dir := pluginsFlag
+	if dir == "" {
+		dir = defaultConfigPath("columns")
+		if _, err := os.Stat(dir); err != nil {
+			return
+		}
+	}
+	if err := ui.AddColumnScripts(dir); err != nil {
+		exitf("%s", err)
+	}
+}
+
+func validateColumnsFlag() {
+	columns, err := ui.ParseColumns(columnsFlag)
+	if err != nil {
+		exitf("%s", err)
+	}
+	ui.Columns = columns
+
+	// The columns of the enabled options are shown, unless they're already
+	// listed.
+	if netFlag {
+		addColumns(ui.NetRxColumn, ui.NetTxColumn)
+	}
+	if gpuFlag {
+		addColumns(ui.GPUPercentColumn, ui.GPUMemoryColumn)
+		ui.GPUSection.Enabled = true
+	}
+	if latencyFlag {
+		addColumns(ui.CPUWaitColumn, ui.IOWaitColumn)
+	}
+	if numaFlag {
+		addColumns(ui.NumaColumn)
+	}
+}
+
+// addColumns appends the columns that aren't displayed yet, keeping COMMAND
+// last.
+func addColumns(columns ...*ui.Column) {
+	for _, column := range columns {
+		if !ui.ColumnVisible(column) {
+			ui.Columns = ui.CommandLast(append(ui.Columns, column))
+		}
+	}
+}
+
+func validateMeminfoFlag() {
+	var fields []string
+	for _, field := range strings.Split(meminfoFlag, ",") {
+		if field = strings.TrimSuffix(strings.TrimSpace(field), ":"); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	ui.MeminfoFields = fields
+}
+
+func validateDelayFlag() {
+	if delayFlag <= 0 {
+		exitf("delay (%s) must be positive", delayFlag)
+	}
+}
+
+func validateSensorsFlags() {
+	if tempWarnFlag > tempCritFlag {
+		exitf("--temp-warn (%g) must not be above --temp-crit (%g)", tempWarnFlag, tempCritFlag)
+	}
+	ui.TempWarning, ui.TempCritical = tempWarnFlag, tempCritFlag
+	ui.SensorsSection.Enabled = sensorsFlag
+}
+
+func validateIdleFlags() {
+	if idleCPUFlag < 0 {
+		exitf("--idle-cpu (%g) must not be negative", idleCPUFlag)
+	}
+	io, err := proc.ParseNumber(idleIOFlag)
+	if err != nil || io < 0 {
+		exitf("invalid --idle-io %s", idleIOFlag)
+	}
+	ui.HideIdle, ui.IdleCPU, ui.IdleIO = hideIdleFlag, idleCPUFlag, io
+}
+
+func validateHeaderFlag() {
+	if headerFlag == "" {
+		return
+	}
+	if err := ui.SetHeaderSections(headerFlag); err != nil {
+		exitf("%s", err)
+	}
+}
+
+// initLanguage translates the messages to the language of the locale, before
+// the flags are parsed so their errors are translated too. Without a catalog
+// for it, the messages are in English.
+func initLanguage() {
+	if dir := defaultConfigPath("locale"); dir != "" {
+		ui.LocaleDirs = append([]string{dir}, ui.LocaleDirs...)
+	}
+	ui.SetLanguage(ui.DetectLanguage())
+}
+
+func validateLangFlag() {
+	if langFlag == "" {
+		return
+	}
+	if err := ui.SetLanguage(langFlag); err != nil {
+		exitf("%s", err)
+	}
+}
+
+func validateThemeFlag() {
+	if themeFlag == "" {
+		themeFlag = "default"
+		if ui.NoColor() {
+			themeFlag = "monochrome"
+		}
+	}
+	if _, ok := ui.Themes[themeFlag]; !ok {
+		exitf("unknown theme %s (%s)", themeFlag, strings.Join(ui.ThemeNames(), ", "))
+	}
+}
+
+func validateTerminalFlag() {
+	if terminalFlag == "" {
+		return
+	}
+	if _, ok := ui.Terminals[terminalFlag]; !ok {
+		exitf("unknown terminal %s (%s)", terminalFlag, strings.Join(ui.TerminalNames(), ", "))
+	}
+}
+
+func validateUnitsFlags() {
+	if err := ui.SetUnits(unitsFlag); err != nil {
+		exitf("%s", err)
+	}
+	if thousandsFlag {
+		ui.SetThousandsSeparator()
+	}
+}
+
+func validateCommandFlag() {
+	if commandFlag == "" {
+		return
+	}
+	re, err := regexp.Compile(commandFlag)
+	if err != nil {
+		exitf("invalid command pattern: %s", err)
+	}
+	ui.CommandPattern = re
+}
+
+func validateExcludeFlags() {
+	if excludeCommandFlag != "" {
+		re, err := regexp.Compile(excludeCommandFlag)
+		if err != nil {
+			exitf("invalid --exclude-command pattern: %s", err)
+		}
+		excludeCommand = re
+	}
+	if excludeUsersFlag != "" && remoteFlag == "" && connectFlag == "" {
+		excludeUsers = lookupUsers(excludeUsersFlag)
+	}
+}
+
+func validateFilterFlag() {
+	if filterFlag == "" {
+		return
+	}
+	filter, err := proc.ParseFilter(filterFlag)
+	if err != nil {
+		exitf("invalid filter: %s", err)
+	}
+	ui.Filter = filter
+}
+
+func validateNamespaceFlag() {
+	if namespaceFlag == "" {
+		return
+	}
+	inode, err := proc.ParseNamespace(namespaceFlag)
+	if err != nil {
+		exitf("%s is not a valid namespace", namespaceFlag)
+	}
+	namespace = inode
+}
+
+func validatePidsFlag() {
+	if pidsFlag == "" {
+		return
+	}
+
+	pids := strings.Split(pidsFlag, ",")
+	for _, value := range pids {
+		if pid, err := proc.ParseUint64(value); err != nil {
+			exitf("%s is not a valid PID", value)
+		} else {
+			pidWhitelist = append(pidWhitelist, pid)
+		}
+	}
+}
+
+func validateOutputFlags() {
+	if recordFlag != "" && replayFlag != "" {
+		exitf("--record and --replay can't be used together")
+	}
+	if logDirFlag != "" && replayFlag != "" {
+		exitf("--log-dir and --replay can't be used together")
+	}
+	if logKeepFlag <= 0 {
+		exitf("--log-keep (%s) must be positive", logKeepFlag)
+	}
+	if headlessFlag && agentFlag == "" && formatFlag == "" && listenFlag == "" && logDirFlag == "" && recordFlag == "" && webFlag == "" {
+		exitf("--headless requires --agent, --format, --listen, --log-dir, --record or --web")
+	}
+	if formatFlag != "" {
+		if _, ok := ui.Formats[formatFlag]; !ok {
+			exitf("%s is not a valid format", formatFlag)
+		}
+		if headlessFlag && recordFlag == "-" {
+			exitf("--format and --record - can't be used together in headless mode")
+		}
+	}
+	if headlessFlag && replayFlag != "" {
+		exitf("--headless and --replay can't be used together")
+	}
+	if headlessFlag && screenReaderFlag {
+		exitf("--headless and --screen-reader can't be used together")
+	}
+	if (remoteFlag != "" || connectFlag != "") && (replayFlag != "" || headlessFlag) {
+		exitf("--remote and --connect can't be used with --replay or --headless")
+	}
+}
+
+func validateSortFlag() {
+	if column := ui.ColumnByTitle(sortFlag); column != nil && column.Sort != proc.SortByNone {
+		sortKey = column.Sort
+		return
+	}
+	exitf("%s is not a valid sort column", sortFlag)
+}
+
+func validateUsersFlag() {
+	if usersFlag == "" || remoteFlag != "" || connectFlag != "" {
+		// The users of a remote machine are validated there.
+		return
+	}
+
+	userWhitelist = lookupUsers(usersFlag)
+}
+
+func validateWatchFlag() {
+	ui.WatchFile = watchFlag
+	if watchFlag == "" {
+		// The default file is created once processes are watched.
+		ui.WatchFile = defaultConfigPath("watch")
+		if _, err := os.Stat(ui.WatchFile); err != nil {
+			return
+		}
+	}
+	if err := ui.ReadWatchFile(ui.WatchFile); err != nil {
+		exitf("%s", err)
+	}
+}
+
+// lookupUsers looks up a comma-separated list of users. Uids are accepted
+// too, for the users that have no name, like the ones of containers.
+func lookupUsers(usernames string) []*user.User {
+	var users []*user.User
+	for _, username := range strings.Split(usernames, ",") {
+		if user, err := user.Lookup(username); err == nil {
+			users = append(users, user)
+		} else if _, err := strconv.ParseUint(username, 10, 32); err == nil {
+			users = append(users, proc.UserByUid(username))
+		} else {
+			exitf("user %s does not exist", username)
+		}
+	}
+	return users
+}
+
+func validateFlags() {
+	validateProfileFlag()
+	validateLangFlag()
+	validateAlertsFlag()
+	validateGuardFlag()
+	validateKeysFlag()
+	validateMeminfoFlag()
+	validatePluginsFlag()
+	validateColumnsFlag()
+	validateCommandFlag()
+	validateDelayFlag()
+	validateExcludeFlags()
+	validateFilterFlag()
+	validateNamespaceFlag()
+	validatePidsFlag()
+	validateProfileTimeFlag()
+	validateOutputFlags()
+	validateSensorsFlags()
+	validateHeaderFlag()
+	validateIdleFlags()
+	validateThemeFlag()
+	validateTerminalFlag()
+	validateUnitsFlags()
+	validateSortFlag()
+	validateUsersFlag()
+	validateWatchFlag()
+}
+
+func init() {
+	flag.StringVar(&agentFlag, "agent", "", "")
+
+	flag.BoolVar(&aggregateFlag, "aggregate", false, "")
+
+	flag.BoolVar(&aggregateUnitsFlag, "aggregate-units", false, "")
+
+	flag.StringVar(&alertsFlag, "alerts", "", "")
+
+	flag.StringVar(&commandFlag, "c", "", "")
+	flag.StringVar(&commandFlag, "command", "", "")
+
+	flag.StringVar(&columnsFlag, "columns", ui.DefaultColumns, "")
+
+	flag.StringVar(&connectFlag, "connect", "", "")
+
+	flag.StringVar(&containerFlag, "container", "", "")
+
+	defaultDelay := time.Duration(1500 * time.Millisecond)
+	flag.DurationVar(&delayFlag, "d", defaultDelay, "")
+	flag.DurationVar(&delayFlag, "delay", defaultDelay, "")
+
+	flag.StringVar(&excludeCommandFlag, "exclude-command", "", "")
+	flag.StringVar(&excludeUsersFlag, "exclude-users", "", "")
+
+	flag.StringVar(&filterFlag, "filter", "", "")
+
+	flag.StringVar(&formatFlag, "format", "", "")
+
+	flag.BoolVar(&gpuFlag, "gpu", false, "")
+
+	flag.StringVar(&guardFlag, "guard", "", "")
+
+	flag.StringVar(&headerFlag, "header", "", "")
+
+	flag.BoolVar(&headlessFlag, "headless", false, "")
+
+	flag.BoolVar(&hideIdleFlag, "hide-idle", false, "")
+	flag.Float64Var(&idleCPUFlag, "idle-cpu", 0.5, "")
+	flag.StringVar(&idleIOFlag, "idle-io", "0", "")
+
+	flag.BoolVar(&keepColumnsFlag, "keep-columns", false, "")
+
+	flag.BoolVar(&kernelFlag, "k", false, "")
+	flag.BoolVar(&kernelFlag, "kernel", false, "")
+
+	flag.StringVar(&keysFlag, "keys", "", "")
+
+	flag.StringVar(&langFlag, "lang", "", "")
+
+	flag.BoolVar(&latencyFlag, "latency", false, "")
+
+	flag.StringVar(&listenFlag, "listen", "", "")
+
+	flag.StringVar(&logDirFlag, "log-dir", "", "")
+	flag.DurationVar(&logKeepFlag, "log-keep", 24*time.Hour, "")
+
+	flag.StringVar(&meminfoFlag, "meminfo", strings.Join(ui.MeminfoFields, ","), "")
+
+	flag.StringVar(&namespaceFlag, "namespace", "", "")
+
+	flag.BoolVar(&netFlag, "net", false, "")
+
+	flag.BoolVar(&numaFlag, "numa", false, "")
+
+	flag.StringVar(&pidsFlag, "p", "", "")
+	flag.StringVar(&pidsFlag, "pids", "", "")
+
+	flag.StringVar(&pluginsFlag, "plugins", "", "")
+
+	flag.StringVar(&profileFlag, "P", "", "")
+	flag.StringVar(&profileFlag, "profile", "", "")
+
+	flag.DurationVar(&profileTimeFlag, "profile-time", 5*time.Second, "")
+
+	flag.StringVar(&recordFlag, "record", "", "")
+
+	flag.StringVar(&remoteFlag, "remote", "", "")
+
+	flag.StringVar(&replayFlag, "replay", "", "")
+
+	flag.BoolVar(&reverseFlag, "r", false, "")
+	flag.BoolVar(&reverseFlag, "reverse", false, "")
+
+	flag.BoolVar(&screenReaderFlag, "screen-reader", false, "")
+
+	defaultSort := ui.CPUPercentColumn.Title
+	flag.BoolVar(&sensorsFlag, "sensors", false, "")
+
+	flag.StringVar(&sortFlag, "s", defaultSort, "")
+	flag.StringVar(&sortFlag, "sort", defaultSort, "")
+
+	flag.Float64Var(&tempCritFlag, "temp-crit", 90, "")
+	flag.Float64Var(&tempWarnFlag, "temp-warn", 70, "")
+
+	flag.StringVar(&terminalFlag, "terminal", "", "")
+
+	flag.StringVar(&themeFlag, "theme", "", "")
+
+	flag.BoolVar(&thousandsFlag, "thousands", false, "")
+
+	flag.StringVar(&tracerFlag, "tracer", defaultTracer, "")
+
+	flag.BoolVar(&treeFlag, "t", false, "")
+	flag.BoolVar(&treeFlag, "tree", false, "")
+
+	flag.StringVar(&unitsFlag, "units", "short", "")
+
+	flag.StringVar(&usersFlag, "u", "", "")
+	flag.StringVar(&usersFlag, "users", "", "")
+
+	flag.BoolVar(&verboseFlag, "verbose", false, "")
+
+	flag.StringVar(&watchFlag, "watch", "", "")
+
+	flag.StringVar(&webFlag, "web", "", "")
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stdout, ui.Translate(usage))
+	}
+}
+
+// resumeTerminal initializes the terminal again after it was suspended.
+func resumeTerminal() {
+	if err := terminal.Resume(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// initTerminal initializes the --terminal Terminal, or the default one and
+// then termbox if it can't be, and sets the theme of the UI.
+func initTerminal() error {
+	name := terminalFlag
+	if name == "" {
+		name = ui.DefaultTerminal
+	}
+	t, err := ui.NewTerminal(name)
+	if err != nil {
+		return err
+	}
+	err = t.Init()
+	if err != nil && terminalFlag == "" && name != "termbox" {
+		t, _ = ui.NewTerminal("termbox")
+		err = t.Init()
+	}
+	if err != nil {
+		return err
+	}
+	terminal = t
+	ui.SetTerminal(t)
+	ui.SetTheme(themeFlag, ui.Supports256Colors())
+	return nil
+}
+
+func main() {
+	initLanguage()
+	flag.Parse()
+	validateFlags()
+	if aggregateFlag {
+		ui.Aggregate = proc.GroupByName
+	}
+	if aggregateUnitsFlag {
+		ui.Aggregate = proc.GroupByUnit
+	}
+
+	collector := newCollector()
+	defer collector.Close()
+	signal.Notify(shutdown, shutdownSignals...)
+
+	if headlessFlag {
+		var status string
+		for {
+			select {
+			case s := <-collector.Updates():
+				collector.Update(s)
+			case sig := <-shutdown:
+				exitSignal(collector, sig)
+			}
+			if formatFlag != "" {
+				if err := ui.WriteTable(os.Stdout, collector.monitor, formatFlag, verboseFlag); err != nil {
+					exitf("%s", err)
+				}
+			}
+			// Only report an error once, rather than on every update.
+			if s := collector.Status(); s != status {
+				if s != "" {
+					fmt.Fprintln(os.Stderr, "jtop: "+s)
+				}
+				status = s
+			}
+		}
+	}
+
+	if screenReaderFlag {
+		runScreenReader(collector)
+		return
+	}
+
+	if err := initTerminal(); err != nil {
+		runPlain(collector, err)
+		return
+	}
+	defer terminal.Close()
+
+	events := make(chan termbox.Event)
+	go func() {
+		for {
+			events <- terminal.PollEvent()
+		}
+	}()
+
+	tui := ui.NewUI(collector.monitor, collector.history)
+	if verboseFlag {
+		tui.Command = ui.CommandLine
+	}
+	tui.KeepColumns = keepColumnsFlag
+	tui.ReadOnly = collector.player != nil || len(collector.remotes) > 0
+	tui.SetStartupProfile(profileFlag)
+	tui.Alerter = collector.alerter
+	tui.SetStatus(collector.Status())
+
+	keys := &keyHandler{tui: tui, collector: collector, bindings: bindings}
+
+	// handleEvent handles a termbox event, returning true when jtop should
+	// quit.
+	handleEvent := func(ev termbox.Event) bool {
+		if ev.Type == termbox.EventKey && tui.Prompting() {
+			tui.HandlePromptKey(ev)
+		} else if ev.Type == termbox.EventKey && tui.ScreenActive() {
+			tui.HandleScreenKey(ev)
+		} else if ev.Type == termbox.EventKey {
+			return keys.handleKey(ev)
+		} else if ev.Type == termbox.EventMouse && !tui.ScreenActive() {
+			switch ev.Key {
+			case termbox.MouseLeft:
+				tui.HandleClick(ev.MouseX, ev.MouseY)
+			case termbox.MouseWheelDown:
+				tui.HandleWheelDown()
+			case termbox.MouseWheelUp:
+				tui.HandleWheelUp()
+			}
+		} else if ev.Type == termbox.EventResize {
+			tui.HandleResize(ev.Width, ev.Height)
+		}
+		return false
+	}
+
+	for {
+		tui.Draw()
+
+		select {
+		case s := <-collector.Updates():
+			collector.Update(s)
+			tui.SetStatus(collector.Status())
+
+		case sig := <-shutdown:
+			exitSignal(collector, sig)
+
+		case ev := <-events:
+			if handleEvent(ev) {
+				return
+			}
+			// Handle the events that arrived while drawing before
+			// drawing again, so the display keeps up with held down
+			// keys and the mouse wheel.
+			for pending := true; pending; {
+				select {
+				case ev := <-events:
+					if handleEvent(ev) {
+						return
+					}
+				default:
+					pending = false
+				}
+			}
+			// The keys may have changed what has to be collected.
+			collector.SyncFields()
+		}
+	}
+}
+
+// exportFormat returns the format the e key exports the process table in.
+func exportFormat() string {
+	if formatFlag == "" {
+		return "csv"
+	}
+	return formatFlag
+}
+
+// setDelay changes the delay between updates, down to minDelay.
+func setDelay(collector *collector, tui *ui.UI, delay time.Duration) {
+	if delay < minDelay {
+		delay = minDelay
+	}
+	delayFlag = delay
+	collector.SetDelay(delay)
+	tui.SetStatus(fmt.Sprintf(ui.Translate("Delay: %s"), delay))
+}
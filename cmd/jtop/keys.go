@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/mayhewj/gtop/pkg/ui"
+	"github.com/nsf/termbox-go"
+)
+
+// key is a key of a termbox event, either a rune or a special key.
+type key struct {
+	ch  rune
+	key termbox.Key
+}
+
+// keyNames are the names of the special keys in the keys file, lowercase.
+var keyNames = map[string]termbox.Key{
+	"f1": termbox.KeyF1, "f2": termbox.KeyF2, "f3": termbox.KeyF3, "f4": termbox.KeyF4,
+	"f5": termbox.KeyF5, "f6": termbox.KeyF6, "f7": termbox.KeyF7, "f8": termbox.KeyF8,
+	"f9": termbox.KeyF9, "f10": termbox.KeyF10, "f11": termbox.KeyF11, "f12": termbox.KeyF12,
+	"up": termbox.KeyArrowUp, "down": termbox.KeyArrowDown,
+	"left": termbox.KeyArrowLeft, "right": termbox.KeyArrowRight,
+	"home": termbox.KeyHome, "end": termbox.KeyEnd,
+	"pgup": termbox.KeyPgup, "pgdn": termbox.KeyPgdn,
+	"insert": termbox.KeyInsert, "delete": termbox.KeyDelete,
+	"backspace": termbox.KeyBackspace2,
+	"enter":     termbox.KeyEnter,
+	"tab":       termbox.KeyTab,
+	"space":     termbox.KeySpace,
+	"esc":       termbox.KeyEsc,
+}
+
+// parseKey parses the name of a key: a single character, a special key like
+// F5 or pgdn, or ctrl- or C- followed by a letter.
+func parseKey(name string) (key, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		ch, _ := utf8.DecodeRuneInString(name)
+		return key{ch: ch}, nil
+	}
+	lower := strings.ToLower(name)
+	if k, ok := keyNames[lower]; ok {
+		return key{key: k}, nil
+	}
+	for _, prefix := range []string{"ctrl-", "c-"} {
+		rest := strings.TrimPrefix(lower, prefix)
+		if rest != lower && len(rest) == 1 && rest[0] >= 'a' && rest[0] <= 'z' {
+			return key{key: termbox.KeyCtrlA + termbox.Key(rest[0]-'a')}, nil
+		}
+	}
+	return key{}, fmt.Errorf("unknown key %s", name)
+}
+
+// keyHandler runs the actions bound to the keys of the process table.
+type keyHandler struct {
+	tui       *ui.UI
+	collector *collector
+	bindings  map[key][]string
+
+	// quit is set by the quit action.
+	quit bool
+}
+
+// actions are what keys can be bound to, by name. An action returns false
+// when it doesn't apply, e.g. stepping through a recording while monitoring
+// this machine, in which case the next action bound to the key runs.
+var actions = map[string]func(h *keyHandler) bool{
+	"quit":                   func(h *keyHandler) bool { h.quit = true; return true },
+	"help":                   func(h *keyHandler) bool { h.tui.HandleHelp(); return true },
+	"slower":                 func(h *keyHandler) bool { setDelay(h.collector, h.tui, delayFlag*2); return true },
+	"faster":                 func(h *keyHandler) bool { setDelay(h.collector, h.tui, delayFlag/2); return true },
+	"left":                   func(h *keyHandler) bool { h.tui.HandleLeft(); return true },
+	"down":                   func(h *keyHandler) bool { h.tui.HandleDown(); return true },
+	"up":                     func(h *keyHandler) bool { h.tui.HandleUp(); return true },
+	"right":                  func(h *keyHandler) bool { h.tui.HandleRight(); return true },
+	"first-column":           func(h *keyHandler) bool { h.tui.HandleResetOffset(); return true },
+	"first":                  func(h *keyHandler) bool { h.tui.HandleSelectFirst(); return true },
+	"last":                   func(h *keyHandler) bool { h.tui.HandleSelectLast(); return true },
+	"half-page-down":         func(h *keyHandler) bool { h.tui.HandleCtrlD(); return true },
+	"half-page-up":           func(h *keyHandler) bool { h.tui.HandleCtrlU(); return true },
+	"toggle-memory":          func(h *keyHandler) bool { h.tui.HandleToggleSection(ui.MemorySection); return true },
+	"toggle-disk":            func(h *keyHandler) bool { h.tui.HandleToggleSection(ui.DiskSection); return true },
+	"toggle-net":             func(h *keyHandler) bool { h.tui.HandleToggleSection(ui.NetSection); return true },
+	"toggle-sensors":         func(h *keyHandler) bool { h.tui.HandleToggleSection(ui.SensorsSection); return true },
+	"toggle-battery":         func(h *keyHandler) bool { h.tui.HandleToggleSection(ui.BatterySection); return true },
+	"toggle-pressure":        func(h *keyHandler) bool { h.tui.HandleToggleSection(ui.PressureSection); return true },
+	"toggle-log":             func(h *keyHandler) bool { h.tui.HandleToggleLog(); return true },
+	"reverse-sort":           func(h *keyHandler) bool { h.tui.HandleReverseSort(); return true },
+	"sort-left":              func(h *keyHandler) bool { h.tui.HandleSortLeft(); return true },
+	"sort-right":             func(h *keyHandler) bool { h.tui.HandleSortRight(); return true },
+	"files":                  func(h *keyHandler) bool { h.tui.HandleFiles(); return true },
+	"sockets":                func(h *keyHandler) bool { h.tui.HandleSockets(); return true },
+	"mounts":                 func(h *keyHandler) bool { h.tui.HandleMounts(); return true },
+	"trace":                  func(h *keyHandler) bool { runTracer(h.tui); return true },
+	"shell":                  func(h *keyHandler) bool { runShell(h.tui); return true },
+	"profile":                func(h *keyHandler) bool { h.tui.HandleProfile(); return true },
+	"mark-baseline":          func(h *keyHandler) bool { h.tui.HandleMarkBaseline(); return true },
+	"baseline-diff":          func(h *keyHandler) bool { h.tui.HandleBaselineDiff(); return true },
+	"cgroups":                func(h *keyHandler) bool { h.tui.HandleCgroups(); return true },
+	"users":                  func(h *keyHandler) bool { h.tui.HandleUsers(); return true },
+	"interrupts":             func(h *keyHandler) bool { h.tui.HandleInterrupts(); return true },
+	"graphs":                 func(h *keyHandler) bool { h.tui.HandleGraphs(); return true },
+	"export":                 func(h *keyHandler) bool { h.tui.HandleExport(exportFormat()); return true },
+	"freeze":                 func(h *keyHandler) bool { h.tui.HandleFreeze(); return true },
+	"tag":                    func(h *keyHandler) bool { h.tui.HandleTag(); return true },
+	"untag-all":              func(h *keyHandler) bool { h.tui.HandleUntagAll(); return true },
+	"signal":                 func(h *keyHandler) bool { h.tui.HandleSignal(); return true },
+	"renice-down":            func(h *keyHandler) bool { h.tui.HandleRenice(-1); return true },
+	"renice-up":              func(h *keyHandler) bool { h.tui.HandleRenice(1); return true },
+	"affinity":               func(h *keyHandler) bool { h.tui.HandleAffinity(); return true },
+	"oom-score-adj":          func(h *keyHandler) bool { h.tui.HandleOOMScoreAdj(); return true },
+	"io-priority":            func(h *keyHandler) bool { h.tui.HandleIOPriority(); return true },
+	"stop":                   func(h *keyHandler) bool { h.tui.HandleStop(); return true },
+	"freeze-cgroup":          func(h *keyHandler) bool { h.tui.HandleFreezeCgroup(); return true },
+	"watch":                  func(h *keyHandler) bool { h.tui.HandleWatch(); return true },
+	"watch-command":          func(h *keyHandler) bool { h.tui.HandleWatchCommand(); return true },
+	"profiles":               func(h *keyHandler) bool { h.tui.HandleProfiles(); return true },
+	"toggle-pause":           func(h *keyHandler) bool { return h.replay((*proc.Player).TogglePause) },
+	"step-back":              func(h *keyHandler) bool { return h.replay(func(p *proc.Player) { p.Step(-1) }) },
+	"step-forward":           func(h *keyHandler) bool { return h.replay(func(p *proc.Player) { p.Step(1) }) },
+	"next-remote":            func(h *keyHandler) bool { h.nextRemote(); return true },
+	"dashboard":              func(h *keyHandler) bool { h.dashboard(); return true },
+	"search":                 func(h *keyHandler) bool { h.tui.HandleSearch(); return true },
+	"next-match":             func(h *keyHandler) bool { return h.nextMatch(1) },
+	"previous-match":         func(h *keyHandler) bool { return h.nextMatch(-1) },
+	"clear-search":           func(h *keyHandler) bool { h.tui.HandleClearSearch(); return true },
+	"follow":                 func(h *keyHandler) bool { h.tui.HandleFollow(); return true },
+	"parent":                 func(h *keyHandler) bool { h.tui.HandleSelectParent(); return true },
+	"next-child":             func(h *keyHandler) bool { h.tui.HandleNextChild(); return true },
+	"filter":                 func(h *keyHandler) bool { h.tui.HandleFilter(); return true },
+	"toggle-command-pattern": func(h *keyHandler) bool { h.tui.HandleToggleCommandPattern(); return true },
+	"toggle-idle":            func(h *keyHandler) bool { h.tui.HandleToggleIdle(); return true },
+	"command-pattern":        func(h *keyHandler) bool { h.tui.HandleCommandPattern(); return true },
+	"toggle-tree":            func(h *keyHandler) bool { h.tui.HandleToggleTree(); return true },
+	"aggregate-name":         func(h *keyHandler) bool { h.tui.HandleToggleAggregate(proc.GroupByName); return true },
+	"aggregate-unit":         func(h *keyHandler) bool { h.tui.HandleToggleAggregate(proc.GroupByUnit); return true },
+	"kernel-threads-last":    func(h *keyHandler) bool { h.tui.HandleToggleKernelThreadsLast(); return true },
+	"cpu-lifetime":           func(h *keyHandler) bool { h.tui.HandleToggleCPULifetime(); return true },
+	"cpu-children":           func(h *keyHandler) bool { h.tui.HandleToggleCPUChildren(); return true },
+	"details":                func(h *keyHandler) bool { h.tui.HandleDetails(); return true },
+	"setup":                  func(h *keyHandler) bool { h.tui.HandleSetup(); return true },
+	"next-command-mode":      func(h *keyHandler) bool { h.tui.HandleNextCommandMode(); return true },
+	"highlight-program":      func(h *keyHandler) bool { h.tui.HandleToggleHighlightProgram(); return true },
+	"toggle-wrap":            func(h *keyHandler) bool { h.tui.HandleToggleWrap(); return true },
+	"suspend":                func(h *keyHandler) bool { suspend(); return true },
+	"none":                   func(h *keyHandler) bool { return true },
+}
+
+// defaultBindings are the keys of the vim keymap, which is the default, and
+// the actions they're bound to. The actions bound to a key are tried in
+// order until one applies.
+var defaultBindings = [][2]string{
+	{"q ctrl-c", "quit"},
+	{"? F1", "help"},
+	{"+ =", "slower"},
+	{"-", "faster"},
+	{"h left", "left"},
+	{"j down", "down"},
+	{"k up", "up"},
+	{"l right", "right"},
+	{"0 ^", "first-column"},
+	{"g", "first"},
+	{"G", "last"},
+	{"ctrl-d", "half-page-down"},
+	{"ctrl-u", "half-page-up"},
+	{"R", "toggle-memory"},
+	{"D", "toggle-disk"},
+	{"S", "toggle-sensors"},
+	{"B", "toggle-battery"},
+	{"P", "toggle-pressure"},
+	{"E", "toggle-log"},
+	{"I", "reverse-sort"},
+	{"<", "sort-left"},
+	{">", "sort-right"},
+	{"L", "files"},
+	{"O", "sockets"},
+	{"F", "mounts"},
+	{"s", "trace"},
+	{"!", "shell"},
+	{"p", "profile"},
+	{"m", "mark-baseline"},
+	{"b", "baseline-diff"},
+	{"o", "cgroups"},
+	{"u", "users"},
+	{"i", "interrupts"},
+	{"tab", "graphs"},
+	{"e", "export"},
+	{"Z", "freeze"},
+	{"space", "toggle-pause tag"},
+	{"insert ctrl-t", "tag"},
+	{"U", "untag-all"},
+	{"x F9", "signal"},
+	{"[ F7", "renice-down"},
+	{"] F8", "renice-up"},
+	{"y", "affinity"},
+	{"X", "oom-score-adj"},
+	{"d", "io-priority"},
+	{"z", "stop"},
+	{"Y", "freeze-cgroup"},
+	{"J", "watch"},
+	{"ctrl-w", "watch-command"},
+	{",", "step-back"},
+	{".", "step-forward"},
+	{"H", "next-remote"},
+	{"M", "dashboard"},
+	{"/", "search"},
+	{"n", "next-match toggle-net"},
+	{"N", "previous-match"},
+	{"esc", "clear-search"},
+	{"f", "follow"},
+	// P toggles the pressure header section, so the parent is {, which
+	// can be rebound in the keys file.
+	{"{", "parent"},
+	{"}", "next-child"},
+	{"\\ F4", "filter"},
+	{"c", "toggle-command-pattern"},
+	{"|", "command-pattern"},
+	{"r", "toggle-idle"},
+	{"t", "toggle-tree"},
+	{"a", "aggregate-name"},
+	{"A", "aggregate-unit"},
+	{"K", "kernel-threads-last"},
+	{"T", "cpu-lifetime"},
+	{"W", "cpu-children"},
+	{"enter", "details"},
+	{"C F2", "setup"},
+	{"ctrl-o", "profiles"},
+	{"v", "next-command-mode"},
+	{"V", "highlight-program"},
+	{"w", "toggle-wrap"},
+	{"ctrl-z", "suspend"},
+}
+
+// keymaps are the bindings the keymap line of the keys file adds to the
+// default ones, by name.
+var keymaps = map[string][][2]string{
+	"vim": nil,
+	"emacs": {
+		{"ctrl-b", "left"},
+		{"ctrl-n", "down"},
+		{"ctrl-p", "up"},
+		{"ctrl-f", "right"},
+		{"ctrl-a", "first-column"},
+		{"home", "first"},
+		{"end", "last"},
+		{"ctrl-v pgdn", "half-page-down"},
+		{"pgup", "half-page-up"},
+		{"ctrl-s", "next-match search"},
+		{"ctrl-r", "previous-match search"},
+		{"ctrl-g", "clear-search"},
+	},
+}
+
+// bindings are the key bindings of the process table, set by
+// validateKeysFlag.
+var bindings map[key][]string
+
+// bind binds keys separated by spaces to actions separated by spaces, or to
+// a single command if they start with !.
+func bind(bindings map[key][]string, keys, command string) error {
+	var names []string
+	if strings.HasPrefix(command, "!") {
+		names = []string{command}
+	} else {
+		names = strings.Fields(command)
+		for _, name := range names {
+			if _, ok := actions[name]; !ok {
+				return fmt.Errorf("unknown action %s", name)
+			}
+		}
+	}
+	for _, name := range strings.Fields(keys) {
+		k, err := parseKey(name)
+		if err != nil {
+			return err
+		}
+		bindings[k] = names
+	}
+	return nil
+}
+
+// readKeysFile binds the keys of a keys file over the default bindings, and
+// adds them to the help screen. Each line binds a key to actions, to none to
+// unbind it, or to a command starting with ! in which {pid} is replaced by
+// the PID of the selected process:
+//
+//	keymap emacs
+//	J      last
+//	s      none
+//	y      !kill -STOP {pid}
+func readKeysFile(path string, bindings map[key][]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) < 2 {
+			return fmt.Errorf("%s:%d: missing action", path, n)
+		}
+		name, command := fields[0], strings.TrimSpace(fields[1])
+		if name == "keymap" {
+			keymap, ok := keymaps[command]
+			if !ok {
+				return fmt.Errorf("%s:%d: unknown keymap %s (%s)", path, n, command, strings.Join(keymapNames(), ", "))
+			}
+			for _, binding := range keymap {
+				if err := bind(bindings, binding[0], binding[1]); err != nil {
+					return fmt.Errorf("%s:%d: %s", path, n, err)
+				}
+			}
+			continue
+		}
+		if err := bind(bindings, name, command); err != nil {
+			return fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+		ui.KeysHelp = append(ui.KeysHelp, ui.KeyHelp{Keys: name, Description: strings.TrimPrefix(command, "!")})
+	}
+	return scanner.Err()
+}
+
+// keymapNames returns the names of the keymaps, sorted.
+func keymapNames() []string {
+	var names []string
+	for name := range keymaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validateKeysFlag() {
+	bindings = make(map[key][]string)
+	for _, binding := range defaultBindings {
+		if err := bind(bindings, binding[0], binding[1]); err != nil {
+			panic(err)
+		}
+	}
+	path := keysFlag
+	if path == "" {
+		path = defaultConfigPath("keys")
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+	}
+	if err := readKeysFile(path, bindings); err != nil {
+		exitf("%s", err)
+	}
+}
+
+// handleKey runs the actions bound to the key of an event, and returns
+// true if jtop should quit.
+func (h *keyHandler) handleKey(ev termbox.Event) bool {
+	k := key{key: ev.Key}
+	if ev.Ch != 0 {
+		k = key{ch: ev.Ch}
+	}
+	for _, name := range h.bindings[k] {
+		if strings.HasPrefix(name, "!") {
+			runCommand(h.tui, name[1:])
+			break
+		}
+		if actions[name](h) {
+			break
+		}
+	}
+	return h.quit
+}
+
+func (h *keyHandler) replay(f func(p *proc.Player)) bool {
+	if h.collector.player == nil {
+		return false
+	}
+	f(h.collector.player)
+	return true
+}
+
+func (h *keyHandler) nextMatch(delta int) bool {
+	if !h.tui.Searching() {
+		return false
+	}
+	h.tui.HandleNextMatch(delta)
+	return true
+}
+
+func (h *keyHandler) nextRemote() {
+	h.collector.NextRemote()
+	h.tui.SetMonitor(h.collector.monitor)
+}
+
+func (h *keyHandler) dashboard() {
+	h.tui.HandleDashboard(h.collector.remotes, h.collector.current, func(i int) {
+		h.collector.SetRemote(i)
+		h.tui.SetMonitor(h.collector.monitor)
+	})
+}
+
+// runCommand suspends the UI to run a command of the keys file with {pid}
+// replaced by the PID of the selected process and the environment of
+// runShell, and resumes it when the command exits.
+func runCommand(tui *ui.UI, command string) {
+	process := tui.SelectedProcess()
+	if process == nil {
+		return
+	}
+	if tui.ReadOnly {
+		tui.SetStatus(ui.Translate("Commands can't be run on the processes of a recording or a remote machine"))
+		return
+	}
+
+	command = strings.ReplaceAll(command, "{pid}", strconv.FormatUint(process.Pid, 10))
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), processEnv(process)...)
+	terminal.Suspend()
+	fmt.Printf(ui.Translate("jtop: running %s, jtop resumes when it exits")+"\n", command)
+	if err := runSuspended(cmd); err != nil {
+		tui.SetStatus(fmt.Sprintf("%s: %s", command, err))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/mayhewj/gtop/pkg/ui"
+)
+
+// plainHelp is displayed below the table in plain mode, whose commands are
+// read a line at a time since the terminal isn't in raw mode.
+const plainHelp = "Enter a command: q quit  t tree  a aggregate  v commands  s <column> sort"
+
+// runPlain displays the process table without termbox, which failed to
+// initialize with err, on a dumb terminal or when the output isn't a
+// terminal. On a terminal that understands ANSI escape sequences the screen
+// is cleared before each update, on a dumb one the updates are printed one
+// after the other, and otherwise, such as under watch(1), the table is
+// printed once.
+func runPlain(collector *collector, err error) {
+	fmt.Fprintf(os.Stderr, "jtop: %s, falling back to plain output\n", err)
+
+	tty := isTerminal(os.Stdout)
+	clear := tty && os.Getenv("TERM") != "dumb"
+
+	commands := make(chan string)
+	if tty {
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				commands <- strings.TrimSpace(scanner.Text())
+			}
+		}()
+	}
+
+	mode := ui.CommandName
+	if verboseFlag {
+		mode = ui.CommandLine
+	}
+	status := ""
+	for {
+		select {
+		case s := <-collector.Updates():
+			collector.Update(s)
+			status = collector.Status()
+		case sig := <-shutdown:
+			exitSignal(collector, sig)
+		case command := <-commands:
+			var quit bool
+			if quit, status = handlePlainCommand(collector.monitor, &mode, command); quit {
+				return
+			}
+		}
+
+		width, height := terminalSize()
+		if clear {
+			fmt.Print("\x1b[H\x1b[2J")
+		}
+		if tty {
+			// Leave room for the status and the command being typed.
+			height -= 2
+		}
+		if err := ui.WritePlain(os.Stdout, collector.monitor, width, height, mode); err != nil {
+			exitf("%s", err)
+		}
+		if !tty {
+			return
+		}
+		if status == "" {
+			status = plainHelp
+		}
+		fmt.Println(status)
+		if !clear {
+			fmt.Println()
+		}
+	}
+}
+
+// handlePlainCommand runs a command read in plain mode, which v switches the
+// CommandMode of, and returns whether or not jtop should quit and the status
+// to display.
+func handlePlainCommand(m *proc.Monitor, mode *ui.CommandMode, command string) (bool, string) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false, ""
+	}
+	switch fields[0] {
+	case "q":
+		return true, ""
+	case "t":
+		m.Tree = !m.Tree
+		m.Sort()
+	case "a":
+		if ui.Aggregate == proc.Ungrouped {
+			ui.Aggregate = proc.GroupByName
+		} else {
+			ui.Aggregate = proc.Ungrouped
+		}
+	case "v":
+		*mode = (*mode + 1) % (ui.CommandLine + 1)
+		return false, "Displaying the " + mode.String()
+	case "s":
+		if len(fields) != 2 {
+			return false, "Usage: s <column>"
+		}
+		for _, column := range ui.AllColumns {
+			if strings.EqualFold(column.Title, fields[1]) && column.Sort != proc.SortByNone {
+				m.SortKey, m.Reverse = column.Sort, false
+				m.Sort()
+				return false, ""
+			}
+		}
+		return false, "Unknown column " + fields[1]
+	default:
+		return false, "Unknown command " + fields[0] + " (" + plainHelp + ")"
+	}
+	return false, ""
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/mayhewj/gtop/pkg/ui"
+)
+
+// readerPrompt is printed when the screen reader mode waits for a command.
+const readerPrompt = "jtop> "
+
+// runScreenReader runs the screen reader mode of --screen-reader, which reads
+// commands a line at a time, leaving the editing of the line to the terminal
+// and the screen reader, and prints the answers one after the other without
+// ever clearing the screen or moving the cursor.
+func runScreenReader(collector *collector) {
+	mode := ui.CommandName
+	if verboseFlag {
+		mode = ui.CommandLine
+	}
+	reader := ui.NewScreenReader(collector.monitor, mode)
+
+	commands := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			commands <- scanner.Text()
+		}
+		close(commands)
+	}()
+
+	fmt.Println("jtop screen reader mode, type help for the commands.")
+	printLines(reader.Summary())
+	fmt.Print(readerPrompt)
+
+	status := ""
+	for {
+		select {
+		case s := <-collector.Updates():
+			collector.Update(s)
+			lines := reader.Updated()
+			// Only report an error once, rather than on every update.
+			if s := collector.Status(); s != status {
+				if s != "" {
+					lines = append(lines, "Error: "+s)
+				}
+				status = s
+			}
+			if len(lines) > 0 {
+				fmt.Println()
+				printLines(lines)
+				fmt.Print(readerPrompt)
+			}
+		case sig := <-shutdown:
+			fmt.Println()
+			exitSignal(collector, sig)
+		case command, ok := <-commands:
+			if !ok {
+				fmt.Println()
+				return
+			}
+			lines, quit := reader.HandleCommand(command)
+			if quit {
+				return
+			}
+			printLines(lines)
+			fmt.Print(readerPrompt)
+		}
+	}
+}
+
+func printLines(lines []string) {
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
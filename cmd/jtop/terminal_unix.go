@@ -0,0 +1,53 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal returns whether or not a file is a terminal, which is what has
+// a window size on every Unix, unlike the termios of TCGETS which is Linux's.
+func isTerminal(f *os.File) bool {
+	_, ok := windowSize(f)
+	return ok
+}
+
+// terminalSize returns the size of the terminal of the standard output, or
+// the size in $COLUMNS and $LINES, which watch(1) sets, if it isn't one. The
+// height is 0 if it's unknown.
+func terminalSize() (width, height int) {
+	if ws, ok := windowSize(os.Stdout); ok && ws.Col > 0 {
+		return int(ws.Col), int(ws.Row)
+	}
+	width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	height, _ = strconv.Atoi(os.Getenv("LINES"))
+	if width <= 0 {
+		width = 80
+	}
+	return width, height
+}
+
+// suspend suspends jtop like Ctrl-Z does in a shell, restoring the terminal
+// until jtop is resumed.
+func suspend() {
+	terminal.Suspend()
+	signalSelf(syscall.SIGTSTP)
+	resumeTerminal()
+}
+
+// windowSize returns the struct winsize of a terminal.
+func windowSize(f *os.File) (ws struct{ Row, Col, Xpixel, Ypixel uint16 }, ok bool) {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	return ws, errno == 0
+}
+
+func signalSelf(sig syscall.Signal) {
+	if err := syscall.Kill(os.Getpid(), sig); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// consoleScreenBufferInfo is a struct CONSOLE_SCREEN_BUFFER_INFO.
+type consoleScreenBufferInfo struct {
+	Size              struct{ X, Y int16 }
+	CursorPosition    struct{ X, Y int16 }
+	Attributes        uint16
+	Window            struct{ Left, Top, Right, Bottom int16 }
+	MaximumWindowSize struct{ X, Y int16 }
+}
+
+// isTerminal returns whether or not a file is a console, which Windows
+// Terminal is too.
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+// terminalSize returns the size of the window of the console of the standard
+// output, or the size in $COLUMNS and $LINES if it isn't one. The height is 0
+// if it's unknown.
+func terminalSize() (width, height int) {
+	var info consoleScreenBufferInfo
+	r, _, _ := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r != 0 && info.Window.Right > info.Window.Left {
+		return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1
+	}
+	width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	height, _ = strconv.Atoi(os.Getenv("LINES"))
+	if width <= 0 {
+		width = 80
+	}
+	return width, height
+}
+
+// suspend does nothing, Windows has no job control.
+func suspend() {}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/mayhewj/gtop/pkg/ui"
+)
+
+// defaultTracer is the command --tracer defaults to.
+const defaultTracer = "strace -f -p"
+
+// runTracer suspends the UI to run the --tracer command with the PID of the
+// selected process appended, and resumes it when the command exits.
+func runTracer(tui *ui.UI) {
+	process := tui.SelectedProcess()
+	if process == nil {
+		return
+	}
+	if tui.ReadOnly {
+		tui.SetStatus(ui.Translate("Processes of a recording or a remote machine can't be traced"))
+		return
+	}
+
+	command := fmt.Sprintf("%s %d", tracerFlag, process.Pid)
+	terminal.Suspend()
+	fmt.Printf(ui.Translate("jtop: running %s, jtop resumes when it exits")+"\n", command)
+	if err := runSuspended(exec.Command("sh", "-c", command)); err != nil {
+		tui.SetStatus(fmt.Sprintf("%s: %s", command, err))
+	}
+}
+
+// runShell suspends the UI to run $SHELL with $JTOP_PID, $JTOP_NAME,
+// $JTOP_COMMAND and $JTOP_USER set to those of the selected process, and
+// resumes it when the shell exits.
+func runShell(tui *ui.UI) {
+	process := tui.SelectedProcess()
+	if process == nil {
+		return
+	}
+	if tui.ReadOnly {
+		tui.SetStatus(ui.Translate("Processes of a recording or a remote machine can't be inspected from a shell"))
+		return
+	}
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+	cmd := exec.Command(shell)
+	cmd.Env = append(os.Environ(), processEnv(process)...)
+	terminal.Suspend()
+	fmt.Printf(ui.Translate("jtop: $JTOP_PID is %s, jtop resumes when the shell exits")+"\n", process)
+	if err := runSuspended(cmd); err != nil {
+		tui.SetStatus(fmt.Sprintf("%s: %s", shell, err))
+	}
+}
+
+// processEnv returns the environment variables describing a process to the
+// commands run from the UI.
+func processEnv(p *proc.Process) []string {
+	return []string{
+		fmt.Sprintf("JTOP_PID=%d", p.Pid),
+		"JTOP_NAME=" + p.Name,
+		"JTOP_COMMAND=" + p.Command,
+		"JTOP_USER=" + p.User.Username,
+	}
+}
+
+// runSuspended runs a command on the terminal after it was suspended, and
+// resumes it when the command exits.
+func runSuspended(cmd *exec.Cmd) error {
+	// Ctrl-C and Ctrl-\ are sent to jtop too, which waits for the command
+	// to exit instead, and only quits afterwards on the other
+	// shutdownSignals.
+	signal.Reset(syscall.SIGINT)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGQUIT)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := cmd.Run()
+	signal.Stop(signals)
+	signal.Notify(shutdown, shutdownSignals...)
+
+	resumeTerminal()
+	return err
+}
@@ -0,0 +1,85 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Affinity returns the CPUs the process is allowed to run on, in ascending
+// order, from the Cpus_allowed_list of /proc/<pid>/status.
+func (p *Process) Affinity() ([]int, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", p.Pid))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// line = "Cpus_allowed_list:\t0-3,8"
+		if strings.HasPrefix(line, "Cpus_allowed_list:") {
+			return ParseCPUList(strings.TrimSpace(line[len("Cpus_allowed_list:"):]))
+		}
+	}
+	return nil, fmt.Errorf("no Cpus_allowed_list in /proc/%d/status", p.Pid)
+}
+
+// ParseCPUList parses a list of CPUs in the format of the kernel and taskset
+// -c, like 0-3,8 or 0-7:2 for every other CPU, into ascending CPU numbers.
+func ParseCPUList(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		stride := 1
+		if i := strings.IndexByte(part, ':'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid stride in %q", part)
+			}
+			stride, part = n, part[:i]
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid CPU %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU range %q", part)
+			}
+		}
+		for cpu := first; cpu <= last; cpu += stride {
+			seen[cpu] = true
+		}
+	}
+
+	var cpus []int
+	for cpu := range seen {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// FormatCPUList formats ascending CPU numbers like the kernel, such as
+// 0-3,8.
+func FormatCPUList(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,33 @@
+package proc
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// SetAffinity allows every thread of the process to run only on the passed
+// in CPUs, like taskset -a -p. Changing the affinity of the processes of
+// other users requires CAP_SYS_NICE.
+func (p *Process) SetAffinity(cpus []int) error {
+	if len(cpus) == 0 {
+		return fmt.Errorf("no CPUs")
+	}
+	var mask []uint64
+	for _, cpu := range cpus {
+		for len(mask) <= cpu/64 {
+			mask = append(mask, 0)
+		}
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+
+	for _, tid := range p.threadIDs() {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY,
+			uintptr(tid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+		// A thread that exited in the meantime doesn't matter.
+		if errno != 0 && errno != syscall.ESRCH {
+			return errno
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package proc
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"time"
+)
+
+// Agent serves the Snapshots of a Monitor over JSON-RPC, so they can be
+// received on other machines with DialAgent.
+type Agent struct {
+	mu     sync.Mutex
+	latest *Snapshot
+}
+
+// agentService contains the methods exposed over JSON-RPC.
+type agentService struct {
+	agent *Agent
+}
+
+// ListenAgent returns an Agent serving on addr.
+func ListenAgent(addr string) (*Agent, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Agent{}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Agent", &agentService{a}); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	return a, nil
+}
+
+// Update records a Snapshot of the Monitor to be served. It must be called
+// from the goroutine that updates the Monitor.
+func (a *Agent) Update(m *Monitor) {
+	s := m.Snapshot()
+	a.mu.Lock()
+	a.latest = s
+	a.mu.Unlock()
+}
+
+// Snapshot returns the most recent Snapshot.
+func (s *agentService) Snapshot(args *struct{}, reply *Snapshot) error {
+	s.agent.mu.Lock()
+	defer s.agent.mu.Unlock()
+	if s.agent.latest == nil {
+		return errors.New("no snapshot yet")
+	}
+	*reply = *s.agent.latest
+	return nil
+}
+
+// DialAgent returns a Remote that polls the Agent at addr for a Snapshot
+// every interval, reconnecting if the connection fails or a call takes
+// longer than interval.
+func DialAgent(addr string, interval time.Duration) *Remote {
+	r := newRemote(addr)
+	go func() {
+		var conn net.Conn
+		var client *rpc.Client
+		for ; ; time.Sleep(interval) {
+			if client == nil {
+				var err error
+				if conn, err = net.DialTimeout("tcp", addr, interval); err != nil {
+					r.setErr(err)
+					continue
+				}
+				client = jsonrpc.NewClient(conn)
+			}
+
+			// A call timing out fails with a net.Error, so the next
+			// one reconnects.
+			conn.SetDeadline(time.Now().Add(interval))
+			s := &Snapshot{}
+			if err := client.Call("Agent.Snapshot", &struct{}{}, s); err != nil {
+				r.setErr(err)
+				if err == rpc.ErrShutdown || isNetError(err) {
+					client.Close()
+					client = nil
+				}
+				continue
+			}
+			r.set(s)
+		}
+	}()
+	return r
+}
+
+func isNetError(err error) bool {
+	_, ok := err.(net.Error)
+	return ok
+}
@@ -0,0 +1,131 @@
+package proc
+
+// Grouping is how Aggregate groups processes.
+type Grouping int
+
+const (
+	Ungrouped Grouping = iota
+	GroupByName
+	GroupByUnit
+)
+
+// Aggregate groups a list of processes by Name or by Unit. It returns a
+// Process for each group, whose Name is the name or unit, whose Members are
+// its processes and whose counters are the sums of theirs, sorted by SortKey.
+// With GroupByName, kernel threads are grouped by KernelThreadKind in
+// brackets, such as "[kworker]", and processes that don't share their name
+// with another one are returned as they are.
+func (m *Monitor) Aggregate(processes []*Process, by Grouping) []*Process {
+	groups := make(map[string]*Process)
+	var list []*Process
+	for _, p := range processes {
+		key := p.Name
+		if p.IsKernelThread() {
+			key = "[" + KernelThreadKind(p.Name) + "]"
+		}
+		if by == GroupByUnit {
+			key = p.Unit
+			if key == "" {
+				key = "-"
+			}
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &Process{
+				Pid:           p.Pid,
+				User:          p.User,
+				Name:          key,
+				Command:       key,
+				ContainerID:   p.ContainerID,
+				containerName: p.containerName,
+				Cgroup:        p.Cgroup,
+				Unit:          p.Unit,
+				State:         p.State,
+				Ppid:          p.Ppid,
+				Pgrp:          p.Pgrp,
+				StartTime:     p.StartTime,
+				Nice:          p.Nice,
+				IOPriority:    p.IOPriority,
+				FDs:           -1,
+			}
+			groups[key] = g
+			list = append(list, g)
+		}
+		g.add(p)
+	}
+
+	for i, g := range list {
+		if len(g.Members) == 1 && by == GroupByName {
+			list[i] = g.Members[0]
+		}
+	}
+	m.sortList(list)
+	return list
+}
+
+// add adds a Process to the Members of an aggregated Process.
+func (g *Process) add(p *Process) {
+	g.Members = append(g.Members, p)
+	if p.State == 'R' {
+		g.State = 'R'
+	}
+	if p.StartTime < g.StartTime {
+		g.StartTime = p.StartTime
+	}
+	g.Utime += p.Utime
+	g.Stime += p.Stime
+	g.RSS += p.RSS
+	g.UtimeDiff += p.UtimeDiff
+	g.StimeDiff += p.StimeDiff
+	g.Cutime += p.Cutime
+	g.Cstime += p.Cstime
+	g.CutimeDiff += p.CutimeDiff
+	g.CstimeDiff += p.CstimeDiff
+	g.ReadBytes += p.ReadBytes
+	g.WriteBytes += p.WriteBytes
+	g.ReadBytesDiff += p.ReadBytesDiff
+	g.WriteBytesDiff += p.WriteBytesDiff
+	g.Minflt += p.Minflt
+	g.Majflt += p.Majflt
+	g.MinfltDiff += p.MinfltDiff
+	g.MajfltDiff += p.MajfltDiff
+	g.Swap += p.Swap
+	g.Threads += p.Threads
+	// The limits are per process, so a group has none.
+	if p.FDs >= 0 {
+		if g.FDs < 0 {
+			g.FDs = 0
+		}
+		g.FDs += p.FDs
+	}
+	// Members can share a cgroup, so their limits aren't added up.
+	if p.CgroupMemoryPercent() > g.CgroupMemoryPercent() {
+		g.CgroupMemoryMax, g.CgroupMemoryCurrent = p.CgroupMemoryMax, p.CgroupMemoryCurrent
+	}
+	if p.CgroupThrottledDiff > g.CgroupThrottledDiff {
+		g.CgroupThrottledDiff = p.CgroupThrottledDiff
+	}
+	if p.OOMScore > g.OOMScore {
+		// The member most likely to be killed first.
+		g.OOMScore = p.OOMScore
+	}
+	g.CPUDelay += p.CPUDelay
+	g.BlkioDelay += p.BlkioDelay
+	g.CPUDelayDiff += p.CPUDelayDiff
+	g.BlkioDelayDiff += p.BlkioDelayDiff
+	g.VoluntaryCtxtSwitches += p.VoluntaryCtxtSwitches
+	g.NonvoluntaryCtxtSwitches += p.NonvoluntaryCtxtSwitches
+	g.VoluntaryCtxtSwitchesDiff += p.VoluntaryCtxtSwitchesDiff
+	g.NonvoluntaryCtxtSwitchesDiff += p.NonvoluntaryCtxtSwitchesDiff
+	g.NetRxBytesDiff += p.NetRxBytesDiff
+	g.NetTxBytesDiff += p.NetTxBytesDiff
+	g.GPUTime += p.GPUTime
+	g.GPUTimeDiff += p.GPUTimeDiff
+	g.GPUMemory += p.GPUMemory
+	for len(g.NumaMemory) < len(p.NumaMemory) {
+		g.NumaMemory = append(g.NumaMemory, 0)
+	}
+	for node, memory := range p.NumaMemory {
+		g.NumaMemory[node] += memory
+	}
+}
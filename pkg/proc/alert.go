@@ -0,0 +1,289 @@
+package proc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// AlertRule is a rule of an alerts file. A process alerts when it matches
+// the Filter for the duration For, and then runs Command, writes to syslog or
+// is killed once, until it stops matching.
+type AlertRule struct {
+	Filter *Filter
+	For    time.Duration
+
+	// Command is run by sh with the JTOP_RULE, JTOP_PID, JTOP_NAME,
+	// JTOP_COMMAND and JTOP_USER environment variables set, if it isn't "".
+	Command string
+	Syslog  bool
+
+	// Kill terminates the process, see Alerter.Guard.
+	Kill bool
+
+	// condition is the rule without its hook.
+	condition string
+}
+
+func (r *AlertRule) String() string {
+	return r.condition
+}
+
+// ParseAlertRules parses an alerts file, where each line is a filter
+// expression, see ParseFilter, optionally followed by "for" and a duration,
+// and then by "run" and a command, "syslog" or "kill":
+//
+//	state == Z
+//	cpu > 90 for 30s syslog
+//	rss > 2G run notify-send "$JTOP_NAME uses more than 2G"
+//	name == chrome && rss > 8G for 10s kill
+//
+// The keywords are values when they're quoted or follow a comparison, like
+// in name == kill. Blank lines and lines starting with # are ignored.
+func ParseAlertRules(r io.Reader) ([]*AlertRule, error) {
+	var rules []*AlertRule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseAlertRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// alertKeywords are the keywords ending the filter expression of an alert
+// rule, and the hooks that can follow its duration.
+var (
+	alertKeywords = map[string]bool{"for": true, "run": true, "syslog": true, "kill": true}
+	alertHooks    = map[string]bool{"run": true, "syslog": true, "kill": true}
+)
+
+// isAlertKeyword returns whether or not a token following prev is one of
+// words, which it isn't if it's quoted or the value of a comparison, like in
+// "name == kill".
+func isAlertKeyword(token, prev filterToken, words map[string]bool) bool {
+	return !token.quoted && words[token.text] && (!prev.isOperator() || prev.text == ")")
+}
+
+func parseAlertRule(line string) (*AlertRule, error) {
+	// The tokens are scanned one at a time, as the command after "run" is
+	// the rest of the line rather than a filter expression.
+	i := 0
+	next := func() (filterToken, bool, error) {
+		if i = skipFilterSpaces(line, i); i == len(line) {
+			return filterToken{}, false, nil
+		}
+		token, err := scanFilterToken(line, i)
+		i = token.end
+		return token, true, err
+	}
+
+	var keyword, prev filterToken
+	for {
+		token, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if isAlertKeyword(token, prev, alertKeywords) {
+			keyword = token
+			break
+		}
+		prev = token
+	}
+	expr := line
+	if keyword.text != "" {
+		expr = strings.TrimSpace(line[:keyword.start])
+	}
+
+	rule := &AlertRule{}
+	if keyword.text == "for" {
+		duration, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("missing duration after for")
+		}
+		if rule.For, err = time.ParseDuration(duration.text); err != nil {
+			return nil, err
+		}
+		if keyword, ok, err = next(); err != nil {
+			return nil, err
+		}
+		if ok && !isAlertKeyword(keyword, duration, alertHooks) {
+			return nil, fmt.Errorf("unexpected %q", keyword.text)
+		}
+	}
+	rule.condition = strings.TrimSpace(line)
+	if keyword.text != "" {
+		rule.condition = strings.TrimSpace(line[:keyword.start])
+	}
+
+	switch keyword.text {
+	case "run":
+		rule.Command = strings.TrimSpace(line[keyword.end:])
+		if rule.Command == "" {
+			return nil, fmt.Errorf("missing command after run")
+		}
+	case "syslog":
+		rule.Syslog = true
+	case "kill":
+		rule.Kill = true
+	}
+	if keyword.text != "run" {
+		if token, ok, err := next(); err != nil {
+			return nil, err
+		} else if ok {
+			return nil, fmt.Errorf("unexpected %q", token.text)
+		}
+	}
+
+	filter, err := ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	rule.Filter = filter
+	return rule, nil
+}
+
+// Alert is a process that alerts according to a rule.
+type Alert struct {
+	Rule    *AlertRule
+	Process *Process
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("%s: %s", a.Process, a.Rule)
+}
+
+// alertKey identifies a process matching a rule.
+type alertKey struct {
+	rule *AlertRule
+	pid  uint64
+}
+
+// Alerter checks the processes of a Monitor against alert rules.
+type Alerter struct {
+	Rules []*AlertRule
+
+	// Guard enables the rules that kill processes, which log what they
+	// do to it. The processes are only displayed as alerting otherwise.
+	Guard io.Writer
+
+	// since contains when each process started matching each rule, and
+	// alerting the ones that matched for long enough at the last check.
+	since    map[alertKey]time.Time
+	alerting map[alertKey]bool
+	alerts   []Alert
+	active   map[uint64]bool
+
+	// terminated contains when the processes killed by rules were sent
+	// SIGTERM, unless they were sent SIGKILL since.
+	terminated map[alertKey]time.Time
+
+	syslog syslogWriter
+}
+
+// syslogWriter writes to the system log, see newSyslog.
+type syslogWriter interface {
+	Warning(m string) error
+}
+
+// NewAlerter returns an Alerter checking the passed in rules.
+func NewAlerter(rules []*AlertRule) *Alerter {
+	return &Alerter{Rules: rules, since: make(map[alertKey]time.Time)}
+}
+
+// Check checks the processes of a Monitor at time now, and runs the hooks of
+// the rules of processes that just started alerting.
+func (a *Alerter) Check(m *Monitor, now time.Time) {
+	since := make(map[alertKey]time.Time)
+	alerting := make(map[alertKey]bool)
+	terminated := make(map[alertKey]time.Time)
+	a.alerts = nil
+	a.active = make(map[uint64]bool)
+	for _, rule := range a.Rules {
+		for _, p := range m.List {
+			if !rule.Filter.Match(m, p) {
+				continue
+			}
+			key := alertKey{rule, p.Pid}
+			start, ok := a.since[key]
+			if !ok {
+				start = now
+			}
+			since[key] = start
+			if now.Sub(start) < rule.For {
+				continue
+			}
+
+			alert := Alert{rule, p}
+			a.alerts = append(a.alerts, alert)
+			a.active[p.Pid] = true
+			alerting[key] = true
+			if !a.alerting[key] {
+				a.runHooks(alert)
+			}
+			if rule.Kill && a.Guard != nil {
+				if t, ok := a.guard(key, alert, now); ok {
+					terminated[key] = t
+				}
+			}
+		}
+	}
+	a.since, a.alerting, a.terminated = since, alerting, terminated
+}
+
+// Alerts returns the Alerts of the last check.
+func (a *Alerter) Alerts() []Alert {
+	if a == nil {
+		return nil
+	}
+	return a.alerts
+}
+
+// Alerting returns whether or not the process with the passed in Pid alerted
+// at the last check.
+func (a *Alerter) Alerting(pid uint64) bool {
+	return a != nil && a.active[pid]
+}
+
+func (a *Alerter) runHooks(alert Alert) {
+	rule, p := alert.Rule, alert.Process
+	if rule.Syslog {
+		if a.syslog == nil {
+			w, err := newSyslog()
+			if err != nil {
+				return
+			}
+			a.syslog = w
+		}
+		a.syslog.Warning(alert.String())
+	}
+	if rule.Command != "" {
+		cmd := exec.Command("sh", "-c", rule.Command)
+		cmd.Env = append(os.Environ(),
+			"JTOP_RULE="+rule.String(),
+			fmt.Sprintf("JTOP_PID=%d", p.Pid),
+			"JTOP_NAME="+p.Name,
+			"JTOP_COMMAND="+p.Command,
+			"JTOP_USER="+p.User.Username,
+		)
+		if err := cmd.Start(); err == nil {
+			go cmd.Wait()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package proc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAlertRules(t *testing.T) {
+	tests := []struct {
+		line      string
+		filter    string
+		condition string
+		duration  time.Duration
+		command   string
+		syslog    bool
+		kill      bool
+	}{
+		{"state == Z", "state == Z", "state == Z", 0, "", false, false},
+		{"cpu > 90 for 30s syslog", "cpu > 90", "cpu > 90 for 30s", 30 * time.Second, "", true, false},
+		{"rss > 2G run notify-send \"$JTOP_NAME uses more than 2G\"", "rss > 2G", "rss > 2G", 0, "notify-send \"$JTOP_NAME uses more than 2G\"", false, false},
+		{"name == chrome && rss > 8G for 10s kill", "name == chrome && rss > 8G", "name == chrome && rss > 8G for 10s", 10 * time.Second, "", false, true},
+		{"command =~ ' run ' for 5s", "command =~ ' run '", "command =~ ' run ' for 5s", 5 * time.Second, "", false, false},
+		{"name == kill", "name == kill", "name == kill", 0, "", false, false},
+		{"name == 'syslog' syslog", "name == 'syslog'", "name == 'syslog'", 0, "", true, false},
+		{"name == kill kill", "name == kill", "name == kill", 0, "", false, true},
+		{"(name == for) for 1m run echo it's $JTOP_PID", "(name == for)", "(name == for) for 1m", time.Minute, "echo it's $JTOP_PID", false, false},
+		{"name=~run run  kill -9 $JTOP_PID ", "name=~run", "name=~run", 0, "kill -9 $JTOP_PID", false, false},
+	}
+	for _, test := range tests {
+		rules, err := ParseAlertRules(strings.NewReader(test.line))
+		if err != nil {
+			t.Errorf("ParseAlertRules(%q): %v", test.line, err)
+			continue
+		}
+		if len(rules) != 1 {
+			t.Errorf("ParseAlertRules(%q) returned %d rules, want 1", test.line, len(rules))
+			continue
+		}
+		rule := rules[0]
+		if rule.Filter.String() != test.filter || rule.String() != test.condition || rule.For != test.duration ||
+			rule.Command != test.command || rule.Syslog != test.syslog || rule.Kill != test.kill {
+			t.Errorf("ParseAlertRules(%q) = {%q %q %v %q %v %v}, want {%q %q %v %q %v %v}", test.line,
+				rule.Filter, rule, rule.For, rule.Command, rule.Syslog, rule.Kill,
+				test.filter, test.condition, test.duration, test.command, test.syslog, test.kill)
+		}
+	}
+}
+
+func TestParseAlertRulesErrors(t *testing.T) {
+	tests := []struct {
+		rules string
+		err   string
+	}{
+		{"kill", "line 1: missing field"},
+		{"cpu > 1 for", "line 1: missing duration after for"},
+		{"cpu > 1 for x", `line 1: time: invalid duration "x"`},
+		{"cpu > 1 for 5s for 5s", `line 1: unexpected "for"`},
+		{"cpu > 1 for 5s name", `line 1: unexpected "name"`},
+		{"cpu > 1 run ", "line 1: missing command after run"},
+		{"cpu > 1 syslog kill", `line 1: unexpected "kill"`},
+		{"cpu > 1 kill 'x", "line 1: unterminated string at 13"},
+		{"cpu > 1 && name == 'kill", "line 1: unterminated string at 19"},
+		{"# cpu\n\nstate == Z\nfoo > 1 kill", `line 4: unknown field "foo"`},
+	}
+	for _, test := range tests {
+		_, err := ParseAlertRules(strings.NewReader(test.rules))
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("ParseAlertRules(%q) error = %v, want %q", test.rules, err, test.err)
+		}
+	}
+}
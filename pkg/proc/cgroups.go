@@ -0,0 +1,300 @@
+package proc
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// Cgroup contains the aggregated resource usage of a cgroup v2 group, read
+// from the cgroup2 filesystem.
+type Cgroup struct {
+	Path      string // /system.slice/foo.service
+	Processes []*Process
+
+	UsageUsec     uint64 // usage_usec from cpu.stat
+	UsageUsecDiff uint64
+	MemoryCurrent uint64 // memory.current in bytes
+
+	// MemoryMax is the memory.max of the group, or of its closest ancestor
+	// with a limit, or 0 if the memory isn't limited. MemoryLimitCurrent
+	// is the memory.current of the group with the limit.
+	MemoryMax          uint64
+	MemoryLimitCurrent uint64
+
+	// ThrottledUsec is the throttled_usec from the cpu.stat of the group,
+	// or of its closest ancestor with a cpu.max limit.
+	ThrottledUsec     uint64
+	ThrottledUsecDiff uint64
+
+	// Frozen is whether or not the processes of the group are frozen, from
+	// cgroup.events.
+	Frozen bool
+
+	alive bool
+}
+
+// cgroup2Root returns the mount point of the cgroup2 filesystem, or "" if
+// it isn't mounted. On hybrid systems it's mounted alongside the v1
+// hierarchies.
+func cgroup2Root() string {
+	for _, root := range []string{"/sys/fs/cgroup", "/sys/fs/cgroup/unified"} {
+		// Only the cgroup2 filesystem has a cgroup.controllers file.
+		if _, err := os.Stat(root + "/cgroup.controllers"); err == nil {
+			return root
+		}
+	}
+	return ""
+}
+
+// updateCgroups groups the processes by their cgroup v2 path and reads the
+// usage of every group.
+func (m *Monitor) updateCgroups() {
+	if m.Cgroup2Root == "" {
+		return
+	}
+
+	for _, cg := range m.Cgroups {
+		cg.alive = false
+		cg.Processes = nil
+	}
+
+	// The limits of the ancestors of the groups, which many groups share.
+	limits := make(map[string]*cgroupLimits)
+	for _, p := range m.List {
+		if p.Cgroup == "" {
+			continue
+		}
+		cg, ok := m.cgroupMap[p.Cgroup]
+		if !ok {
+			cg = &Cgroup{Path: p.Cgroup}
+			m.cgroupMap[p.Cgroup] = cg
+			m.Cgroups = append(m.Cgroups, cg)
+		}
+		if !cg.alive {
+			cg.alive = true
+			cg.parseFiles(m.Cgroup2Root)
+			cg.parseLimits(m.Cgroup2Root, limits)
+		}
+		cg.Processes = append(cg.Processes, p)
+		p.Frozen = cg.Frozen
+		p.CgroupMemoryMax, p.CgroupMemoryCurrent = cg.MemoryMax, cg.MemoryLimitCurrent
+		p.CgroupThrottledDiff = cg.ThrottledUsecDiff
+	}
+
+	for i := len(m.Cgroups) - 1; i >= 0; i-- {
+		if cg := m.Cgroups[i]; !cg.alive {
+			m.Cgroups = append(m.Cgroups[:i], m.Cgroups[i+1:]...)
+			delete(m.cgroupMap, cg.Path)
+		}
+	}
+}
+
+func (cg *Cgroup) parseFiles(root string) {
+	dir := path.Join(root, cg.Path)
+
+	// Controllers that aren't enabled for the group don't have files, in
+	// which case the values are left at 0.
+	if usage, ok := parseCgroupKeyedFile(dir+"/cpu.stat", "usage_usec"); ok {
+		if cg.UsageUsec != 0 {
+			cg.UsageUsecDiff = usage - cg.UsageUsec
+		}
+		cg.UsageUsec = usage
+	}
+	if data, err := ioutil.ReadFile(dir + "/memory.current"); err == nil {
+		cg.MemoryCurrent, _ = ParseUint64(strings.TrimSpace(string(data)))
+	}
+	frozen, ok := parseCgroupKeyedFile(dir+"/cgroup.events", "frozen")
+	cg.Frozen = ok && frozen == 1
+}
+
+// cgroupLimits are the memory and CPU limits of a group.
+type cgroupLimits struct {
+	memoryMax     uint64 // 0 if the memory.max is "max"
+	memoryCurrent uint64
+	cpuLimited    bool // whether or not the quota of cpu.max isn't "max"
+	throttledUsec uint64
+}
+
+func readCgroupLimits(dir string) *cgroupLimits {
+	l := &cgroupLimits{}
+	if data, err := ioutil.ReadFile(dir + "/memory.max"); err == nil {
+		// data = "max\n" or "536870912\n"
+		if max, err := ParseUint64(strings.TrimSpace(string(data))); err == nil {
+			l.memoryMax = max
+			data, _ := ioutil.ReadFile(dir + "/memory.current")
+			l.memoryCurrent, _ = ParseUint64(strings.TrimSpace(string(data)))
+		}
+	}
+	if data, err := ioutil.ReadFile(dir + "/cpu.max"); err == nil {
+		// data = "max 100000\n" or "50000 100000\n"
+		if fields := strings.Fields(string(data)); len(fields) > 0 && fields[0] != "max" {
+			l.cpuLimited = true
+			l.throttledUsec, _ = parseCgroupKeyedFile(dir+"/cpu.stat", "throttled_usec")
+		}
+	}
+	return l
+}
+
+// parseLimits sets the memory and CPU limits of the group from its closest
+// ancestors with limits, since the limits of a Kubernetes pod or a systemd
+// slice apply to every group below it.
+func (cg *Cgroup) parseLimits(root string, limits map[string]*cgroupLimits) {
+	cg.MemoryMax, cg.MemoryLimitCurrent = 0, 0
+	throttled, cpuLimited := uint64(0), false
+	for dir := cg.Path; dir != "/" && dir != "." && dir != ""; dir = path.Dir(dir) {
+		l, ok := limits[dir]
+		if !ok {
+			l = readCgroupLimits(path.Join(root, dir))
+			limits[dir] = l
+		}
+		if cg.MemoryMax == 0 && l.memoryMax != 0 {
+			cg.MemoryMax, cg.MemoryLimitCurrent = l.memoryMax, l.memoryCurrent
+		}
+		if !cpuLimited && l.cpuLimited {
+			throttled, cpuLimited = l.throttledUsec, true
+		}
+	}
+
+	cg.ThrottledUsecDiff = 0
+	if cpuLimited && cg.ThrottledUsec != 0 && throttled >= cg.ThrottledUsec {
+		cg.ThrottledUsecDiff = throttled - cg.ThrottledUsec
+	}
+	cg.ThrottledUsec = throttled
+}
+
+// MemoryPercent returns the percentage of its memory limit the group uses, or
+// 0 if it has none.
+func (cg *Cgroup) MemoryPercent() float64 {
+	if cg.MemoryMax == 0 {
+		return 0
+	}
+	return 100 * float64(cg.MemoryLimitCurrent) / float64(cg.MemoryMax)
+}
+
+// FreezeCgroup freezes the processes of a cgroup v2 group and of its
+// descendants with the freezer of cgroup.freeze, or thaws them. Unlike
+// SIGSTOP, the processes can't tell they were frozen. The groups of jtop
+// itself can't be frozen, since it couldn't thaw them.
+func (m *Monitor) FreezeCgroup(cgroup string, freeze bool) error {
+	switch {
+	case m.Cgroup2Root == "":
+		return fmt.Errorf("freezing requires the cgroup2 filesystem")
+	case cgroup == "" || cgroup == "/":
+		return fmt.Errorf("the root cgroup can't be frozen")
+	}
+	if freeze {
+		self, err := NewProcess(uint64(os.Getpid()))
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(self.Cgroup+"/", strings.TrimSuffix(cgroup, "/")+"/") {
+			return fmt.Errorf("jtop is in %s, freezing it would freeze jtop", cgroup)
+		}
+	}
+	value := "0"
+	if freeze {
+		value = "1"
+	}
+	if err := ioutil.WriteFile(path.Join(m.Cgroup2Root, cgroup, "cgroup.freeze"), []byte(value), 0644); err != nil {
+		return err
+	}
+	if cg, ok := m.cgroupMap[cgroup]; ok {
+		cg.Frozen = freeze
+		for _, p := range cg.Processes {
+			p.Frozen = freeze
+		}
+	}
+	return nil
+}
+
+// parseCgroupKeyedFile returns the value of key in a flat keyed cgroup file
+// such as cpu.stat.
+func parseCgroupKeyedFile(path, key string) (uint64, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line = "usage_usec 123456"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			value, err := ParseUint64(fields[1])
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
+
+// CPUPercent returns the CPU usage of the group since the last update,
+// scaled like the %CPU column so a group using two full CPUs is at 200%.
+func (cg *Cgroup) CPUPercent(m *Monitor) float64 {
+	if m.Interval == 0 {
+		return 0
+	}
+	return 100 * float64(cg.UsageUsecDiff) / float64(m.Interval.Nanoseconds()/1000)
+}
+
+// ThrottledPercent returns the percentage of time since the last update a
+// cgroup was throttled for, given the difference of its throttled_usec. It's
+// above 100 when it's throttled on several CPUs.
+func (m *Monitor) ThrottledPercent(usecDiff uint64) float64 {
+	if m.Interval == 0 {
+		return 0
+	}
+	return 100 * float64(usecDiff) / float64(m.Interval.Nanoseconds()/1000)
+}
+
+// CgroupMemoryPercent returns the percentage of the memory limit of its
+// cgroup the cgroup of the process uses, or 0 if it has none.
+func (p *Process) CgroupMemoryPercent() float64 {
+	if p.CgroupMemoryMax == 0 {
+		return 0
+	}
+	return 100 * float64(p.CgroupMemoryCurrent) / float64(p.CgroupMemoryMax)
+}
+
+// ByCgroupMemory sorts the processes whose cgroups are the closest to their
+// memory limit first.
+type ByCgroupMemory []*Process
+
+func (p ByCgroupMemory) Len() int      { return len(p) }
+func (p ByCgroupMemory) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByCgroupMemory) Less(i, j int) bool {
+	p1, p2 := p[i].CgroupMemoryPercent(), p[j].CgroupMemoryPercent()
+	if p1 == p2 {
+		return p[i].Pid < p[j].Pid
+	}
+	return p1 > p2
+}
+
+// ByThrottled sorts the processes whose cgroups were throttled the longest
+// since the last update first.
+type ByThrottled []*Process
+
+func (p ByThrottled) Len() int      { return len(p) }
+func (p ByThrottled) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByThrottled) Less(i, j int) bool {
+	p1, p2 := p[i].CgroupThrottledDiff, p[j].CgroupThrottledDiff
+	if p1 == p2 {
+		return p[i].Pid < p[j].Pid
+	}
+	return p1 > p2
+}
+
+type ByCgroupCPU []*Cgroup
+
+func (c ByCgroupCPU) Len() int      { return len(c) }
+func (c ByCgroupCPU) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c ByCgroupCPU) Less(i, j int) bool {
+	if c[i].UsageUsecDiff == c[j].UsageUsecDiff {
+		return c[i].Path < c[j].Path
+	}
+	return c[i].UsageUsecDiff > c[j].UsageUsecDiff
+}
@@ -0,0 +1,189 @@
+package proc
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// The following are from linux/cn_proc.h.
+const (
+	procEventFork = 0x00000001
+	procEventExec = 0x00000002
+	procEventExit = 0x80000000
+
+	cnMsgLen = 20
+	// procEventHdrLen is the length of the what, cpu and timestamp_ns
+	// fields that precede the event data.
+	procEventHdrLen = 16
+
+	// procEventsScanInterval is how often /proc is listed anyway, in case
+	// an event was missed.
+	procEventsScanInterval = time.Minute
+)
+
+// nativeEndian is the byte order of the netlink messages.
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	i := uint16(1)
+	if *(*byte)(unsafe.Pointer(&i)) == 0 {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// procConnector receives the fork, exec and exit events of every process
+// from the kernel's proc connector. It requires CAP_NET_ADMIN.
+type procConnector struct {
+	fd int
+	// quit is closed by Close, and done when the receiving goroutine has
+	// returned, after which fd can be closed.
+	quit chan struct{}
+	done chan struct{}
+
+	mu      sync.Mutex
+	started map[uint64]bool // forked or exec'd since the last drain
+	exited  map[uint64]bool
+	// commands contains the command lines of the processes that exec'd
+	// since the last drain, read as soon as they did, and exitCodes the
+	// wait statuses of the processes that exited.
+	commands  map[uint64]string
+	exitCodes map[uint64]uint32
+	// overflow is set when events were dropped because they weren't read
+	// fast enough, in which case /proc has to be listed again.
+	overflow bool
+	err      error
+}
+
+// procEvent is a fork, exec or exit event of a process.
+type procEvent struct {
+	what uint32
+	pid  uint64
+	// command is the command line of a process that exec'd, if it was
+	// still running, and exitCode the wait status of one that exited.
+	command  string
+	exitCode uint32
+}
+
+// parseProcEvent parses a struct cn_msg containing a struct proc_event, and
+// reads the command line of the process if it exec'd. Events about threads
+// are ignored.
+func parseProcEvent(data []byte) (procEvent, bool) {
+	if len(data) < cnMsgLen+procEventHdrLen+8 {
+		return procEvent{}, false
+	}
+	event := data[cnMsgLen:]
+	what := nativeEndian.Uint32(event[0:])
+	body := event[procEventHdrLen:]
+
+	switch what {
+	case procEventFork:
+		// parent_pid, parent_tgid, child_pid, child_tgid
+		if len(body) < 16 {
+			return procEvent{}, false
+		}
+		pid, tgid := nativeEndian.Uint32(body[8:]), nativeEndian.Uint32(body[12:])
+		return procEvent{what: what, pid: uint64(pid)}, pid == tgid
+	case procEventExec:
+		// process_pid, process_tgid
+		pid := uint64(nativeEndian.Uint32(body[4:]))
+		// Short-lived processes have exited by the next update.
+		command, _ := readCommand(pid)
+		return procEvent{what: what, pid: pid, command: command}, true
+	case procEventExit:
+		// process_pid, process_tgid, exit_code, exit_signal
+		pid, tgid := nativeEndian.Uint32(body[0:]), nativeEndian.Uint32(body[4:])
+		if pid != tgid || len(body) < 12 {
+			return procEvent{}, false
+		}
+		return procEvent{what: what, pid: uint64(pid), exitCode: nativeEndian.Uint32(body[8:])}, true
+	}
+	return procEvent{}, false
+}
+
+// record records the process that an event is about. It must be called with
+// c.mu held.
+func (c *procConnector) record(event procEvent) {
+	switch event.what {
+	case procEventFork:
+		c.started[event.pid] = true
+		delete(c.exited, event.pid)
+	case procEventExec:
+		c.started[event.pid] = true
+		if event.command != "" {
+			c.commands[event.pid] = event.command
+		}
+	case procEventExit:
+		c.exited[event.pid] = true
+		c.exitCodes[event.pid] = event.exitCode
+		delete(c.started, event.pid)
+	}
+}
+
+// drain returns the processes started and exited since the last drain, and
+// whether or not any events were dropped. It returns an error if events are
+// no longer being received. The commands and exit codes since the last
+// drain are stored in the Monitor, for its Log.
+func (c *procConnector) drain(m *Monitor) (started, exited map[uint64]bool, overflow bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	started, exited, overflow = c.started, c.exited, c.overflow
+	m.execCommands, m.exitCodes = c.commands, c.exitCodes
+	c.started = make(map[uint64]bool)
+	c.exited = make(map[uint64]bool)
+	c.commands = make(map[uint64]string)
+	c.exitCodes = make(map[uint64]uint32)
+	c.overflow = false
+	return started, exited, overflow, c.err
+}
+
+// eventPids returns the Pids of the processes known from the last update and
+// the ones started since, according to the proc connector, along with the
+// ones that exec'd. It returns false if /proc has to be listed instead: the
+// proc connector isn't available, events were dropped, or it's been
+// procEventsScanInterval since /proc was last listed.
+func (m *Monitor) eventPids() ([]uint64, map[uint64]bool, bool) {
+	if !m.ProcEvents {
+		if m.connector != nil {
+			m.Close()
+		}
+		return nil, nil, false
+	}
+	if m.connector == nil {
+		if m.connectorFailed {
+			return nil, nil, false
+		}
+		connector, err := listenProcConnector()
+		if err != nil {
+			m.connectorFailed = true
+			return nil, nil, false
+		}
+		// Events are only received from now on.
+		m.connector = connector
+		return nil, nil, false
+	}
+
+	started, exited, overflow, err := m.connector.drain(m)
+	if err != nil {
+		m.Close()
+		m.connectorFailed = true
+		return nil, nil, false
+	}
+	if overflow || time.Since(m.lastScan) >= procEventsScanInterval {
+		return nil, nil, false
+	}
+
+	var pids []uint64
+	for pid := range m.Map {
+		if !exited[pid] {
+			pids = append(pids, pid)
+		}
+	}
+	for pid := range started {
+		if _, ok := m.Map[pid]; !ok {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, started, true
+}
@@ -0,0 +1,134 @@
+package proc
+
+import (
+	"syscall"
+	"time"
+)
+
+// The following are from linux/netlink.h and linux/connector.h.
+const (
+	netlinkConnector = 11
+
+	cnIdxProc = 1
+	cnValProc = 1
+
+	procCnMcastListen = 1
+	procCnMcastIgnore = 2
+
+	nlmsgHdrLen = 16
+
+	connectorRcvBuf = 1 << 20
+
+	// connectorRcvTimeout is how long receiving events blocks for, after
+	// which the receiving goroutine checks whether or not it was closed.
+	connectorRcvTimeout = 100 * time.Millisecond
+)
+
+// listenProcConnector subscribes to the proc connector and starts receiving
+// events in the background.
+func listenProcConnector() (*procConnector, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkConnector)
+	if err != nil {
+		return nil, err
+	}
+	syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, connectorRcvBuf)
+	timeout := syscall.NsecToTimeval(connectorRcvTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	addr := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	if err := sendProcCnOp(fd, procCnMcastListen); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	c := &procConnector{
+		fd:        fd,
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+		started:   make(map[uint64]bool),
+		exited:    make(map[uint64]bool),
+		commands:  make(map[uint64]string),
+		exitCodes: make(map[uint64]uint32),
+	}
+	go c.receive()
+	return c, nil
+}
+
+// sendProcCnOp sends a PROC_CN_MCAST_LISTEN or PROC_CN_MCAST_IGNORE message.
+func sendProcCnOp(fd int, op uint32) error {
+	msg := make([]byte, nlmsgHdrLen+cnMsgLen+4)
+	// struct nlmsghdr
+	nativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	nativeEndian.PutUint16(msg[4:], syscall.NLMSG_DONE)
+	nativeEndian.PutUint32(msg[12:], uint32(syscall.Getpid()))
+	// struct cn_msg
+	cn := msg[nlmsgHdrLen:]
+	nativeEndian.PutUint32(cn[0:], cnIdxProc)
+	nativeEndian.PutUint32(cn[4:], cnValProc)
+	nativeEndian.PutUint16(cn[16:], 4)
+	// enum proc_cn_mcast_op
+	nativeEndian.PutUint32(cn[cnMsgLen:], op)
+
+	return syscall.Sendto(fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+func (c *procConnector) receive() {
+	defer close(c.done)
+	buf := make([]byte, syscall.Getpagesize())
+	for {
+		select {
+		case <-c.quit:
+			return
+		default:
+		}
+
+		n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+		if err == syscall.ENOBUFS {
+			c.mu.Lock()
+			c.overflow = true
+			c.mu.Unlock()
+			continue
+		}
+		if err == syscall.EINTR || err == syscall.EAGAIN || err == syscall.EWOULDBLOCK {
+			continue
+		}
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			return
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		var events []procEvent
+		for _, msg := range msgs {
+			if event, ok := parseProcEvent(msg.Data); ok {
+				events = append(events, event)
+			}
+		}
+		c.mu.Lock()
+		for _, event := range events {
+			c.record(event)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Close stops receiving events, and closes the socket once the receiving
+// goroutine has returned, so it can't read from a reused file descriptor.
+func (c *procConnector) Close() error {
+	sendProcCnOp(c.fd, procCnMcastIgnore)
+	close(c.quit)
+	<-c.done
+	return syscall.Close(c.fd)
+}
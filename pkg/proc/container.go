@@ -0,0 +1,196 @@
+package proc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dockerSocket  = "/var/run/docker.sock"
+	dockerTimeout = 500 * time.Millisecond
+
+	// containerMissTTL is how long a container that couldn't be resolved
+	// waits before being resolved again.
+	containerMissTTL = time.Minute
+
+	// ShortContainerIDLen is the length of a container ID as displayed by
+	// docker.
+	ShortContainerIDLen = 12
+)
+
+var (
+	// containerIDRegexp matches the container ID in cgroup paths created by
+	// Docker ("/docker/<id>", "docker-<id>.scope"), containerd
+	// ("cri-containerd-<id>.scope"), CRI-O ("crio-<id>.scope"), Podman
+	// ("libpod-<id>.scope") and Kubernetes ("/kubepods/.../<id>").
+	containerIDRegexp = regexp.MustCompile(`(?:docker|containerd|crio|libpod|kubepods)[-/](?:.*[-/])?([0-9a-f]{64})(?:\.scope)?$`)
+
+	dockerClient = &http.Client{
+		Timeout: dockerTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", dockerSocket)
+			},
+		},
+	}
+)
+
+// parseCgroupFile sets Cgroup, Unit and ContainerID from /proc/<pid>/cgroup.
+func (p *Process) parseCgroupFile(buf *readBuffer) error {
+	data, err := buf.readFile(fmt.Sprintf("/proc/%d/cgroup", p.Pid))
+	if err != nil {
+		return err
+	}
+
+	// line = "12:cpu,cpuacct:/docker/0123...ef" or "0::/system.slice/..."
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			// The cgroup v2 hierarchy always has ID 0 and no controllers.
+			p.Cgroup = parts[2]
+			p.Unit = cgroupUnit(parts[2])
+		} else if parts[1] == "name=systemd" && p.Unit == "" {
+			// systemd has its own hierarchy with cgroup v1.
+			p.Unit = cgroupUnit(parts[2])
+		}
+		if match := containerIDRegexp.FindStringSubmatch(parts[2]); match != nil {
+			p.ContainerID = match[1]
+		}
+	}
+	return nil
+}
+
+// ContainerName returns the name of the container the Process is running
+// in, its short ID if the name wasn't resolved by the last update, or "" if
+// the Process isn't running in a container.
+func (p *Process) ContainerName() string {
+	if p.ContainerID == "" {
+		return ""
+	}
+	if p.containerName != "" {
+		return p.containerName
+	}
+	return p.ContainerID[:ShortContainerIDLen]
+}
+
+// containerCache caches the names of the containers resolved via the Docker
+// socket. The names are resolved in the background, so updates don't wait
+// for Docker, and the containers that couldn't be resolved are resolved
+// again after containerMissTTL.
+type containerCache struct {
+	mu        sync.Mutex
+	names     map[string]string
+	misses    map[string]time.Time
+	resolving map[string]bool
+	// seen contains the containers looked up since the last prune.
+	seen map[string]bool
+}
+
+func newContainerCache() *containerCache {
+	return &containerCache{
+		names:     make(map[string]string),
+		misses:    make(map[string]time.Time),
+		resolving: make(map[string]bool),
+		seen:      make(map[string]bool),
+	}
+}
+
+// name returns the name of a container if it was resolved, and starts
+// resolving it otherwise, returning "" in the meantime.
+func (c *containerCache) name(id string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[id] = true
+	if name, ok := c.names[id]; ok {
+		return name
+	}
+	if c.resolving[id] || time.Since(c.misses[id]) < containerMissTTL {
+		return ""
+	}
+	c.resolving[id] = true
+	go c.resolve(id)
+	return ""
+}
+
+// resolve resolves a container ID to its name by asking the Docker daemon,
+// if it's available.
+func (c *containerCache) resolve(id string) {
+	name := ""
+	if _, err := os.Stat(dockerSocket); err == nil {
+		name = queryDockerName(id)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.resolving, id)
+	if name == "" {
+		c.misses[id] = time.Now()
+		return
+	}
+	delete(c.misses, id)
+	c.names[id] = name
+}
+
+// prune forgets the containers that weren't looked up since the last prune,
+// which no longer have any processes.
+func (c *containerCache) prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.names {
+		if !c.seen[id] {
+			delete(c.names, id)
+		}
+	}
+	for id := range c.misses {
+		if !c.seen[id] {
+			delete(c.misses, id)
+		}
+	}
+	c.seen = make(map[string]bool)
+}
+
+func queryDockerName(id string) string {
+	// The host is ignored since we always dial the Docker socket.
+	resp, err := dockerClient.Get("http://docker/containers/" + id + "/json")
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	var container struct {
+		Name string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(container.Name, "/")
+}
+
+// containerWhitelisted returns whether or not a Process is running in the
+// Container the Monitor is restricted to, by container name or ID prefix.
+func (m *Monitor) containerWhitelisted(p *Process) bool {
+	if m.Container == "" {
+		return true
+	}
+	if p.ContainerID == "" {
+		return false
+	}
+	return p.ContainerName() == m.Container ||
+		strings.HasPrefix(p.ContainerID, m.Container)
+}
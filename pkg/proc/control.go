@@ -0,0 +1,57 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// threadIDs returns the IDs of the threads of the process from
+// /proc/<pid>/task, or only its Pid if they can't be listed.
+func (p *Process) threadIDs() []uint64 {
+	entries, err := ioutil.ReadDir(fmt.Sprintf("/proc/%d/task", p.Pid))
+	if err != nil {
+		return []uint64{p.Pid}
+	}
+	var tids []uint64
+	for _, entry := range entries {
+		if tid, err := ParseUint64(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids
+}
+
+// OOMScoreAdj returns the adjustment of the OOM score of the process, from
+// /proc/<pid>/oom_score_adj.
+func (p *Process) OOMScoreAdj() (int, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/oom_score_adj", p.Pid))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// SetOOMScoreAdj sets the adjustment of the OOM score of the process, from
+// MinOOMScoreAdj which never kills it to MaxOOMScoreAdj which kills it
+// first. Lowering it requires CAP_SYS_RESOURCE.
+func (p *Process) SetOOMScoreAdj(adj int) error {
+	if adj < MinOOMScoreAdj || adj > MaxOOMScoreAdj {
+		return fmt.Errorf("the OOM score adjustment must be between %d and %d", MinOOMScoreAdj, MaxOOMScoreAdj)
+	}
+	path := fmt.Sprintf("/proc/%d/oom_score_adj", p.Pid)
+	return ioutil.WriteFile(path, []byte(strconv.Itoa(adj)), 0644)
+}
+
+// The range of OOM score adjustments.
+const (
+	MinOOMScoreAdj = -1000
+	MaxOOMScoreAdj = 1000
+)
+
+// The range of nice values.
+const (
+	MinNice = -20
+	MaxNice = 19
+)
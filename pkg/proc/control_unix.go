@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import "syscall"
+
+// Signal sends a signal to the process.
+func (p *Process) Signal(sig syscall.Signal) error {
+	return syscall.Kill(int(p.Pid), sig)
+}
+
+// SetNice sets the nice value of the process. Lowering it requires
+// CAP_SYS_NICE.
+func (p *Process) SetNice(nice int) error {
+	if nice < MinNice {
+		nice = MinNice
+	} else if nice > MaxNice {
+		nice = MaxNice
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, int(p.Pid), nice); err != nil {
+		return err
+	}
+	p.Nice = nice
+	return nil
+}
+
+// waitStatus returns the status of a process that exited, as reported by the
+// proc connector.
+func waitStatus(status uint32) syscall.WaitStatus {
+	return syscall.WaitStatus(status)
+}
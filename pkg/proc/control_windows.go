@@ -0,0 +1,48 @@
+package proc
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signal terminates the process for SIGKILL, SIGTERM and SIGINT, since
+// Windows has no signals, and fails for the others.
+func (p *Process) Signal(sig syscall.Signal) error {
+	switch sig {
+	case syscall.SIGKILL, syscall.SIGTERM, syscall.SIGINT:
+		process, err := os.FindProcess(int(p.Pid))
+		if err != nil {
+			return err
+		}
+		defer process.Release()
+		return process.Kill()
+	}
+	return errUnsupported
+}
+
+// SetNice sets the priority class of the process to the one closest to a
+// nice value. Raising it to the realtime class requires administrator
+// rights, or it's set to the high class instead.
+func (p *Process) SetNice(nice int) error {
+	if nice < MinNice {
+		nice = MinNice
+	} else if nice > MaxNice {
+		nice = MaxNice
+	}
+	h, err := syscall.OpenProcess(processSetInformation, false, uint32(p.Pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+	if r, _, err := procSetPriorityClass.Call(uintptr(h), priorityClass(nice)); r == 0 {
+		return err
+	}
+	p.Nice = nice
+	return nil
+}
+
+// waitStatus returns the status of a process that exited, which is only its
+// exit code on Windows.
+func waitStatus(status uint32) syscall.WaitStatus {
+	return syscall.WaitStatus{ExitCode: status}
+}
@@ -0,0 +1,79 @@
+package proc
+
+import "sort"
+
+// ProcessDiff is how a process changed between two Snapshots.
+type ProcessDiff struct {
+	// Process is the latest state of the process, or its state in the
+	// baseline if it exited.
+	Process ProcessSnapshot
+
+	// CPUTime is the CPU time in clock ticks the process consumed since
+	// the baseline, and RSSDiff how many pages its RSS grew by.
+	CPUTime uint64
+	RSSDiff int64
+
+	// New is set for the processes that started since the baseline, and
+	// Exited for the ones that exited.
+	New    bool
+	Exited bool
+}
+
+// Diff returns the changes of the processes of the Snapshot since a
+// baseline Snapshot, sorted by CPU time consumed. A Pid reused since the
+// baseline is an exited process and a new one.
+func (s *Snapshot) Diff(baseline *Snapshot) []ProcessDiff {
+	before := make(map[uint64]*ProcessSnapshot, len(baseline.Processes))
+	for i := range baseline.Processes {
+		p := &baseline.Processes[i]
+		before[p.Pid] = p
+	}
+
+	var diffs []ProcessDiff
+	for _, p := range s.Processes {
+		b, ok := before[p.Pid]
+		if !ok || b.StartTime != p.StartTime {
+			diffs = append(diffs, ProcessDiff{
+				Process: p,
+				CPUTime: p.Utime + p.Stime,
+				RSSDiff: int64(p.RSS),
+				New:     true,
+			})
+			continue
+		}
+		delete(before, p.Pid)
+
+		var cpuTime uint64
+		if p.Utime+p.Stime > b.Utime+b.Stime {
+			cpuTime = p.Utime + p.Stime - b.Utime - b.Stime
+		}
+		diffs = append(diffs, ProcessDiff{
+			Process: p,
+			CPUTime: cpuTime,
+			RSSDiff: int64(p.RSS) - int64(b.RSS),
+		})
+	}
+	for _, b := range before {
+		diffs = append(diffs, ProcessDiff{Process: *b, RSSDiff: -int64(b.RSS), Exited: true})
+	}
+
+	sort.Sort(byCPUTime(diffs))
+	return diffs
+}
+
+// byCPUTime sorts ProcessDiffs by CPU time consumed, then by RSS growth,
+// from the highest.
+type byCPUTime []ProcessDiff
+
+func (d byCPUTime) Len() int      { return len(d) }
+func (d byCPUTime) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d byCPUTime) Less(i, j int) bool {
+	d1, d2 := d[i], d[j]
+	if d1.CPUTime != d2.CPUTime {
+		return d1.CPUTime > d2.CPUTime
+	}
+	if d1.RSSDiff != d2.RSSDiff {
+		return d1.RSSDiff > d2.RSSDiff
+	}
+	return d1.Process.Pid < d2.Process.Pid
+}
@@ -0,0 +1,121 @@
+package proc
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// The values in /proc/diskstats after the device name
+	diskReadsCompleted = iota
+	diskReadsMerged
+	diskSectorsRead
+	diskTimeReading
+	diskWritesCompleted
+	diskWritesMerged
+	diskSectorsWritten
+	diskTimeWriting
+	diskIosInProgress
+	diskTimeDoingIos
+	diskWeightedTimeDoingIos
+
+	// SectorSize is the unit of the sector counts. The kernel always
+	// reports sectors in 512 byte units, regardless of the actual sector
+	// size of the device.
+	SectorSize = 512
+)
+
+// Disk contains the I/O counters of a block device.
+type Disk struct {
+	Name           string
+	SectorsRead    uint64
+	SectorsWritten uint64
+	TimeDoingIos   uint64 // milliseconds
+
+	SectorsReadDiff    uint64
+	SectorsWrittenDiff uint64
+	TimeDoingIosDiff   uint64
+
+	alive bool
+}
+
+// parseDiskstatsFile updates Disks from /proc/diskstats.
+func (m *Monitor) parseDiskstatsFile() error {
+	file, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, disk := range m.Disks {
+		disk.alive = false
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line = "   8       0 sda 1234 56 ..."
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3+diskWeightedTimeDoingIos+1 {
+			continue
+		}
+		name := fields[2]
+		if !isWholeDisk(name) {
+			continue
+		}
+		values := fields[3:]
+		sectorsRead, err1 := ParseUint64(values[diskSectorsRead])
+		sectorsWritten, err2 := ParseUint64(values[diskSectorsWritten])
+		timeDoingIos, err3 := ParseUint64(values[diskTimeDoingIos])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+
+		disk, ok := m.diskMap[name]
+		if ok {
+			disk.SectorsReadDiff = sectorsRead - disk.SectorsRead
+			disk.SectorsWrittenDiff = sectorsWritten - disk.SectorsWritten
+			disk.TimeDoingIosDiff = timeDoingIos - disk.TimeDoingIos
+		} else {
+			disk = &Disk{Name: name}
+			m.diskMap[name] = disk
+			m.Disks = append(m.Disks, disk)
+		}
+		disk.SectorsRead = sectorsRead
+		disk.SectorsWritten = sectorsWritten
+		disk.TimeDoingIos = timeDoingIos
+		disk.alive = true
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for i := len(m.Disks) - 1; i >= 0; i-- {
+		if disk := m.Disks[i]; !disk.alive {
+			m.Disks = append(m.Disks[:i], m.Disks[i+1:]...)
+			delete(m.diskMap, disk.Name)
+		}
+	}
+	sort.Sort(ByDiskName(m.Disks))
+	return nil
+}
+
+// isWholeDisk returns whether or not a block device is an actual disk, as
+// opposed to a partition or a loop or ram device.
+func isWholeDisk(name string) bool {
+	if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+		return false
+	}
+	// Partitions don't have an entry in /sys/block.
+	_, err := os.Stat("/sys/block/" + strings.Replace(name, "/", "!", -1))
+	return err == nil
+}
+
+type ByDiskName []*Disk
+
+func (d ByDiskName) Len() int      { return len(d) }
+func (d ByDiskName) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d ByDiskName) Less(i, j int) bool {
+	return d[i].Name < d[j].Name
+}
@@ -0,0 +1,39 @@
+// Package proc collects the processes and resource utilization of a Linux
+// system from the proc filesystem, or of a Windows one.
+//
+// A Monitor holds every Process and the system wide counters, and is brought
+// up to date by calling Update. Rates such as the CPU usage of a Process are
+// computed from the difference between the last two updates. A Snapshot is
+// the state of a Monitor at a point in time, which can be encoded, recorded
+// and loaded into another Monitor. A Sampler updates a Monitor at a fixed
+// interval and returns a Snapshot of every update:
+//
+//	s := proc.NewSampler(time.Second)
+//	for {
+//		snapshot, err := s.Next()
+//		if err != nil {
+//			log.Print(err)
+//		}
+//		for _, p := range snapshot.Processes {
+//			fmt.Println(p.Pid, p.Name)
+//		}
+//	}
+//
+// It can also sample in the background until a context is cancelled,
+// collecting only the optional Fields needed, and dropping the oldest
+// Snapshots when the receiver falls behind:
+//
+//	s := proc.NewSampler(100 * time.Millisecond)
+//	s.Fields = proc.FieldFDs
+//	s.Start(ctx)
+//	for snapshot := range s.Snapshots() {
+//		record(snapshot)
+//	}
+//
+// On Linux the package reads /proc, netlink (the proc connector, sock_diag
+// and taskstats), cgroup v2 and sysfs. On Windows it reads a Toolhelp
+// snapshot of the processes and a handle to each, which fill the same fields
+// where there's an equivalent; what only Linux has fails with an error. On
+// the other systems Update fails, but a Monitor can still be loaded with the
+// Snapshots of another one.
+package proc
@@ -0,0 +1,130 @@
+package proc
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Exporter serves the metrics collected by the Monitor in the Prometheus
+// text exposition format.
+type Exporter struct {
+	mu      sync.Mutex
+	metrics []byte
+}
+
+// metric is a Prometheus metric family.
+type metric struct {
+	name    string
+	kind    string // gauge or counter
+	help    string
+	samples bytes.Buffer
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	m.samples.WriteString(m.name)
+	if len(labels) > 0 {
+		m.samples.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				m.samples.WriteByte(',')
+			}
+			fmt.Fprintf(&m.samples, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		m.samples.WriteByte('}')
+	}
+	fmt.Fprintf(&m.samples, " %g\n", value)
+}
+
+func (m *metric) writeTo(buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+	buf.Write(m.samples.Bytes())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// Update renders the metrics of the Monitor. It must be called from the
+// goroutine that updates the Monitor, the HTTP handler only ever serves the
+// rendered metrics.
+func (e *Exporter) Update(m *Monitor) {
+	cpus := &metric{name: "jtop_cpus", kind: "gauge", help: "Number of CPUs."}
+	memTotal := &metric{name: "jtop_memory_total_bytes", kind: "gauge", help: "Total usable memory."}
+	processes := &metric{name: "jtop_processes", kind: "gauge", help: "Number of monitored processes."}
+	cpus.add(float64(m.NumCPUs))
+	memTotal.add(float64(m.MemTotal))
+	processes.add(float64(len(m.List)))
+
+	cpuPercent := &metric{name: "jtop_process_cpu_percent", kind: "gauge",
+		help: "CPU usage of the process since the last update, 100 is one full CPU."}
+	cpuSeconds := &metric{name: "jtop_process_cpu_seconds_total", kind: "counter",
+		help: "User and system CPU time consumed by the process."}
+	rss := &metric{name: "jtop_process_resident_memory_bytes", kind: "gauge",
+		help: "Resident set size of the process."}
+	readBytes := &metric{name: "jtop_process_disk_read_bytes_total", kind: "counter",
+		help: "Bytes the process caused to be read from storage."}
+	writeBytes := &metric{name: "jtop_process_disk_written_bytes_total", kind: "counter",
+		help: "Bytes the process caused to be written to storage."}
+	for _, p := range m.List {
+		labels := []string{"pid", fmt.Sprint(p.Pid), "user", p.User.Username, "command", p.Name}
+		cpuPercent.add(m.cpuPercent(p, false), labels...)
+		cpuSeconds.add(float64(p.Utime+p.Stime)/float64(m.ClockTicks), labels...)
+		rss.add(float64(p.RSS*m.PageSize), labels...)
+		readBytes.add(float64(p.ReadBytes), labels...)
+		writeBytes.add(float64(p.WriteBytes), labels...)
+	}
+
+	rxBytes := &metric{name: "jtop_network_receive_bytes_total", kind: "counter",
+		help: "Bytes received by the network interface."}
+	txBytes := &metric{name: "jtop_network_transmit_bytes_total", kind: "counter",
+		help: "Bytes transmitted by the network interface."}
+	for _, iface := range m.Interfaces {
+		rxBytes.add(float64(iface.RxBytes), "interface", iface.Name)
+		txBytes.add(float64(iface.TxBytes), "interface", iface.Name)
+	}
+
+	diskRead := &metric{name: "jtop_disk_read_bytes_total", kind: "counter",
+		help: "Bytes read from the block device."}
+	diskWritten := &metric{name: "jtop_disk_written_bytes_total", kind: "counter",
+		help: "Bytes written to the block device."}
+	diskIoTime := &metric{name: "jtop_disk_io_time_seconds_total", kind: "counter",
+		help: "Time the block device had I/O requests queued."}
+	for _, disk := range m.Disks {
+		diskRead.add(float64(disk.SectorsRead*SectorSize), "device", disk.Name)
+		diskWritten.add(float64(disk.SectorsWritten*SectorSize), "device", disk.Name)
+		diskIoTime.add(float64(disk.TimeDoingIos)/1000, "device", disk.Name)
+	}
+
+	var buf bytes.Buffer
+	for _, metric := range []*metric{
+		cpus, memTotal, processes,
+		cpuPercent, cpuSeconds, rss, readBytes, writeBytes,
+		rxBytes, txBytes,
+		diskRead, diskWritten, diskIoTime,
+	} {
+		metric.writeTo(&buf)
+	}
+
+	e.mu.Lock()
+	e.metrics = buf.Bytes()
+	e.mu.Unlock()
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+
+	e.mu.Lock()
+	metrics := e.metrics
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(metrics)
+}
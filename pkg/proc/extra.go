@@ -0,0 +1,39 @@
+package proc
+
+import "strconv"
+
+// ByExtra sorts processes by one of their Extra values, numerically in
+// decreasing order if both are numbers and alphabetically otherwise. The
+// processes without a value are sorted last.
+type ByExtra struct {
+	Processes []*Process
+	// Index is the index of the value in Extra.
+	Index int
+}
+
+func (p ByExtra) Len() int { return len(p.Processes) }
+func (p ByExtra) Swap(i, j int) {
+	p.Processes[i], p.Processes[j] = p.Processes[j], p.Processes[i]
+}
+func (p ByExtra) Less(i, j int) bool {
+	v1, v2 := p.value(i), p.value(j)
+	if v1 == v2 {
+		return p.Processes[i].Pid < p.Processes[j].Pid
+	}
+	if v1 == "" || v2 == "" {
+		return v2 == ""
+	}
+	f1, err1 := strconv.ParseFloat(v1, 64)
+	f2, err2 := strconv.ParseFloat(v2, 64)
+	if err1 == nil && err2 == nil {
+		return f1 > f2
+	}
+	return v1 < v2
+}
+
+func (p ByExtra) value(i int) string {
+	if extra := p.Processes[i].Extra; p.Index < len(extra) {
+		return extra[p.Index]
+	}
+	return ""
+}
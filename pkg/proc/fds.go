@@ -0,0 +1,75 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// updateFDs sets the number of open file descriptors of every process and
+// their soft limit. Listing the file descriptors of the processes of other
+// users requires root, so their count is left at -1.
+func (m *Monitor) updateFDs() {
+	for _, p := range m.List {
+		p.FDs = countFDs(p.Pid)
+		p.FDLimit = readFDLimit(p.Pid)
+	}
+}
+
+func countFDs(pid uint64) int {
+	dir, err := os.Open(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return -1
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	return len(names)
+}
+
+// readFDLimit returns the soft limit of open files of a process from
+// /proc/<pid>/limits, or 0 if it's unlimited or unknown.
+func readFDLimit(pid uint64) uint64 {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// line = "Max open files            1024                 524288               files"
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(line[len("Max open files"):])
+		if len(fields) == 0 {
+			return 0
+		}
+		limit, _ := ParseUint64(fields[0])
+		return limit
+	}
+	return 0
+}
+
+// FDPercent returns the percentage of its soft limit of open files the
+// process has open, or 0 if either is unknown.
+func (p *Process) FDPercent() float64 {
+	if p.FDs < 0 || p.FDLimit == 0 {
+		return 0
+	}
+	return 100 * float64(p.FDs) / float64(p.FDLimit)
+}
+
+// ByFDs sorts the processes with the most open file descriptors first.
+type ByFDs []*Process
+
+func (p ByFDs) Len() int      { return len(p) }
+func (p ByFDs) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByFDs) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.FDs == p2.FDs {
+		return p1.Pid < p2.Pid
+	}
+	return p1.FDs > p2.FDs
+}
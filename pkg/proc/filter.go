@@ -0,0 +1,400 @@
+package proc
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a boolean expression over the fields of a process, such as
+// "cpu > 50 && user != root". It supports the comparison operators ==, !=,
+// <, <=, > and >=, the regular expression operators =~ and !~, and combining
+// expressions with &&, || and ! and parentheses. Values can be quoted, and
+// numbers can have a K, M, G or T suffix, or KB, MB, GB or TB.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// filterField is a field of a process that a Filter can compare, which is
+// either a number or a string.
+type filterField struct {
+	number func(m *Monitor, p *Process) float64
+	text   func(m *Monitor, p *Process) string
+}
+
+var filterFields = map[string]filterField{
+	"pid":  {number: func(m *Monitor, p *Process) float64 { return float64(p.Pid) }},
+	"ppid": {number: func(m *Monitor, p *Process) float64 { return float64(p.Ppid) }},
+	"cpu":  {number: func(m *Monitor, p *Process) float64 { return m.CPUPercent(p) }},
+	"mem": {number: func(m *Monitor, p *Process) float64 {
+		if m.MemTotal == 0 {
+			return 0
+		}
+		return 100 * float64(p.RSS*m.PageSize) / float64(m.MemTotal)
+	}},
+	"rss":  {number: func(m *Monitor, p *Process) float64 { return float64(p.RSS * m.PageSize) }},
+	"swap": {number: func(m *Monitor, p *Process) float64 { return float64(p.Swap) }},
+	"nice": {number: func(m *Monitor, p *Process) float64 { return float64(p.Nice) }},
+	// time is the CPU time in seconds.
+	"time": {number: func(m *Monitor, p *Process) float64 {
+		return float64(p.Utime+p.Stime) / float64(m.ClockTicks)
+	}},
+	"pidns": {number: func(m *Monitor, p *Process) float64 { return float64(p.Namespaces[0]) }},
+	"netns": {number: func(m *Monitor, p *Process) float64 { return float64(p.Namespaces[1]) }},
+	"mntns": {number: func(m *Monitor, p *Process) float64 { return float64(p.Namespaces[2]) }},
+	"read":  {number: func(m *Monitor, p *Process) float64 { return m.Rate(p.ReadBytesDiff) }},
+	"write": {number: func(m *Monitor, p *Process) float64 { return m.Rate(p.WriteBytesDiff) }},
+
+	"threads": {number: func(m *Monitor, p *Process) float64 { return float64(p.Threads) }},
+	// csw and ncsw are the voluntary and nonvoluntary context switches per
+	// second, and cpuwait and iowait percentages, see Monitor.Delays.
+	"csw":     {number: func(m *Monitor, p *Process) float64 { return m.Rate(p.VoluntaryCtxtSwitchesDiff) }},
+	"ncsw":    {number: func(m *Monitor, p *Process) float64 { return m.Rate(p.NonvoluntaryCtxtSwitchesDiff) }},
+	"cpuwait": {number: func(m *Monitor, p *Process) float64 { return m.DelayPercent(p.CPUDelayDiff) }},
+	"iowait":  {number: func(m *Monitor, p *Process) float64 { return m.DelayPercent(p.BlkioDelayDiff) }},
+
+	"user":      {text: func(m *Monitor, p *Process) string { return p.User.Username }},
+	"uid":       {text: func(m *Monitor, p *Process) string { return p.User.Uid }},
+	"state":     {text: func(m *Monitor, p *Process) string { return string(p.State) }},
+	"name":      {text: func(m *Monitor, p *Process) string { return p.Name }},
+	"command":   {text: func(m *Monitor, p *Process) string { return p.Command }},
+	"comm":      {text: func(m *Monitor, p *Process) string { return p.Comm }},
+	"exe":       {text: func(m *Monitor, p *Process) string { return p.Exe }},
+	"container": {text: func(m *Monitor, p *Process) string { return p.ContainerName() }},
+	"cgroup":    {text: func(m *Monitor, p *Process) string { return p.Cgroup }},
+	"unit":      {text: func(m *Monitor, p *Process) string { return p.Unit }},
+}
+
+// FilterFields returns the names of the fields a Filter can compare,
+// sorted.
+func FilterFields() []string {
+	var names []string
+	for name := range filterFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFilter parses a filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !parser.done() {
+		return nil, fmt.Errorf("unexpected %q", parser.peek())
+	}
+	return &Filter{expr, root}, nil
+}
+
+// Match returns whether or not a process of the Monitor matches the Filter.
+func (f *Filter) Match(m *Monitor, p *Process) bool {
+	return f.root.match(m, p)
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+type filterNode interface {
+	match(m *Monitor, p *Process) bool
+}
+
+type filterAnd struct{ left, right filterNode }
+type filterOr struct{ left, right filterNode }
+type filterNot struct{ node filterNode }
+
+func (n filterAnd) match(m *Monitor, p *Process) bool {
+	return n.left.match(m, p) && n.right.match(m, p)
+}
+
+func (n filterOr) match(m *Monitor, p *Process) bool {
+	return n.left.match(m, p) || n.right.match(m, p)
+}
+
+func (n filterNot) match(m *Monitor, p *Process) bool {
+	return !n.node.match(m, p)
+}
+
+// filterComparison compares a field to a value.
+type filterComparison struct {
+	field  filterField
+	op     string
+	number float64
+	text   string
+	regexp *regexp.Regexp
+}
+
+func (n *filterComparison) match(m *Monitor, p *Process) bool {
+	if n.field.number != nil {
+		v := n.field.number(m, p)
+		switch n.op {
+		case "==":
+			return v == n.number
+		case "!=":
+			return v != n.number
+		case "<":
+			return v < n.number
+		case "<=":
+			return v <= n.number
+		case ">":
+			return v > n.number
+		case ">=":
+			return v >= n.number
+		}
+		return false
+	}
+
+	v := n.field.text(m, p)
+	switch n.op {
+	case "==":
+		return v == n.text
+	case "!=":
+		return v != n.text
+	case "=~":
+		return n.regexp.MatchString(v)
+	case "!~":
+		return !n.regexp.MatchString(v)
+	}
+	return false
+}
+
+// filterToken is a token of a filter expression. Quoted strings are always
+// values, even if they look like operators.
+type filterToken struct {
+	text   string
+	quoted bool
+
+	// start and end are the offsets of the token in the expression,
+	// including its quotes.
+	start, end int
+}
+
+// isOperator returns whether or not a token is an operator, which can't be
+// a value unless it's quoted.
+func (t filterToken) isOperator() bool {
+	if t.quoted {
+		return false
+	}
+	for _, op := range filterOperators {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+var filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "=", "!", "(", ")"}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := skipFilterSpaces(expr, 0); i < len(expr); i = skipFilterSpaces(expr, i) {
+		token, err := scanFilterToken(expr, i)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+		i = token.end
+	}
+	return tokens, nil
+}
+
+// scanFilterToken returns the token starting at the offset i of an
+// expression, which mustn't be a space.
+func scanFilterToken(expr string, i int) (filterToken, error) {
+	if c := expr[i]; c == '"' || c == '\'' {
+		end := strings.IndexByte(expr[i+1:], c)
+		if end == -1 {
+			return filterToken{}, fmt.Errorf("unterminated string at %d", i)
+		}
+		return filterToken{expr[i+1 : i+1+end], true, i, i + end + 2}, nil
+	}
+	for _, op := range filterOperators {
+		if strings.HasPrefix(expr[i:], op) {
+			return filterToken{op, false, i, i + len(op)}, nil
+		}
+	}
+	// A bare word ends at a space or an operator.
+	end := i
+	for end < len(expr) && !unicode.IsSpace(rune(expr[end])) && !strings.ContainsRune("&|=!<>()\"'", rune(expr[end])) {
+		end++
+	}
+	return filterToken{expr[i:end], false, i, end}, nil
+}
+
+// skipFilterSpaces returns the offset of the first character of an
+// expression from the offset i that isn't a space.
+func skipFilterSpaces(expr string, i int) int {
+	for i < len(expr) && unicode.IsSpace(rune(expr[i])) {
+		i++
+	}
+	return i
+}
+
+type filterParser struct {
+	tokens []filterToken
+	next   int
+}
+
+func (p *filterParser) done() bool {
+	return p.next >= len(p.tokens)
+}
+
+func (p *filterParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.next].text
+}
+
+// accept consumes the next token if it's the operator op.
+func (p *filterParser) accept(op string) bool {
+	if p.done() || p.tokens[p.next].quoted || p.tokens[p.next].text != op {
+		return false
+	}
+	p.next++
+	return true
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	switch {
+	case p.accept("!"):
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	case p.accept("("):
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	if p.done() {
+		return nil, fmt.Errorf("missing field")
+	}
+	name := strings.ToLower(p.tokens[p.next].text)
+	field, ok := filterFields[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q (%s)", p.tokens[p.next].text, strings.Join(FilterFields(), ", "))
+	}
+	p.next++
+
+	op := ""
+	for _, o := range []string{"==", "!=", "<=", ">=", "=~", "!~", "<", ">", "="} {
+		if p.accept(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("missing operator after %s", name)
+	}
+	if op == "=" {
+		op = "=="
+	}
+	if p.done() || p.tokens[p.next].isOperator() {
+		return nil, fmt.Errorf("missing value after %s %s", name, op)
+	}
+	value := p.tokens[p.next].text
+	p.next++
+
+	n := &filterComparison{field: field, op: op, text: value}
+	if field.number != nil {
+		if op == "=~" || op == "!~" {
+			return nil, fmt.Errorf("%s is a number, it can't be matched with %s", name, op)
+		}
+		number, err := ParseNumber(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number", name, value)
+		}
+		n.number = number
+		return n, nil
+	}
+
+	switch op {
+	case "<", "<=", ">", ">=":
+		return nil, fmt.Errorf("%s is a string, it can't be compared with %s", name, op)
+	case "=~", "!~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		n.regexp = re
+	}
+	return n, nil
+}
+
+// ParseNumber parses a number like the values of a Filter, with an optional
+// K, M, G or T suffix, which can be followed by B.
+func ParseNumber(s string) (float64, error) {
+	multiplier := 1.0
+	if len(s) > 1 && strings.ContainsRune("KMGTkmgt", rune(s[len(s)-2])) && (s[len(s)-1] == 'B' || s[len(s)-1] == 'b') {
+		s = s[:len(s)-1]
+	}
+	if s != "" {
+		switch unicode.ToUpper(rune(s[len(s)-1])) {
+		case 'K':
+			multiplier = KB
+		case 'M':
+			multiplier = MB
+		case 'G':
+			multiplier = GB
+		case 'T':
+			multiplier = TB
+		}
+		if multiplier != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	number, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return number * multiplier, err
+}
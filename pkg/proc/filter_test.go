@@ -0,0 +1,150 @@
+package proc
+
+import (
+	"os/user"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"1.5", 1.5, true},
+		{"50%", 50, true},
+		{"2K", 2 * KB, true},
+		{"2k", 2 * KB, true},
+		{"2KB", 2 * KB, true},
+		{"1.5M", 1.5 * MB, true},
+		{"3G", 3 * GB, true},
+		{"1T", TB, true},
+		{"1Mb", MB, true},
+		{"", 0, false},
+		{"K", 0, false},
+		{"1B", 0, false},
+		{"x", 0, false},
+		{"1X", 0, false},
+	}
+	for _, test := range tests {
+		got, err := ParseNumber(test.s)
+		if (err == nil) != test.ok || (test.ok && got != test.want) {
+			t.Errorf("ParseNumber(%q) = %v, %v, want %v, ok %v", test.s, got, err, test.want, test.ok)
+		}
+	}
+}
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []filterToken
+		err  string
+	}{
+		{"", nil, ""},
+		{"cpu>50", []filterToken{{"cpu", false, 0, 3}, {">", false, 3, 4}, {"50", false, 4, 6}}, ""},
+		{" cpu >= 5K ", []filterToken{{"cpu", false, 1, 4}, {">=", false, 5, 7}, {"5K", false, 8, 10}}, ""},
+		{"!(a||b)&&c", []filterToken{{"!", false, 0, 1}, {"(", false, 1, 2}, {"a", false, 2, 3}, {"||", false, 3, 5}, {"b", false, 5, 6}, {")", false, 6, 7}, {"&&", false, 7, 9}, {"c", false, 9, 10}}, ""},
+		{"name=~'^a b'", []filterToken{{"name", false, 0, 4}, {"=~", false, 4, 6}, {"^a b", true, 6, 12}}, ""},
+		{`user == "&&"`, []filterToken{{"user", false, 0, 4}, {"==", false, 5, 7}, {"&&", true, 8, 12}}, ""},
+		{"name != x!~y", []filterToken{{"name", false, 0, 4}, {"!=", false, 5, 7}, {"x", false, 8, 9}, {"!~", false, 9, 11}, {"y", false, 11, 12}}, ""},
+		{"pid\n==\t1", []filterToken{{"pid", false, 0, 3}, {"==", false, 4, 6}, {"1", false, 7, 8}}, ""},
+		{"name == 'x", nil, "unterminated string at 8"},
+		{`name == "x'`, nil, "unterminated string at 8"},
+	}
+	for _, test := range tests {
+		got, err := tokenizeFilter(test.expr)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("tokenizeFilter(%q) error = %v, want %q", test.expr, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tokenizeFilter(%q) = %v, %v, want %v", test.expr, got, err, test.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"", "missing field"},
+		{"foo > 1", `unknown field "foo"`},
+		{"cpu", "missing operator after cpu"},
+		{"cpu >", "missing value after cpu >"},
+		{"cpu > )", "missing value after cpu >"},
+		{"user == &&", "missing value after user =="},
+		{"user == ||", "missing value after user =="},
+		{"user = (", "missing value after user =="},
+		{"cpu > x", `cpu: "x" is not a number`},
+		{"cpu =~ 1", "cpu is a number, it can't be matched with =~"},
+		{"user < root", "user is a string, it can't be compared with <"},
+		{"name =~ '('", "error parsing regexp"},
+		{"(cpu > 1", "missing )"},
+		{"cpu > 1 )", `unexpected ")"`},
+		{"cpu > 1 user == root", `unexpected "user"`},
+		{"cpu > 1 &&", "missing field"},
+		{"!", "missing field"},
+	}
+	for _, test := range tests {
+		_, err := ParseFilter(test.expr)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseFilter(%q) error = %v, want %q", test.expr, err, test.err)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	m := &Monitor{MemTotal: 1000 * 4096, PageSize: 4096}
+	p := &Process{
+		Pid:     42,
+		Ppid:    1,
+		Name:    "nginx",
+		Command: "/usr/sbin/nginx -g daemon off;",
+		User:    &user.User{Username: "www-data", Uid: "33"},
+		State:   'S',
+		RSS:     250,
+		Nice:    -5,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"pid == 42", true},
+		{"pid = 42", true},
+		{"pid != 42", false},
+		{"ppid < 2 && pid > 40", true},
+		{"rss >= 1M", false},
+		{"rss >= 1000K", true},
+		{"mem == 25", true},
+		{"nice < 0", true},
+		{"NAME == nginx", true},
+		{"user == 'www-data'", true},
+		{"user != www-data || uid == 33", true},
+		{"command =~ '^/usr/sbin/'", true},
+		{"command !~ daemon", false},
+		{"name == '&&'", false},
+		{"!(state == S)", false},
+		{"state == R || (name =~ ngi && !(pid < 10))", true},
+		{"pid == 1 || pid == 2 && pid == 42", false},
+		{"pid == 42 || pid == 2 && pid == 3", true},
+	}
+	for _, test := range tests {
+		f, err := ParseFilter(test.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", test.expr, err)
+			continue
+		}
+		if got := f.Match(m, p); got != test.want {
+			t.Errorf("%q matches %v, want %v", test.expr, got, test.want)
+		}
+		if f.String() != test.expr {
+			t.Errorf("String() = %q, want %q", f.String(), test.expr)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package proc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	drmDevicePrefix = "/dev/dri/"
+	drmClassDir     = "/sys/class/drm"
+)
+
+// GPU contains the usage of a graphics card.
+type GPU struct {
+	Name string
+
+	// Busy is the percentage of time the GPU was busy, or -1 if unknown.
+	Busy     float64
+	MemUsed  uint64
+	MemTotal uint64
+}
+
+// readDRMFdinfo sets GPUTime and GPUMemory from the DRM client usage stats
+// in /proc/<pid>/fdinfo of the GPU device files the process has open. See
+// Documentation/gpu/drm-usage-stats.rst in the kernel.
+func (p *Process) readDRMFdinfo(buf *readBuffer) error {
+	// Unknown rather than those of the last update, nvidia-smi's usage is
+	// added to them.
+	p.GPUTimeDiff, p.GPUMemory = 0, 0
+	dir := fmt.Sprintf("/proc/%d/fd", p.Pid)
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	names, err := file.Readdirnames(-1)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	var gpuTime, gpuMemory uint64
+	// Several file descriptors can refer to the same DRM client.
+	seen := make(map[string]bool)
+	for _, name := range names {
+		target, err := os.Readlink(dir + "/" + name)
+		if err != nil || !strings.HasPrefix(target, drmDevicePrefix) {
+			continue
+		}
+		data, err := buf.readFile(fmt.Sprintf("/proc/%d/fdinfo/%s", p.Pid, name))
+		if err != nil {
+			continue
+		}
+		client, engines, memory := parseDRMFdinfo(data)
+		if client == "" || seen[client] {
+			continue
+		}
+		seen[client] = true
+		gpuTime += engines
+		gpuMemory += memory
+	}
+
+	lastGPUTime := p.GPUTime
+	p.GPUTime, p.GPUMemory = gpuTime, gpuMemory
+	if !p.initializing && p.GPUTime >= lastGPUTime {
+		p.GPUTimeDiff = p.GPUTime - lastGPUTime
+	} else {
+		p.GPUTimeDiff = 0
+	}
+	return nil
+}
+
+// parseDRMFdinfo returns the client ID, the total time in nanoseconds spent
+// by every engine, and the memory in bytes, of an fdinfo file of a DRM
+// device.
+func parseDRMFdinfo(data []byte) (client string, engines, memory uint64) {
+	for _, line := range strings.Split(string(data), "\n") {
+		// line = "drm-engine-gfx:	1234 ns" or "drm-memory-vram:	56 KiB"
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		key := strings.TrimSuffix(fields[0], ":")
+		switch {
+		case key == "drm-client-id":
+			client = fields[1]
+		case strings.HasPrefix(key, "drm-engine-") && !strings.HasPrefix(key, "drm-engine-capacity-"):
+			if value, err := ParseUint64(fields[1]); err == nil {
+				engines += value
+			}
+		case strings.HasPrefix(key, "drm-memory-") || strings.HasPrefix(key, "drm-resident-"):
+			if value, err := ParseUint64(fields[1]); err == nil {
+				memory += value * drmUnit(fields)
+			}
+		}
+	}
+	return client, engines, memory
+}
+
+func drmUnit(fields []string) uint64 {
+	if len(fields) < 3 {
+		return 1
+	}
+	switch fields[2] {
+	case "KiB":
+		return KB
+	case "MiB":
+		return MB
+	}
+	return 1
+}
+
+// updateGPUs reads the usage of every GPU, and the usage of NVIDIA GPUs by
+// every process. The two nvidia-smi commands run concurrently, pmon taking
+// about a second to sample the processes.
+func (m *Monitor) updateGPUs() error {
+	m.GPUs = readDRMGPUs()
+
+	if m.nvidiaSMI == "" {
+		return nil
+	}
+	type pmonResult struct {
+		usage map[uint64]nvidiaUsage
+		err   error
+	}
+	pmon := make(chan pmonResult, 1)
+	go func() {
+		usage, err := queryNvidiaProcesses(m.nvidiaSMI)
+		pmon <- pmonResult{usage, err}
+	}()
+
+	gpus, err := queryNvidiaGPUs(m.nvidiaSMI)
+	result := <-pmon
+	if err != nil {
+		return err
+	}
+	m.GPUs = append(m.GPUs, gpus...)
+	if result.err != nil {
+		return result.err
+	}
+	for pid, usage := range result.usage {
+		if p, ok := m.Map[pid]; ok {
+			p.GPUMemory += usage.memory
+			// The percentage of time the SMs were busy with the process,
+			// as the time they spent on it.
+			p.GPUTimeDiff += uint64(usage.sm / 100 * float64(m.Interval.Nanoseconds()))
+		}
+	}
+	return nil
+}
+
+// readDRMGPUs returns the GPUs whose driver reports their usage in sysfs,
+// which amdgpu does.
+func readDRMGPUs() []*GPU {
+	cards, _ := filepath.Glob(drmClassDir + "/card[0-9]*")
+	var gpus []*GPU
+	for _, card := range cards {
+		if strings.Contains(filepath.Base(card), "-") {
+			continue // connectors such as card0-HDMI-A-1
+		}
+		device := filepath.Join(card, "device")
+		busy, ok := readSysfsUint64(filepath.Join(device, "gpu_busy_percent"))
+		if !ok {
+			continue
+		}
+		gpu := &GPU{Name: filepath.Base(card), Busy: float64(busy)}
+		if driver, err := os.Readlink(filepath.Join(device, "driver")); err == nil {
+			gpu.Name += " " + filepath.Base(driver)
+		}
+		gpu.MemUsed, _ = readSysfsUint64(filepath.Join(device, "mem_info_vram_used"))
+		gpu.MemTotal, _ = readSysfsUint64(filepath.Join(device, "mem_info_vram_total"))
+		gpus = append(gpus, gpu)
+	}
+	return gpus
+}
+
+func readSysfsUint64(path string) (uint64, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := ParseUint64(strings.TrimSpace(string(data)))
+	return value, err == nil
+}
+
+// findNvidiaSMI returns the path of nvidia-smi, the command line interface
+// of NVML, or "" if it isn't installed.
+func findNvidiaSMI() string {
+	path, err := exec.LookPath("nvidia-smi")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func queryNvidiaGPUs(nvidiaSMI string) ([]*GPU, error) {
+	records, err := queryNvidiaSMI(nvidiaSMI, "--query-gpu=index,name,utilization.gpu,memory.used,memory.total")
+	if err != nil {
+		return nil, err
+	}
+	var gpus []*GPU
+	for _, r := range records {
+		if len(r) != 5 {
+			continue
+		}
+		gpu := &GPU{Name: "nvidia" + r[0] + " " + r[1], Busy: -1}
+		if busy, err := strconv.ParseFloat(r[2], 64); err == nil {
+			gpu.Busy = busy
+		}
+		used, _ := ParseUint64(r[3])
+		total, _ := ParseUint64(r[4])
+		gpu.MemUsed, gpu.MemTotal = used*MB, total*MB
+		gpus = append(gpus, gpu)
+	}
+	return gpus, nil
+}
+
+// nvidiaUsage is the usage of NVIDIA GPUs by a process: the percentage of
+// time their streaming multiprocessors were busy with it, and the memory in
+// bytes it uses.
+type nvidiaUsage struct {
+	sm     float64
+	memory uint64
+}
+
+// queryNvidiaProcesses returns the usage of every process running on an
+// NVIDIA GPU, graphics as well as compute ones, keyed by Pid.
+func queryNvidiaProcesses(nvidiaSMI string) (map[uint64]nvidiaUsage, error) {
+	out, err := exec.Command(nvidiaSMI, "pmon", "-c", "1", "-s", "um").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi pmon: %v", err)
+	}
+	return parseNvidiaPmon(out), nil
+}
+
+// parseNvidiaPmon parses the output of nvidia-smi pmon -s um, whose columns
+// depend on the version, hence are found from its header:
+//
+//	# gpu        pid  type    sm   mem   enc   dec    fb   command
+//	# Idx          #   C/G     %     %     %     %    MB   name
+//	    0       1234     G     5     2     -     -   120   Xorg
+func parseNvidiaPmon(out []byte) map[uint64]nvidiaUsage {
+	usage := make(map[uint64]nvidiaUsage)
+	columns := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "#" {
+			if len(columns) == 0 {
+				for i, name := range fields[1:] {
+					columns[name] = i
+				}
+			}
+			continue
+		}
+		pidColumn, ok := columns["pid"]
+		if !ok || pidColumn >= len(fields) {
+			continue
+		}
+		pid, err := ParseUint64(fields[pidColumn])
+		if err != nil {
+			continue // a GPU without processes
+		}
+		u := usage[pid]
+		if i, ok := columns["sm"]; ok && i < len(fields) {
+			if sm, err := strconv.ParseFloat(fields[i], 64); err == nil {
+				u.sm += sm
+			}
+		}
+		if i, ok := columns["fb"]; ok && i < len(fields) {
+			if fb, err := ParseUint64(fields[i]); err == nil {
+				u.memory += fb * MB
+			}
+		}
+		usage[pid] = u
+	}
+	return usage
+}
+
+// queryNvidiaSMI runs an nvidia-smi query and returns its comma-separated
+// values, without units.
+func queryNvidiaSMI(nvidiaSMI, query string) ([][]string, error) {
+	out, err := exec.Command(nvidiaSMI, query, "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %v", err)
+	}
+	var records [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		records = append(records, fields)
+	}
+	return records, nil
+}
+
+type ByGPU []*Process
+
+func (p ByGPU) Len() int      { return len(p) }
+func (p ByGPU) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByGPU) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.GPUTimeDiff == p2.GPUTimeDiff {
+		if p1.GPUMemory == p2.GPUMemory {
+			return p1.Pid < p2.Pid
+		}
+		return p1.GPUMemory > p2.GPUMemory
+	}
+	return p1.GPUTimeDiff > p2.GPUTimeDiff
+}
+
+type ByGPUMemory []*Process
+
+func (p ByGPUMemory) Len() int      { return len(p) }
+func (p ByGPUMemory) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByGPUMemory) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.GPUMemory == p2.GPUMemory {
+		return p1.Pid < p2.Pid
+	}
+	return p1.GPUMemory > p2.GPUMemory
+}
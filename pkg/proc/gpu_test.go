@@ -0,0 +1,128 @@
+package proc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNvidiaPmon(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want map[uint64]nvidiaUsage
+	}{
+		{
+			name: "older driver",
+			out: `# gpu        pid  type    sm   mem   enc   dec    fb   command
+# Idx          #   C/G     %     %     %     %    MB   name
+    0       1234     G     5     2     -     -   120   Xorg
+    0       5678     C    40    10     -     -  2048   python
+    1          -     -     -     -     -     -     -   -
+`,
+			want: map[uint64]nvidiaUsage{
+				1234: {sm: 5, memory: 120 * MB},
+				5678: {sm: 40, memory: 2048 * MB},
+			},
+		},
+		{
+			name: "newer driver",
+			out: `# gpu         pid   type     sm    mem    enc    dec    jpg    ofa     fb   ccpm    command
+# Idx           #    C/G      %      %      %      %      %      %     MB     MB    name
+    0       2345     C     30      8      -      -      -      -   1024      0    python
+    0       3456   C+G      -      -      -      -      -      -     64      0    firefox
+    1       2345     C     20      4      -      -      -      -    512      0    python
+`,
+			want: map[uint64]nvidiaUsage{
+				2345: {sm: 50, memory: 1536 * MB},
+				3456: {sm: 0, memory: 64 * MB},
+			},
+		},
+		{
+			name: "no fb column",
+			out: `# gpu        pid  type    sm   mem   enc   dec   command
+# Idx          #   C/G     %     %     %     %   name
+    0       1234     G     5     2     -     -   Xorg
+`,
+			want: map[uint64]nvidiaUsage{
+				1234: {sm: 5},
+			},
+		},
+		{
+			name: "no processes",
+			out: `# gpu        pid  type    sm   mem   enc   dec    fb   command
+# Idx          #   C/G     %     %     %     %    MB   name
+    0          -     -     -     -     -     -     -   -
+`,
+			want: map[uint64]nvidiaUsage{},
+		},
+	}
+	for _, test := range tests {
+		if got := parseNvidiaPmon([]byte(test.out)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: parseNvidiaPmon() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseDRMFdinfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		fdinfo  string
+		client  string
+		engines uint64
+		memory  uint64
+	}{
+		{
+			name: "amdgpu",
+			fdinfo: `pos:	0
+flags:	02100002
+drm-driver:	amdgpu
+drm-client-id:	42
+drm-memory-vram:	1024 KiB
+drm-memory-gtt:	2 MiB
+drm-memory-cpu:	0 KiB
+drm-engine-gfx:	1000 ns
+drm-engine-compute:	500 ns
+`,
+			client:  "42",
+			engines: 1500,
+			memory:  1024*KB + 2*MB,
+		},
+		{
+			// Another file descriptor of the same client reports the
+			// same usage, which readDRMFdinfo only counts once.
+			name: "same client",
+			fdinfo: `drm-driver:	amdgpu
+drm-client-id:	42
+drm-memory-vram:	1024 KiB
+drm-engine-gfx:	1000 ns
+`,
+			client:  "42",
+			engines: 1000,
+			memory:  1024 * KB,
+		},
+		{
+			name: "i915 with capacities",
+			fdinfo: `drm-driver:	i915
+drm-client-id:	7
+drm-engine-render:	123456 ns
+drm-engine-video:	1000 ns
+drm-engine-capacity-video:	2
+drm-resident-system:	4096
+`,
+			client:  "7",
+			engines: 124456,
+			memory:  4096,
+		},
+		{
+			name:   "not a DRM client",
+			fdinfo: "pos:\t0\nflags:\t02\nmnt_id:\t24\n",
+		},
+	}
+	for _, test := range tests {
+		client, engines, memory := parseDRMFdinfo([]byte(test.fdinfo))
+		if client != test.client || engines != test.engines || memory != test.memory {
+			t.Errorf("%s: parseDRMFdinfo() = %q, %d, %d, want %q, %d, %d",
+				test.name, client, engines, memory, test.client, test.engines, test.memory)
+		}
+	}
+}
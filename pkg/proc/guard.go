@@ -0,0 +1,56 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// KillGrace is how long the processes killed by rules have to exit after
+// SIGTERM before they're sent SIGKILL.
+var KillGrace = 10 * time.Second
+
+// guard kills a process alerting according to a rule that kills. It's sent
+// SIGTERM when it starts alerting, and SIGKILL if it still does KillGrace
+// later. guard returns the time SIGTERM was sent and true while the process
+// is within KillGrace of it, or a zero time and false once SIGKILL was sent
+// or if the process is exempt.
+func (a *Alerter) guard(key alertKey, alert Alert, now time.Time) (time.Time, bool) {
+	p := alert.Process
+	if p.Pid == InitPid || p.Pid == uint64(os.Getpid()) {
+		return time.Time{}, false
+	}
+
+	t, ok := a.terminated[key]
+	switch {
+	case !a.alerting[key]:
+		a.kill(alert, syscall.SIGTERM, now)
+		return now, true
+	case ok && now.Sub(t) >= KillGrace:
+		a.kill(alert, syscall.SIGKILL, now)
+		return time.Time{}, false
+	}
+	return t, ok
+}
+
+// kill sends a signal to an alerting process and logs it to Guard.
+func (a *Alerter) kill(alert Alert, sig syscall.Signal, now time.Time) {
+	result := "sent"
+	if err := alert.Process.Signal(sig); err != nil {
+		result = err.Error()
+	}
+	fmt.Fprintf(a.Guard, "%s %s %s user=%s command=%q rule=%q: %s\n",
+		now.Format(time.RFC3339), signalName(sig), alert.Process, alert.Process.User.Username,
+		alert.Process.Command, alert.Rule, result)
+}
+
+func signalName(sig syscall.Signal) string {
+	switch sig {
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	case syscall.SIGKILL:
+		return "SIGKILL"
+	}
+	return sig.String()
+}
@@ -0,0 +1,114 @@
+package proc
+
+import (
+	"math"
+	"time"
+)
+
+const processHistorySize = 60
+
+// SystemHistorySize is the number of samples a SystemHistory keeps.
+const SystemHistorySize = 300
+
+// SystemHistory keeps the most recent samples of the system wide CPU, memory
+// and swap usage of a Monitor, which is sampled at every update so it's the
+// one of the machine or recording displayed.
+type SystemHistory struct {
+	cpu   *Ring // percentage of all CPUs
+	mem   *Ring // percentage of MemTotal
+	swap  *Ring // percentage of SwapTotal
+	times *Ring // LastUpdate of the samples in Unix seconds
+}
+
+// NewSystemHistory returns an empty SystemHistory.
+func NewSystemHistory() *SystemHistory {
+	h := &SystemHistory{}
+	h.Reset()
+	return h
+}
+
+// Reset discards every sample, e.g. when another Monitor is displayed.
+func (h *SystemHistory) Reset() {
+	h.cpu = NewRing(SystemHistorySize)
+	h.mem = NewRing(SystemHistorySize)
+	h.swap = NewRing(SystemHistorySize)
+	h.times = NewRing(SystemHistorySize)
+}
+
+// Add adds a sample of the last update of the Monitor.
+func (h *SystemHistory) Add(m *Monitor) {
+	swap := 0.0
+	if swapTotal := m.Meminfo["SwapTotal"]; swapTotal > 0 {
+		swap = 100 * float64(swapTotal-m.Meminfo["SwapFree"]) / float64(swapTotal)
+	}
+	h.cpu.Add(m.SystemCPUPercent())
+	h.mem.Add(m.MemPercent())
+	h.swap.Add(swap)
+	h.times.Add(float64(m.LastUpdate.UnixNano()) / float64(time.Second))
+}
+
+// Values returns the CPU, memory and swap usage samples in percent, from
+// oldest to newest.
+func (h *SystemHistory) Values() (cpu, mem, swap []float64) {
+	return h.cpu.Values(), h.mem.Values(), h.swap.Values()
+}
+
+// Span returns the time between the oldest and the newest sample.
+func (h *SystemHistory) Span() time.Duration {
+	times := h.times.Values()
+	if len(times) < 2 {
+		return 0
+	}
+	return time.Duration((times[len(times)-1] - times[0]) * float64(time.Second))
+}
+
+// Ring holds the most recent samples of a value, discarding the oldest
+// sample once it's full.
+type Ring struct {
+	samples []float64
+	next    int
+	full    bool
+}
+
+// NewRing returns a Ring that holds up to size samples.
+func NewRing(size int) *Ring {
+	return &Ring{samples: make([]float64, size)}
+}
+
+// Add adds a sample, replacing the oldest sample if the Ring is full.
+func (r *Ring) Add(v float64) {
+	r.samples[r.next] = v
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Values returns the samples from oldest to newest.
+func (r *Ring) Values() []float64 {
+	if !r.full {
+		return append([]float64{}, r.samples[:r.next]...)
+	}
+	return append(append([]float64{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// Max returns the largest sample, or 0 if there are no samples.
+func (r *Ring) Max() float64 {
+	max := 0.0
+	for _, v := range r.Values() {
+		max = math.Max(max, v)
+	}
+	return max
+}
+
+// recordHistory records the current CPU usage and RSS of every process.
+func (m *Monitor) recordHistory() {
+	for _, p := range m.List {
+		if p.CPUHistory == nil {
+			p.CPUHistory = NewRing(processHistorySize)
+			p.RSSHistory = NewRing(processHistorySize)
+		}
+		p.CPUHistory.Add(m.cpuPercent(p, false))
+		p.RSSHistory.Add(float64(p.RSS * m.PageSize))
+	}
+}
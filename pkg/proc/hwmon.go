@@ -0,0 +1,95 @@
+package proc
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const hwmonClassDir = "/sys/class/hwmon"
+
+// Sensor is a temperature sensor or a fan of a hardware monitoring chip.
+type Sensor struct {
+	Chip  string // coretemp
+	Label string // Package id 0
+
+	// Value is in degrees Celsius for temperatures and RPM for fans.
+	Value float64
+	// Max and Crit are the thresholds reported by the chip for
+	// temperatures, or 0 if there are none.
+	Max  float64
+	Crit float64
+}
+
+// updateSensors reads the temperatures and fan speeds from hwmon.
+func (m *Monitor) updateSensors() error {
+	chips, err := filepath.Glob(hwmonClassDir + "/hwmon[0-9]*")
+	if err != nil {
+		return err
+	}
+	sort.Sort(byHwmonIndex(chips))
+
+	m.Temps, m.Fans = nil, nil
+	for _, chip := range chips {
+		name := readSysfsString(filepath.Join(chip, "name"))
+		if name == "" {
+			name = filepath.Base(chip)
+		}
+		m.Temps = append(m.Temps, readHwmonSensors(chip, name, "temp", 1000)...)
+		m.Fans = append(m.Fans, readHwmonSensors(chip, name, "fan", 1)...)
+	}
+	return nil
+}
+
+// readHwmonSensors returns the sensors of a chip whose files start with
+// prefix, such as temp1_input, where the values are in units of divisor.
+func readHwmonSensors(chip, name, prefix string, divisor float64) []*Sensor {
+	inputs, _ := filepath.Glob(filepath.Join(chip, prefix+"[0-9]*_input"))
+	sort.Sort(byHwmonIndex(inputs))
+
+	var sensors []*Sensor
+	for _, input := range inputs {
+		base := strings.TrimSuffix(input, "_input") // .../temp1
+		value, ok := readSysfsUint64(input)
+		if !ok {
+			continue // the sensor is disabled or faulty
+		}
+		s := &Sensor{
+			Chip:  name,
+			Label: readSysfsString(base + "_label"),
+			Value: float64(value) / divisor,
+		}
+		if s.Label == "" {
+			s.Label = filepath.Base(base)
+		}
+		if max, ok := readSysfsUint64(base + "_max"); ok {
+			s.Max = float64(max) / divisor
+		}
+		if crit, ok := readSysfsUint64(base + "_crit"); ok {
+			s.Crit = float64(crit) / divisor
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors
+}
+
+func readSysfsString(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// byHwmonIndex sorts paths such as hwmon10 after hwmon9.
+type byHwmonIndex []string
+
+func (p byHwmonIndex) Len() int      { return len(p) }
+func (p byHwmonIndex) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byHwmonIndex) Less(i, j int) bool {
+	if len(p[i]) == len(p[j]) {
+		return p[i] < p[j]
+	}
+	return len(p[i]) < len(p[j])
+}
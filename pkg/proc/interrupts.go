@@ -0,0 +1,139 @@
+package proc
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Interrupt contains the counts of a hardware interrupt or of a softirq on
+// each CPU, from /proc/interrupts or /proc/softirqs.
+type Interrupt struct {
+	// Name is the IRQ number, or the name of an architecture specific
+	// interrupt or of a softirq, like LOC or NET_RX.
+	Name string
+	// Description is the controller, the trigger and the devices of an
+	// interrupt, or the description of an architecture specific one.
+	Description string
+	Softirq     bool
+
+	Counts     []uint64
+	CountsDiff []uint64
+	Total      uint64
+	TotalDiff  uint64
+
+	alive bool
+}
+
+// parseInterruptFiles updates Interrupts from /proc/interrupts and
+// /proc/softirqs.
+func (m *Monitor) parseInterruptFiles() error {
+	for _, irq := range m.Interrupts {
+		irq.alive = false
+	}
+	err := m.parseInterruptFile("/proc/interrupts", false)
+	if softirqErr := m.parseInterruptFile("/proc/softirqs", true); err == nil {
+		err = softirqErr
+	}
+
+	for i := len(m.Interrupts) - 1; i >= 0; i-- {
+		if irq := m.Interrupts[i]; !irq.alive {
+			m.Interrupts = append(m.Interrupts[:i], m.Interrupts[i+1:]...)
+			delete(m.interruptMap, interruptKey(irq.Name, irq.Softirq))
+		}
+	}
+	return err
+}
+
+func interruptKey(name string, softirq bool) string {
+	if softirq {
+		return "softirq " + name
+	}
+	return name
+}
+
+func (m *Monitor) parseInterruptFile(path string, softirq bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	// header = "           CPU0       CPU1"
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	cpus := len(strings.Fields(scanner.Text()))
+
+	for scanner.Scan() {
+		// line = " 36:      77290      1234  PCI-MSIX-0000:00:02.0   1-edge      virtio1-req.0"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		name := strings.TrimSuffix(fields[0], ":")
+		values := fields[1:]
+
+		counts := make([]uint64, 0, cpus)
+		for _, value := range values {
+			if len(counts) == cpus {
+				break
+			}
+			count, err := ParseUint64(value)
+			if err != nil {
+				break
+			}
+			counts = append(counts, count)
+		}
+		description := strings.Join(values[len(counts):], " ")
+
+		key := interruptKey(name, softirq)
+		irq, ok := m.interruptMap[key]
+		if !ok {
+			irq = &Interrupt{Name: name, Softirq: softirq}
+			m.interruptMap[key] = irq
+			m.Interrupts = append(m.Interrupts, irq)
+		}
+		irq.Description = description
+		irq.CountsDiff = make([]uint64, len(counts))
+		var total uint64
+		for i, count := range counts {
+			if ok && i < len(irq.Counts) && count >= irq.Counts[i] {
+				irq.CountsDiff[i] = count - irq.Counts[i]
+			}
+			total += count
+		}
+		irq.TotalDiff = 0
+		if ok && total >= irq.Total {
+			irq.TotalDiff = total - irq.Total
+		}
+		irq.Counts, irq.Total = counts, total
+		irq.alive = true
+	}
+	return scanner.Err()
+}
+
+// ByInterruptRate sorts the interrupts that were raised the most since the
+// last update first.
+type ByInterruptRate []*Interrupt
+
+func (s ByInterruptRate) Len() int      { return len(s) }
+func (s ByInterruptRate) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByInterruptRate) Less(i, j int) bool {
+	if s[i].TotalDiff == s[j].TotalDiff {
+		return s[i].Total > s[j].Total
+	}
+	return s[i].TotalDiff > s[j].TotalDiff
+}
+
+// ByInterruptTotal sorts the interrupts that were raised the most since boot
+// first.
+type ByInterruptTotal []*Interrupt
+
+func (s ByInterruptTotal) Len() int      { return len(s) }
+func (s ByInterruptTotal) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByInterruptTotal) Less(i, j int) bool {
+	return s[i].Total > s[j].Total
+}
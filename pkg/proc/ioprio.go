@@ -0,0 +1,99 @@
+package proc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IOClass is the I/O scheduling class of a process, see ionice(1).
+type IOClass int
+
+const (
+	// IOClassNone is the class of the processes whose I/O priority was
+	// never set, which is derived from their nice value.
+	IOClassNone IOClass = iota
+	IOClassRealtime
+	IOClassBestEffort
+	IOClassIdle
+)
+
+var ioClassNames = []string{"none", "rt", "be", "idle"}
+
+// The values of ioprio_get and ioprio_set, see include/uapi/linux/ioprio.h.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioLevelMask  = 1<<ioprioClassShift - 1
+
+	// IOLevels is the number of levels of the realtime and best-effort
+	// classes, 0 being the highest priority.
+	IOLevels = 8
+)
+
+// IOPriority is the I/O scheduling class and level of a process.
+type IOPriority struct {
+	Class IOClass
+	Level int
+}
+
+// String formats the priority like the argument of ParseIOPriority.
+func (p IOPriority) String() string {
+	switch p.Class {
+	case IOClassRealtime, IOClassBestEffort:
+		return fmt.Sprintf("%s/%d", ioClassNames[p.Class], p.Level)
+	case IOClassNone, IOClassIdle:
+		return ioClassNames[p.Class]
+	}
+	return "?"
+}
+
+// ParseIOPriority parses an I/O priority like rt/0, be/4 or idle. A class
+// without a level is at level 4, like ionice does.
+func ParseIOPriority(s string) (IOPriority, error) {
+	name, level := s, "4"
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		name, level = s[:i], s[i+1:]
+	}
+	for class, className := range ioClassNames {
+		if !strings.EqualFold(name, className) {
+			continue
+		}
+		p := IOPriority{Class: IOClass(class)}
+		if p.Class == IOClassRealtime || p.Class == IOClassBestEffort {
+			n, err := strconv.Atoi(level)
+			if err != nil || n < 0 || n >= IOLevels {
+				return IOPriority{}, fmt.Errorf("the level of %s must be between 0 and %d", name, IOLevels-1)
+			}
+			p.Level = n
+		}
+		return p, nil
+	}
+	return IOPriority{}, fmt.Errorf("%q isn't an I/O priority (rt/0-7, be/0-7, idle or none)", s)
+}
+
+// effectiveIOPriority returns the class and level the I/O scheduler uses
+// for a Process, which are derived from its nice value for IOClassNone.
+func (p *Process) effectiveIOPriority() IOPriority {
+	if p.IOPriority.Class == IOClassNone {
+		return IOPriority{IOClassBestEffort, (p.Nice + 20) / 5}
+	}
+	return p.IOPriority
+}
+
+// ByIOPriority sorts the processes with the highest effective I/O priority
+// first.
+type ByIOPriority []*Process
+
+func (p ByIOPriority) Len() int      { return len(p) }
+func (p ByIOPriority) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByIOPriority) Less(i, j int) bool {
+	p1, p2 := p[i].effectiveIOPriority(), p[j].effectiveIOPriority()
+	switch {
+	case p1.Class != p2.Class:
+		return p1.Class < p2.Class
+	case p1.Level != p2.Level:
+		return p1.Level < p2.Level
+	}
+	return p[i].Pid < p[j].Pid
+}
@@ -0,0 +1,31 @@
+package proc
+
+import "syscall"
+
+// readIOPriority sets the IOPriority of the main thread of the process. It's
+// left as is if the process exited in the meantime.
+func (p *Process) readIOPriority() {
+	prio, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(p.Pid), 0)
+	if errno != 0 {
+		return
+	}
+	p.IOPriority = IOPriority{IOClass(prio >> ioprioClassShift), int(prio & ioprioLevelMask)}
+}
+
+// SetIOPriority sets the I/O priority of every thread of the process, like
+// ionice does for each of them. Raising it to the realtime class requires
+// CAP_SYS_ADMIN, and lowering the level of the best-effort class requires
+// CAP_SYS_NICE.
+func (p *Process) SetIOPriority(prio IOPriority) error {
+	value := uintptr(prio.Class)<<ioprioClassShift | uintptr(prio.Level)
+
+	for _, tid := range p.threadIDs() {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), value)
+		// A thread that exited in the meantime doesn't matter.
+		if errno != 0 && errno != syscall.ESRCH {
+			return errno
+		}
+	}
+	p.IOPriority = prio
+	return nil
+}
@@ -0,0 +1,122 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxLogEvents is the number of events kept in Monitor.Log.
+const MaxLogEvents = 200
+
+// ProcessEvent is a process that started or exited, see Monitor.Log.
+type ProcessEvent struct {
+	Time    time.Time
+	Pid     uint64
+	Name    string
+	Command string
+
+	// Started and Exited are both set for the processes that started and
+	// exited between two updates, which are only known from the proc
+	// connector, see Monitor.ProcEvents.
+	Started bool
+	Exited  bool
+
+	// Lifetime is how long the process ran, if it exited after being
+	// displayed. It's truncated to the second, the precision of the boot
+	// time.
+	Lifetime time.Duration
+
+	// Status is the exit status of the process as returned by wait, if it
+	// exited and the proc connector reported it.
+	Status    syscall.WaitStatus
+	HasStatus bool
+}
+
+// ExitString describes how the process exited like os.ProcessState, such
+// as "exit status 1" or "signal: killed", or returns "" if that's unknown.
+func (e ProcessEvent) ExitString() string {
+	switch {
+	case !e.HasStatus:
+		return ""
+	case e.Status.Signaled():
+		return "signal: " + e.Status.Signal().String()
+	default:
+		return fmt.Sprintf("exit status %d", e.Status.ExitStatus())
+	}
+}
+
+// logEvent appends an event to the Log, dropping the oldest one if it's
+// full.
+func (m *Monitor) logEvent(e ProcessEvent) {
+	if len(m.Log) >= MaxLogEvents {
+		m.Log = append(m.Log[:0], m.Log[len(m.Log)-MaxLogEvents+1:]...)
+	}
+	m.Log = append(m.Log, e)
+}
+
+// logStarted logs a process that was just added, unless it's the first
+// update, where every process is new.
+func (m *Monitor) logStarted(p *Process) {
+	if m.Interval == 0 {
+		return
+	}
+	m.logEvent(ProcessEvent{Time: m.LastUpdate, Pid: p.Pid, Name: p.Name, Command: p.Command, Started: true})
+}
+
+// logExited logs a process that was just removed.
+func (m *Monitor) logExited(p *Process) {
+	e := ProcessEvent{
+		Time:     m.LastUpdate,
+		Pid:      p.Pid,
+		Name:     p.Name,
+		Command:  p.Command,
+		Exited:   true,
+		Lifetime: m.LastUpdate.Sub(m.StartTime(p)).Truncate(time.Second),
+	}
+	if status, ok := m.exitCodes[p.Pid]; ok {
+		e.Status, e.HasStatus = waitStatus(status), true
+	}
+	m.logEvent(e)
+}
+
+// logShortLived logs the processes that exec'd and exited since the last
+// update according to the proc connector, which were never displayed.
+func (m *Monitor) logShortLived() {
+	var pids []uint64
+	for pid := range m.execCommands {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+
+	for _, pid := range pids {
+		command := m.execCommands[pid]
+		status, ok := m.exitCodes[pid]
+		if _, displayed := m.Map[pid]; !ok || displayed || command == "" || !m.pidWhitelisted(pid) {
+			continue
+		}
+		m.logEvent(ProcessEvent{
+			Time:      m.LastUpdate,
+			Pid:       pid,
+			Name:      commandToName(command),
+			Command:   command,
+			Started:   true,
+			Exited:    true,
+			Status:    waitStatus(status),
+			HasStatus: true,
+		})
+	}
+	m.execCommands, m.exitCodes = nil, nil
+}
+
+// readCommand returns the command line of a process.
+func readCommand(pid uint64) (string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.Replace(string(data), "\x00", " ", -1)), nil
+}
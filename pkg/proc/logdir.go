@@ -0,0 +1,143 @@
+package proc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The files of a SnapshotLog are named after the time they were started at,
+// like jtop-20060102-150405.gob.gz, so their names sort chronologically.
+const (
+	logFilePrefix = "jtop-"
+	logFileSuffix = ".gob.gz"
+	logFileTime   = "20060102-150405"
+
+	// logFileSpan is how long a file of a SnapshotLog is written to before
+	// the next one is started.
+	logFileSpan = time.Hour
+)
+
+// SnapshotLog appends a compressed Snapshot to the files of a directory at
+// every update, like sar, so the recent history of the machine can be
+// replayed after an incident. A file is started every hour, and the files
+// that haven't been written to for longer than Keep are removed.
+type SnapshotLog struct {
+	Dir  string
+	Keep time.Duration
+
+	file    *os.File
+	gz      *gzip.Writer
+	encoder *gob.Encoder
+	started time.Time
+}
+
+// NewSnapshotLog returns a SnapshotLog writing to dir, which is created if it
+// doesn't exist.
+func NewSnapshotLog(dir string, keep time.Duration) (*SnapshotLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &SnapshotLog{Dir: dir, Keep: keep}, nil
+}
+
+// Record appends a Snapshot of the Monitor to the current file, starting the
+// next one when the hour changed. The file is flushed after every Snapshot
+// so it's usable even if jtop doesn't exit cleanly.
+func (l *SnapshotLog) Record(m *Monitor) error {
+	s := m.Snapshot()
+	if l.file == nil || !s.Time.Truncate(logFileSpan).Equal(l.started.Truncate(logFileSpan)) {
+		if err := l.rotate(s.Time); err != nil {
+			return err
+		}
+	}
+	if err := l.encoder.Encode(s); err != nil {
+		return err
+	}
+	return l.gz.Flush()
+}
+
+// rotate closes the current file, starts the next one and removes the old
+// ones. Every file has its own gob stream, since a decoder can't read the
+// types of a stream sent again after jtop is restarted.
+func (l *SnapshotLog) rotate(now time.Time) error {
+	if err := l.Close(); err != nil {
+		return err
+	}
+	path := filepath.Join(l.Dir, logFilePrefix+now.Format(logFileTime)+logFileSuffix)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	l.file, l.started = file, now
+	l.gz = gzip.NewWriter(file)
+	l.encoder = gob.NewEncoder(l.gz)
+	return l.prune(now)
+}
+
+// prune removes the files of the directory that haven't been written to for
+// longer than Keep.
+func (l *SnapshotLog) prune(now time.Time) error {
+	paths, err := logFiles(l.Dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || now.Sub(info.ModTime()) <= l.Keep || path == l.file.Name() {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *SnapshotLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.gz.Close()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// logFiles returns the paths of the files of a SnapshotLog in a directory,
+// oldest first.
+func logFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Mode().IsRegular() && strings.HasPrefix(name, logFilePrefix) && strings.HasSuffix(name, logFileSuffix) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// recordingReader returns a reader of the Snapshots of a recording, which
+// are decompressed if it's one of the files of a SnapshotLog.
+func recordingReader(file *os.File) (io.Reader, error) {
+	r := bufio.NewReader(file)
+	// The magic number of gzip, see RFC 1952.
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(r)
+	}
+	return r, nil
+}
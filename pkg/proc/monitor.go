@@ -0,0 +1,679 @@
+package proc
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	InitPid     uint64 = 1
+	KthreaddPid uint64 = 2
+)
+
+const defaultClockTicks = 100
+
+const (
+	_  = iota
+	KB = 1 << (10 * iota)
+	MB
+	GB
+	TB
+	PB
+)
+
+// SortKey is a value the process list can be sorted by.
+type SortKey int
+
+const (
+	SortByNone SortKey = iota
+	SortByPid
+	SortByUser
+	SortByRSS
+	SortByCPU
+	SortByTime
+	SortByState
+	SortByName
+	SortByDiskRead
+	SortByDiskWrite
+	SortByContainer
+	SortByNetRx
+	SortByNetTx
+	SortByGPU
+	SortByGPUMemory
+	SortByWakeups
+	SortBySwap
+	SortByMajflt
+	SortByMinflt
+	SortByStart
+	SortByNice
+	SortBySecurityContext
+	SortByPidNamespace
+	SortByNetNamespace
+	SortByMntNamespace
+	SortByUnit
+	SortByThreads
+	SortByNonvoluntaryCtxtSwitches
+	SortByCPUDelay
+	SortByBlkioDelay
+	SortByNumaRemote
+	SortByOOMScore
+	SortByIOPriority
+	SortByFDs
+	SortByCgroupMemory
+	SortByThrottled
+
+	// SortByExtra plus i sorts by Process.Extra[i], so it must be the last
+	// SortKey.
+	SortByExtra
+)
+
+// Monitor monitors the processes and resource utilization of the system.
+type Monitor struct {
+	List []*Process
+	Map  map[uint64]*Process
+
+	// The following options are set by the user of the Monitor and take
+	// effect on the next Update or Sort.
+
+	// KernelThreads includes kernel threads in the process list.
+	KernelThreads bool
+	// Pids, Users and Container restrict the process list to the listed
+	// Pids, users and the container with this name or ID prefix, unless
+	// they're empty.
+	Pids      []uint64
+	Users     []*user.User
+	Container string
+	// Namespace restricts the process list to the namespace with this
+	// inode number, of any type, unless it's 0.
+	Namespace uint64
+	// ExcludeUsers and ExcludeCommand hide the processes of the listed
+	// users and the ones whose command line matches ExcludeCommand, unless
+	// they're empty.
+	ExcludeUsers   []*user.User
+	ExcludeCommand *regexp.Regexp
+	// Tree associates every Process with its Parent and Children and
+	// sorts by Pid, otherwise the list is sorted by SortKey.
+	Tree    bool
+	SortKey SortKey
+	Reverse bool
+	// KernelThreadsLast lists the kernel threads after the other
+	// processes when the list is sorted by SortKey.
+	KernelThreadsLast bool
+	// CPULifetime makes CPUPercent the average CPU usage of the processes
+	// since they started rather than since the last update, and
+	// CPUChildren includes the CPU time of their children that exited.
+	CPULifetime bool
+	CPUChildren bool
+
+	NumCPUs      int
+	MemTotal     uint64
+	MemAvailable uint64
+	// Meminfo contains every value of /proc/meminfo keyed by name, in
+	// bytes except for the counts of huge pages like HugePages_Total.
+	Meminfo    map[string]uint64
+	PageSize   uint64
+	ClockTicks uint64
+	BootTime   time.Time
+
+	CPUTimeTotal uint64
+	CPUTimeDiff  uint64
+	CPUIdleTotal uint64
+	CPUIdleDiff  uint64
+	LoadAvg      [3]float64
+
+	// Pressure is empty if the kernel doesn't support PSI.
+	Pressure []*Pressure
+
+	// NumaNodes is empty if the kernel doesn't support NUMA.
+	NumaNodes []*NumaNode
+
+	Interfaces   []*NetInterface
+	interfaceMap map[string]*NetInterface
+
+	Disks   []*Disk
+	diskMap map[string]*Disk
+
+	// IRQs reads the Interrupts from /proc/interrupts and /proc/softirqs,
+	// which list every interrupt of every CPU and are large on the hosts
+	// with many CPUs. Interrupts lists the hardware interrupts, and then
+	// the softirqs.
+	IRQs         bool
+	Interrupts   []*Interrupt
+	interruptMap map[string]*Interrupt
+
+	Cgroups   []*Cgroup
+	cgroupMap map[string]*Cgroup
+	// Cgroup2Root is the mount point of the cgroup2 filesystem, or "" if
+	// it isn't available.
+	Cgroup2Root string
+
+	// Interval is the time elapsed between the last two updates.
+	Interval   time.Duration
+	LastUpdate time.Time
+
+	// NetTraffic attributes the traffic of TCP sockets to the processes
+	// that have them open. It requires the permission to read the file
+	// descriptors of the processes, so usually root.
+	NetTraffic    bool
+	socketTraffic map[uint64]socketTraffic
+
+	// Workers is the maximum number of processes read concurrently, or if
+	// it's 0 the number of CPUs.
+	Workers int
+	jobs    []scanJob
+	buffers []*readBuffer
+
+	// ProcEvents tracks new and exited processes with the kernel's proc
+	// connector instead of listing /proc on every Update, if it's
+	// available (it requires CAP_NET_ADMIN).
+	ProcEvents      bool
+	connector       *procConnector
+	connectorFailed bool
+	lastScan        time.Time
+
+	// Log contains the last MaxLogEvents processes that started and
+	// exited, oldest first. With the proc connector, execCommands and
+	// exitCodes are what it reported since the last update.
+	Log          []ProcessEvent
+	execCommands map[uint64]string
+	exitCodes    map[uint64]uint32
+
+	// GPU reads the GPU usage of every process from the DRM fdinfo files,
+	// and of NVIDIA GPUs from nvidia-smi if it's installed.
+	GPU       bool
+	GPUs      []*GPU
+	nvidiaSMI string
+
+	// containers caches the names of the containers of the processes.
+	containers *containerCache
+
+	// Delays reads how long every process waited for a CPU and for block
+	// I/O, see updateDelays.
+	Delays          bool
+	taskstats       *taskstatsConn
+	taskstatsFailed bool
+
+	// loadedUsers are the users of the processes of the last Snapshot
+	// loaded, by Uid, which the next ones share rather than allocating a
+	// User per process.
+	loadedUsers map[string]*user.User
+
+	// FDs counts the open file descriptors of every process, see updateFDs.
+	FDs bool
+
+	// Numa reads the memory of every process on each NUMA node, see
+	// updateNumaMaps.
+	Numa bool
+
+	// Sensors reads the temperatures and fan speeds from hwmon.
+	Sensors bool
+	Temps   []*Sensor
+	Fans    []*Sensor
+
+	// Power reads the state of the batteries from the power_supply class.
+	Power     bool
+	Batteries []*Battery
+
+	// HidePid and ProcSubset are the hidepid and subset options /proc is
+	// mounted with, like "invisible" and "pid", or "" without them.
+	// RestrictedProcesses is the number of processes of the last update
+	// whose files can't be read, see Process.Restricted, and
+	// UnreadableFiles the other files of /proc that couldn't be read. See
+	// Restrictions.
+	HidePid             string
+	ProcSubset          string
+	RestrictedProcesses int
+	UnreadableFiles     []string
+}
+
+// NewMonitor returns an initialized Monitor.
+func NewMonitor() *Monitor {
+	m := &Monitor{
+		Map:          make(map[uint64]*Process),
+		NumCPUs:      runtime.NumCPU(),
+		interfaceMap: make(map[string]*NetInterface),
+		diskMap:      make(map[string]*Disk),
+		interruptMap: make(map[string]*Interrupt),
+		cgroupMap:    make(map[string]*Cgroup),
+		Cgroup2Root:  cgroup2Root(),
+		SortKey:      SortByCPU,
+		ProcEvents:   true,
+		nvidiaSMI:    findNvidiaSMI(),
+		containers:   newContainerCache(),
+	}
+	m.HidePid, m.ProcSubset = readProcMount()
+	m.queryPageSize()
+	m.queryClockTicks()
+	m.parseMeminfoFile()
+	return m
+}
+
+// Rate returns the per-second rate of a value that changed by diff since
+// the last update.
+func (m *Monitor) Rate(diff uint64) float64 {
+	if m.Interval == 0 {
+		return 0
+	}
+	return float64(diff) / m.Interval.Seconds()
+}
+
+// CPUPercent returns the CPU usage of a Process since the last update, or
+// since it started if CPULifetime is set, where 100% is one full CPU.
+func (m *Monitor) CPUPercent(p *Process) float64 {
+	if m.CPULifetime {
+		return m.lifetimeCPUPercent(p)
+	}
+	return m.cpuPercent(p, m.CPUChildren)
+}
+
+// cpuPercent returns the CPU usage of a Process since the last update,
+// including the children that exited if children is set.
+func (m *Monitor) cpuPercent(p *Process, children bool) float64 {
+	totalUsage := float64(m.CPUTimeDiff)
+	if totalUsage == 0 {
+		return 0
+	}
+	userUsage := 100 * float64(p.UtimeDiff) / totalUsage
+	systemUsage := 100 * float64(p.StimeDiff) / totalUsage
+	usage := userUsage + systemUsage
+	if children {
+		usage += 100 * float64(p.CutimeDiff+p.CstimeDiff) / totalUsage
+	}
+	return usage * float64(m.NumCPUs)
+}
+
+// lifetimeCPUPercent returns the average CPU usage of a Process since it
+// started.
+func (m *Monitor) lifetimeCPUPercent(p *Process) float64 {
+	age := m.LastUpdate.Sub(m.StartTime(p)).Seconds()
+	if age <= 0 || m.ClockTicks == 0 {
+		return 0
+	}
+	ticks := p.Utime + p.Stime
+	if m.CPUChildren {
+		ticks += p.Cutime + p.Cstime
+	}
+	return 100 * float64(ticks) / float64(m.ClockTicks) / age
+}
+
+// SystemCPUPercent returns the usage of all CPUs since the last update.
+func (m *Monitor) SystemCPUPercent() float64 {
+	if m.CPUTimeDiff == 0 {
+		return 0
+	}
+	return 100 * (1 - float64(m.CPUIdleDiff)/float64(m.CPUTimeDiff))
+}
+
+// MemPercent returns the percentage of memory in use.
+func (m *Monitor) MemPercent() float64 {
+	if m.MemTotal == 0 {
+		return 0
+	}
+	return 100 * float64(m.MemTotal-m.MemAvailable) / float64(m.MemTotal)
+}
+
+// UpdateError is returned by Update when parts of the proc filesystem
+// couldn't be read. The Monitor is still updated with everything else.
+type UpdateError struct {
+	Errs []error
+}
+
+func (e *UpdateError) Error() string {
+	if len(e.Errs) == 1 {
+		return e.Errs[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e.Errs[0], len(e.Errs)-1)
+}
+
+// Close releases the resources of the Monitor.
+func (m *Monitor) Close() error {
+	m.closeTaskstats()
+	if m.connector != nil {
+		err := m.connector.Close()
+		m.connector = nil
+		return err
+	}
+	return nil
+}
+
+func (m *Monitor) pidWhitelisted(pid uint64) bool {
+	if len(m.Pids) == 0 {
+		return true
+	}
+	for _, p := range m.Pids {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Monitor) userWhitelisted(p *Process) bool {
+	if len(m.Users) == 0 {
+		return true
+	}
+	for _, user := range m.Users {
+		if user.Uid == p.User.Uid {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded returns whether or not a Process is hidden by ExcludeUsers or
+// ExcludeCommand.
+func (m *Monitor) excluded(p *Process) bool {
+	for _, user := range m.ExcludeUsers {
+		if user.Uid == p.User.Uid {
+			return true
+		}
+	}
+	return m.ExcludeCommand != nil && m.ExcludeCommand.MatchString(p.Command)
+}
+
+// Sort sorts the process list by SortKey, or by Pid when Tree is set.
+func (m *Monitor) Sort() {
+	if m.Tree {
+		sort.Sort(ByPid(m.List))
+		m.associateProcesses()
+		return
+	}
+
+	m.sortList(m.List)
+}
+
+// sortList sorts a list of processes by SortKey.
+func (m *Monitor) sortList(processes []*Process) {
+	var list sort.Interface
+	switch m.SortKey {
+	case SortByPid:
+		list = ByPid(processes)
+	case SortByUser:
+		list = ByUser(processes)
+	case SortByRSS:
+		list = ByRSS(processes)
+	case SortByCPU:
+		list = ByCPU(processes)
+		if m.CPULifetime || m.CPUChildren {
+			list = byCPUPercent{processes, m}
+		}
+	case SortByTime:
+		list = ByTime(processes)
+	case SortByState:
+		list = ByState(processes)
+	case SortByName:
+		list = ByName(processes)
+	case SortByDiskRead:
+		list = ByDiskRead(processes)
+	case SortByDiskWrite:
+		list = ByDiskWrite(processes)
+	case SortByContainer:
+		list = ByContainer(processes)
+	case SortByNetRx:
+		list = ByNetRx(processes)
+	case SortByNetTx:
+		list = ByNetTx(processes)
+	case SortByGPU:
+		list = ByGPU(processes)
+	case SortByGPUMemory:
+		list = ByGPUMemory(processes)
+	case SortByWakeups:
+		list = ByWakeups(processes)
+	case SortBySwap:
+		list = BySwap(processes)
+	case SortByMajflt:
+		list = ByMajflt(processes)
+	case SortByMinflt:
+		list = ByMinflt(processes)
+	case SortByStart:
+		list = ByStart(processes)
+	case SortByNice:
+		list = ByNice(processes)
+	case SortBySecurityContext:
+		list = BySecurityContext(processes)
+	case SortByPidNamespace:
+		list = ByNamespace{processes, 0}
+	case SortByNetNamespace:
+		list = ByNamespace{processes, 1}
+	case SortByMntNamespace:
+		list = ByNamespace{processes, 2}
+	case SortByUnit:
+		list = ByUnit(processes)
+	case SortByThreads:
+		list = ByThreads(processes)
+	case SortByNonvoluntaryCtxtSwitches:
+		list = ByNonvoluntaryCtxtSwitches(processes)
+	case SortByCPUDelay:
+		list = ByCPUDelay(processes)
+	case SortByBlkioDelay:
+		list = ByBlkioDelay(processes)
+	case SortByNumaRemote:
+		list = ByNumaRemote(processes)
+	case SortByOOMScore:
+		list = ByOOMScore(processes)
+	case SortByIOPriority:
+		list = ByIOPriority(processes)
+	case SortByFDs:
+		list = ByFDs(processes)
+	case SortByCgroupMemory:
+		list = ByCgroupMemory(processes)
+	case SortByThrottled:
+		list = ByThrottled(processes)
+	default:
+		if m.SortKey < SortByExtra {
+			return
+		}
+		list = ByExtra{processes, int(m.SortKey - SortByExtra)}
+	}
+
+	if m.Reverse {
+		list = sort.Reverse(list)
+	}
+	sort.Sort(list)
+	if m.KernelThreadsLast {
+		sort.Stable(kernelThreadsLast(processes))
+	}
+}
+
+// byCPUPercent sorts by CPUPercent, when it isn't the usage since the last
+// update that ByCPU sorts by.
+type byCPUPercent struct {
+	processes []*Process
+	m         *Monitor
+}
+
+func (p byCPUPercent) Len() int { return len(p.processes) }
+func (p byCPUPercent) Swap(i, j int) {
+	p.processes[i], p.processes[j] = p.processes[j], p.processes[i]
+}
+func (p byCPUPercent) Less(i, j int) bool {
+	p1, p2 := p.processes[i], p.processes[j]
+	c1, c2 := p.m.CPUPercent(p1), p.m.CPUPercent(p2)
+	if c1 == c2 {
+		return p1.Pid < p2.Pid
+	}
+	return c1 > c2
+}
+
+// kernelThreadsLast sorts the kernel threads after the other processes.
+type kernelThreadsLast []*Process
+
+func (p kernelThreadsLast) Len() int      { return len(p) }
+func (p kernelThreadsLast) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p kernelThreadsLast) Less(i, j int) bool {
+	return !p[i].IsKernelThread() && p[j].IsKernelThread()
+}
+
+func (m *Monitor) addProcess(p *Process) {
+	m.List = append(m.List, p)
+	m.Map[p.Pid] = p
+}
+
+func (m *Monitor) removeDeadProcesses() {
+	for i := len(m.List) - 1; i >= 0; i-- {
+		p := m.List[i]
+
+		if !p.Alive {
+			m.List = append(m.List[:i], m.List[i+1:]...)
+			delete(m.Map, p.Pid)
+			m.logExited(p)
+		}
+	}
+}
+
+// associateProcesses associates each Process with its Parent and Children.
+func (m *Monitor) associateProcesses() {
+	for _, p := range m.List {
+		p.Parent = nil
+		p.Children = nil
+	}
+
+	for _, p := range m.List {
+		parent, ok := m.Map[p.Ppid]
+		if !ok && p.Pid != InitPid && p.Pid != KthreaddPid {
+			// init (1) and kthreadd (2) are the only processes that should
+			// have no parent. The parent may have exited since it was
+			// read, in which case the process has been reparented to
+			// init.
+			parent, ok = m.Map[InitPid]
+		}
+		if ok {
+			p.Parent = parent
+			parent.Children = append(parent.Children, p)
+		}
+	}
+}
+
+func (m *Monitor) parseStatFile() error {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "cpu ") {
+			total, idle, err := parseCPUTimes(line)
+			if err != nil {
+				return err
+			}
+			m.CPUTimeTotal, m.CPUIdleTotal = total, idle
+		} else if strings.HasPrefix(line, "btime ") {
+			// line = "btime 1433828127"
+			btime, err := ParseUint64(strings.TrimPrefix(line, "btime "))
+			if err != nil {
+				return fmt.Errorf("/proc/stat: %v", err)
+			}
+			m.BootTime = time.Unix(int64(btime), 0)
+
+			// Only parsing the CPU jiffies and boot time for now,
+			// ignore rest of file.
+			break
+		}
+	}
+	return scanner.Err()
+}
+
+// parseCPUTimes returns the total and idle (including iowait) jiffies of
+// the "cpu" line of /proc/stat.
+func parseCPUTimes(line string) (total, idle uint64, err error) {
+	// line = "cpu  user nice system idle iowait irq softirq ..."
+	for i, value := range strings.Fields(line)[1:] {
+		jiffies, err := ParseUint64(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("/proc/stat: %v", err)
+		}
+		total += jiffies
+		if i == 3 || i == 4 {
+			idle += jiffies
+		}
+	}
+	return total, idle, nil
+}
+
+func (m *Monitor) parseMeminfoFile() error {
+	meminfo, err := readMeminfo()
+	if err != nil {
+		return err
+	}
+	m.MemTotal = meminfo["MemTotal"]
+	m.MemAvailable = meminfo["MemAvailable"]
+	m.Meminfo = meminfo
+	return nil
+}
+
+// readMeminfo returns the values of /proc/meminfo in bytes, keyed by name.
+func readMeminfo() (map[string]uint64, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	meminfo := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line = "MemTotal:       16371752 kB" or "HugePages_Total:   0"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := ParseUint64(fields[1])
+		if err != nil {
+			continue
+		}
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= KB
+		}
+		meminfo[strings.TrimSuffix(fields[0], ":")] = value
+	}
+	return meminfo, scanner.Err()
+}
+
+func (m *Monitor) parseLoadavgFile() error {
+	data, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return err
+	}
+
+	// data = "0.20 0.18 0.12 1/80 11206"
+	_, err = fmt.Sscanf(string(data), "%f %f %f", &m.LoadAvg[0], &m.LoadAvg[1], &m.LoadAvg[2])
+	if err != nil {
+		return fmt.Errorf("/proc/loadavg: %v", err)
+	}
+	return nil
+}
+
+func (m *Monitor) queryPageSize() {
+	m.PageSize = uint64(os.Getpagesize())
+}
+
+func (m *Monitor) queryClockTicks() {
+	// USER_HZ is 100 on virtually every system, so fall back to it rather
+	// than failing.
+	m.ClockTicks = defaultClockTicks
+	out, err := exec.Command("getconf", "CLK_TCK").Output()
+	if err != nil {
+		return
+	}
+	if ticks, err := ParseUint64(strings.TrimSpace(string(out))); err == nil && ticks > 0 {
+		m.ClockTicks = ticks
+	}
+}
+
+// StartTime returns the time at which a Process was started.
+func (m *Monitor) StartTime(p *Process) time.Time {
+	seconds := float64(p.StartTime) / float64(m.ClockTicks)
+	return m.BootTime.Add(time.Duration(seconds * float64(time.Second)))
+}
@@ -0,0 +1,123 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Update updates the Monitor state via the proc filesystem. Processes that
+// exit while being read are skipped, any other failure is returned as an
+// *UpdateError.
+func (m *Monitor) Update() error {
+	var errs []error
+
+	lastCPUTimeTotal, lastCPUIdleTotal := m.CPUTimeTotal, m.CPUIdleTotal
+	if err := m.parseStatFile(); m.systemFileError(err) != nil {
+		errs = append(errs, err)
+	}
+	m.CPUTimeDiff = m.CPUTimeTotal - lastCPUTimeTotal
+	m.CPUIdleDiff = m.CPUIdleTotal - lastCPUIdleTotal
+
+	for _, parse := range []func() error{
+		m.parseMeminfoFile,
+		m.parseLoadavgFile,
+		m.parseNetDevFile,
+		m.parseDiskstatsFile,
+		m.parsePressureFiles,
+		m.parseNumaNodeFiles,
+	} {
+		if err := parse(); m.systemFileError(err) != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.IRQs {
+		if err := m.parseInterruptFiles(); m.systemFileError(err) != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	now := time.Now()
+	if !m.LastUpdate.IsZero() {
+		m.Interval = now.Sub(m.LastUpdate)
+	}
+	m.LastUpdate = now
+
+	pids, execed, err := m.listPids()
+	if err != nil {
+		// Keep the processes of the last update rather than removing
+		// all of them.
+		return &UpdateError{append(errs, err)}
+	}
+
+	for _, p := range m.List {
+		p.Alive = false
+	}
+
+	errs = append(errs, m.updateProcesses(pids, execed)...)
+	if m.NetTraffic {
+		if err := m.updateNetTraffic(); err != nil {
+			errs = append(errs, fmt.Errorf("sock_diag: %v", err))
+		}
+	}
+	if m.GPU {
+		if err := m.updateGPUs(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.Delays {
+		m.updateDelays()
+	}
+	if m.Numa {
+		m.updateNumaMaps()
+	}
+	if m.FDs {
+		m.updateFDs()
+	}
+	if m.Sensors {
+		if err := m.updateSensors(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.Power {
+		if err := m.updateBatteries(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	m.removeDeadProcesses()
+	m.logShortLived()
+	m.recordHistory()
+	m.updateCgroups()
+	m.Sort()
+
+	if len(errs) > 0 {
+		return &UpdateError{errs}
+	}
+	return nil
+}
+
+// listPids returns the Pids of the processes to update, along with the ones
+// that exec'd since the last update if that's known.
+func (m *Monitor) listPids() ([]uint64, map[uint64]bool, error) {
+	if pids, execed, ok := m.eventPids(); ok {
+		return pids, execed, nil
+	}
+
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, nil, err
+	}
+	m.lastScan = time.Now()
+
+	var pids []uint64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if pid, err := ParseUint64(entry.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package proc
+
+// Update fails on the systems without a proc filesystem like the one of
+// Linux, nor a backend of their own. A Monitor can still be loaded with the
+// Snapshots of another system.
+func (m *Monitor) Update() error {
+	return &UpdateError{[]error{errUnsupported}}
+}
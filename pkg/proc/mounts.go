@@ -0,0 +1,85 @@
+package proc
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Mount is a mounted filesystem from /proc/mounts and its usage.
+type Mount struct {
+	Device  string
+	Path    string
+	Type    string
+	Options string
+
+	// Size, Used and Avail are in bytes. Avail is what's available to
+	// unprivileged users, so Used and Avail don't add up to Size when
+	// blocks are reserved for root.
+	Size  uint64
+	Used  uint64
+	Avail uint64
+}
+
+// UsePercent returns the percentage of the space available to unprivileged
+// users that's used, rounded up like df does.
+func (m *Mount) UsePercent() uint64 {
+	total := m.Used + m.Avail
+	if total == 0 {
+		return 0
+	}
+	return (100*m.Used + total - 1) / total
+}
+
+// ReadMounts returns the mounted filesystems that have a size, in the order
+// of /proc/mounts. Pseudo filesystems like proc and cgroup2 have no blocks
+// and are skipped.
+func ReadMounts() ([]*Mount, error) {
+	file, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var mounts []*Mount
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line = "/dev/sda1 /boot ext4 rw,relatime 0 0"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		mount := &Mount{
+			Device:  unescapeMountField(fields[0]),
+			Path:    unescapeMountField(fields[1]),
+			Type:    fields[2],
+			Options: fields[3],
+		}
+		if !mount.statfs() {
+			continue
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountField replaces the octal escapes of the spaces, tabs,
+// newlines and backslashes in the fields of /proc/mounts, like \040.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,20 @@
+package proc
+
+import "syscall"
+
+// statfs sets the size, used and available bytes of the filesystem, and
+// returns false if it has no blocks or can't be queried.
+func (mount *Mount) statfs() bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(mount.Path, &stat); err != nil || stat.Blocks == 0 {
+		return false
+	}
+	blockSize := uint64(stat.Frsize)
+	if blockSize == 0 {
+		blockSize = uint64(stat.Bsize)
+	}
+	mount.Size = stat.Blocks * blockSize
+	mount.Used = (stat.Blocks - stat.Bfree) * blockSize
+	mount.Avail = stat.Bavail * blockSize
+	return true
+}
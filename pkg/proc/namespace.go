@@ -0,0 +1,72 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Namespaces are the namespaces read from /proc/<pid>/ns, in the order of
+// Process.Namespaces.
+var Namespaces = []string{"pid", "net", "mnt"}
+
+// parseNamespaces sets Namespaces to the inode numbers of the namespaces of
+// the process, which are 0 when they can't be read (usually because the
+// process belongs to another user).
+func (p *Process) parseNamespaces() {
+	for i, ns := range Namespaces {
+		p.Namespaces[i] = 0
+		link, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/%s", p.Pid, ns))
+		if err != nil {
+			continue
+		}
+		if inode, err := ParseNamespace(link); err == nil {
+			p.Namespaces[i] = inode
+		}
+	}
+}
+
+// ParseNamespace parses the inode number of a namespace, either alone or as
+// the target of a link of /proc/<pid>/ns such as "net:[4026531840]".
+func ParseNamespace(s string) (uint64, error) {
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		s = strings.TrimSuffix(strings.TrimPrefix(s[i+1:], "["), "]")
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// InNamespace returns whether or not a Process is in the namespace with the
+// passed in inode number, of any type.
+func (p *Process) InNamespace(inode uint64) bool {
+	for _, ns := range p.Namespaces {
+		if ns == inode {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceWhitelisted returns whether or not a Process is in the Namespace
+// the Monitor is restricted to.
+func (m *Monitor) namespaceWhitelisted(p *Process) bool {
+	return m.Namespace == 0 || p.InNamespace(m.Namespace)
+}
+
+type ByNamespace struct {
+	Processes []*Process
+	// Type is the index of the namespace in Namespaces.
+	Type int
+}
+
+func (p ByNamespace) Len() int { return len(p.Processes) }
+func (p ByNamespace) Swap(i, j int) {
+	p.Processes[i], p.Processes[j] = p.Processes[j], p.Processes[i]
+}
+func (p ByNamespace) Less(i, j int) bool {
+	p1, p2 := p.Processes[i], p.Processes[j]
+	if p1.Namespaces[p.Type] == p2.Namespaces[p.Type] {
+		return p1.Pid < p2.Pid
+	}
+	return p1.Namespaces[p.Type] < p2.Namespaces[p.Type]
+}
@@ -0,0 +1,85 @@
+package proc
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+)
+
+// NetInterface contains the traffic counters of a network interface.
+type NetInterface struct {
+	Name    string
+	RxBytes uint64
+	TxBytes uint64
+
+	RxBytesDiff uint64
+	TxBytesDiff uint64
+
+	alive bool
+}
+
+// parseNetDevFile updates Interfaces from /proc/net/dev.
+func (m *Monitor) parseNetDevFile() error {
+	file, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, iface := range m.Interfaces {
+		iface.alive = false
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line = "  eth0: 1234 12 0 0 0 0 0 0 5678 34 0 0 0 0 0 0"
+		line := scanner.Text()
+		colon := strings.IndexByte(line, ':')
+		if colon == -1 {
+			continue // header lines
+		}
+		name := strings.TrimSpace(line[:colon])
+		values := strings.Fields(line[colon+1:])
+		if len(values) < 9 {
+			continue
+		}
+		rxBytes, err1 := ParseUint64(values[0])
+		txBytes, err2 := ParseUint64(values[8])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		iface, ok := m.interfaceMap[name]
+		if ok {
+			iface.RxBytesDiff = rxBytes - iface.RxBytes
+			iface.TxBytesDiff = txBytes - iface.TxBytes
+		} else {
+			iface = &NetInterface{Name: name}
+			m.interfaceMap[name] = iface
+			m.Interfaces = append(m.Interfaces, iface)
+		}
+		iface.RxBytes, iface.TxBytes = rxBytes, txBytes
+		iface.alive = true
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for i := len(m.Interfaces) - 1; i >= 0; i-- {
+		if iface := m.Interfaces[i]; !iface.alive {
+			m.Interfaces = append(m.Interfaces[:i], m.Interfaces[i+1:]...)
+			delete(m.interfaceMap, iface.Name)
+		}
+	}
+	sort.Sort(ByInterfaceName(m.Interfaces))
+	return nil
+}
+
+type ByInterfaceName []*NetInterface
+
+func (n ByInterfaceName) Len() int      { return len(n) }
+func (n ByInterfaceName) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+func (n ByInterfaceName) Less(i, j int) bool {
+	return n[i].Name < n[j].Name
+}
@@ -0,0 +1,72 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+)
+
+// socketTraffic is the number of bytes sent and received on a socket.
+type socketTraffic struct {
+	tx, rx uint64
+}
+
+// readSocketInodes sets sockets to the inodes of the sockets the process has
+// open. It requires the permission to read /proc/<pid>/fd.
+func (p *Process) readSocketInodes() error {
+	dir := fmt.Sprintf("/proc/%d/fd", p.Pid)
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	names, err := file.Readdirnames(-1)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	p.sockets = p.sockets[:0]
+	for _, name := range names {
+		target, err := os.Readlink(dir + "/" + name)
+		if err != nil {
+			continue // closed since Readdirnames
+		}
+		if inode, ok := SocketInode(target); ok {
+			p.sockets = append(p.sockets, inode)
+		}
+	}
+	return nil
+}
+
+// updateNetTraffic sets the network traffic of every process to the traffic
+// of its TCP sockets since the last update. UDP sockets have no counters, so
+// they aren't accounted for.
+func (m *Monitor) updateNetTraffic() error {
+	traffic, err := readTCPTraffic()
+	if err != nil {
+		return err
+	}
+	if m.socketTraffic == nil {
+		// The traffic since the sockets were opened isn't a rate.
+		m.socketTraffic = traffic
+		return nil
+	}
+
+	for _, p := range m.List {
+		p.NetRxBytesDiff, p.NetTxBytesDiff = 0, 0
+		for _, inode := range p.sockets {
+			t, ok := traffic[inode]
+			if !ok {
+				continue
+			}
+			// A socket that wasn't there on the last update is counted
+			// from when it was opened.
+			last := m.socketTraffic[inode]
+			if t.rx >= last.rx && t.tx >= last.tx {
+				p.NetRxBytesDiff += t.rx - last.rx
+				p.NetTxBytesDiff += t.tx - last.tx
+			}
+		}
+	}
+	m.socketTraffic = traffic
+	return nil
+}
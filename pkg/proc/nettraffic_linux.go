@@ -0,0 +1,118 @@
+package proc
+
+import "syscall"
+
+// The following are from linux/sock_diag.h, linux/inet_diag.h and
+// linux/tcp.h.
+const (
+	netlinkSockDiag  = 4
+	sockDiagByFamily = 20
+
+	inetDiagReqV2Len = 56
+	inetDiagMsgLen   = 72
+	inetDiagInfo     = 2
+
+	// The offsets of the inode in struct inet_diag_msg, and of
+	// tcpi_bytes_acked and tcpi_bytes_received in struct tcp_info.
+	inetDiagMsgInode     = 68
+	tcpInfoBytesAcked    = 120
+	tcpInfoBytesReceived = 128
+)
+
+// readTCPTraffic returns the traffic of every TCP socket, keyed by inode,
+// using the sock_diag netlink interface.
+func readTCPTraffic() (map[uint64]socketTraffic, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkSockDiag)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	traffic := make(map[uint64]socketTraffic)
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		if err := dumpTCPSockets(fd, family, traffic); err != nil {
+			return nil, err
+		}
+	}
+	return traffic, nil
+}
+
+func dumpTCPSockets(fd int, family uint8, traffic map[uint64]socketTraffic) error {
+	req := make([]byte, nlmsgHdrLen+inetDiagReqV2Len)
+	// struct nlmsghdr
+	nativeEndian.PutUint32(req[0:], uint32(len(req)))
+	nativeEndian.PutUint16(req[4:], sockDiagByFamily)
+	nativeEndian.PutUint16(req[6:], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	// struct inet_diag_req_v2
+	body := req[nlmsgHdrLen:]
+	body[0] = family
+	body[1] = syscall.IPPROTO_TCP
+	body[2] = 1 << (inetDiagInfo - 1)
+	nativeEndian.PutUint32(body[4:], 0xffffffff) // every state
+
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8*syscall.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					if errno := int32(nativeEndian.Uint32(msg.Data)); errno != 0 {
+						return syscall.Errno(-errno)
+					}
+				}
+				return nil
+			case sockDiagByFamily:
+				parseInetDiagMsg(msg.Data, traffic)
+			}
+		}
+	}
+}
+
+// parseInetDiagMsg adds the traffic of a struct inet_diag_msg followed by
+// an INET_DIAG_INFO attribute.
+func parseInetDiagMsg(data []byte, traffic map[uint64]socketTraffic) {
+	if len(data) < inetDiagMsgLen {
+		return
+	}
+	inode := uint64(nativeEndian.Uint32(data[inetDiagMsgInode:]))
+	if inode == 0 {
+		return // TIME_WAIT
+	}
+
+	// struct rtattr is a 2 byte length and a 2 byte type, followed by the
+	// data padded to 4 bytes.
+	for attrs := data[inetDiagMsgLen:]; len(attrs) >= 4; {
+		length := int(nativeEndian.Uint16(attrs[0:]))
+		kind := nativeEndian.Uint16(attrs[2:])
+		if length < 4 || length > len(attrs) {
+			return
+		}
+		if kind == inetDiagInfo && length-4 >= tcpInfoBytesReceived+8 {
+			info := attrs[4:length]
+			traffic[inode] = socketTraffic{
+				tx: nativeEndian.Uint64(info[tcpInfoBytesAcked:]),
+				rx: nativeEndian.Uint64(info[tcpInfoBytesReceived:]),
+			}
+			return
+		}
+		aligned := (length + 3) &^ 3
+		if aligned > len(attrs) {
+			return
+		}
+		attrs = attrs[aligned:]
+	}
+}
@@ -0,0 +1,214 @@
+package proc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numaNodeDir is where the kernel lists the NUMA nodes, as node0, node1...
+const numaNodeDir = "/sys/devices/system/node"
+
+// NumaNode contains the memory usage of a NUMA node, from its meminfo and
+// numastat files.
+type NumaNode struct {
+	ID int
+	// MemTotal and MemUsed are in bytes.
+	MemTotal uint64
+	MemUsed  uint64
+
+	// Miss is the number of pages allocated on this node that were
+	// intended for another one, because the latter was full.
+	Miss     uint64
+	MissDiff uint64
+}
+
+// MemPercent returns the percentage of the memory of the node in use.
+func (n *NumaNode) MemPercent() float64 {
+	if n.MemTotal == 0 {
+		return 0
+	}
+	return 100 * float64(n.MemUsed) / float64(n.MemTotal)
+}
+
+// parseNumaNodeFiles updates NumaNodes from /sys/devices/system/node, which
+// only exists on kernels built with NUMA, so its absence isn't an error.
+func (m *Monitor) parseNumaNodeFiles() error {
+	entries, err := ioutil.ReadDir(numaNodeDir)
+	if os.IsNotExist(err) {
+		m.NumaNodes = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	last := make(map[int]*NumaNode, len(m.NumaNodes))
+	for _, node := range m.NumaNodes {
+		last[node.ID] = node
+	}
+	var nodes []*NumaNode
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "node") {
+			continue
+		}
+		id, err := strconv.Atoi(entry.Name()[len("node"):])
+		if err != nil {
+			continue
+		}
+		node := &NumaNode{ID: id}
+		dir := fmt.Sprintf("%s/node%d/", numaNodeDir, id)
+		if err := node.parseMeminfo(dir + "meminfo"); err != nil {
+			return err
+		}
+		// numastat is missing on some architectures.
+		if data, err := ioutil.ReadFile(dir + "numastat"); err == nil {
+			node.Miss = numastatValue(data, "numa_miss")
+		}
+		if l, ok := last[id]; ok && node.Miss >= l.Miss {
+			node.MissDiff = node.Miss - l.Miss
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	m.NumaNodes = nodes
+	return nil
+}
+
+func (n *NumaNode) parseMeminfo(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line = "Node 0 MemTotal:        5209848 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		value, err := ParseUint64(fields[3])
+		if err != nil {
+			continue
+		}
+		switch fields[2] {
+		case "MemTotal:":
+			n.MemTotal = value * 1024
+		case "MemUsed:":
+			n.MemUsed = value * 1024
+		}
+	}
+	return scanner.Err()
+}
+
+// numastatValue returns a counter of a numastat file, or 0 if it's missing.
+func numastatValue(data []byte, name string) uint64 {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		// line = "numa_miss 0"
+		fields := strings.Fields(string(line))
+		if len(fields) == 2 && fields[0] == name {
+			value, _ := ParseUint64(fields[1])
+			return value
+		}
+	}
+	return 0
+}
+
+// updateNumaMaps sets the memory of every process on each NUMA node from
+// /proc/<pid>/numa_maps. The kernel walks the page tables of the process to
+// produce it, so it's only read when Monitor.Numa is set.
+func (m *Monitor) updateNumaMaps() {
+	for _, p := range m.List {
+		data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/numa_maps", p.Pid))
+		if err != nil {
+			// Exited since it was read, or a kernel thread.
+			p.NumaMemory = nil
+			continue
+		}
+		p.NumaMemory = parseNumaMaps(data)
+	}
+}
+
+// parseNumaMaps returns the memory in bytes of each mapping of a numa_maps
+// file on each node, indexed by the node ID.
+func parseNumaMaps(data []byte) []uint64 {
+	var memory []uint64
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		// line = "7f1c2a000000 default anon=512 dirty=512 N0=384 N1=128 kernelpagesize_kB=4"
+		fields := strings.Fields(string(line))
+		pageSize := uint64(4096)
+		for _, field := range fields {
+			if strings.HasPrefix(field, "kernelpagesize_kB=") {
+				if kB, err := ParseUint64(field[len("kernelpagesize_kB="):]); err == nil {
+					pageSize = kB * 1024
+				}
+			}
+		}
+		for _, field := range fields {
+			i := strings.IndexByte(field, '=')
+			if len(field) < 2 || field[0] != 'N' || i < 0 {
+				continue
+			}
+			node, err1 := strconv.Atoi(field[1:i])
+			pages, err2 := ParseUint64(field[i+1:])
+			if err1 != nil || err2 != nil || node < 0 {
+				continue
+			}
+			for len(memory) <= node {
+				memory = append(memory, 0)
+			}
+			memory[node] += pages * pageSize
+		}
+	}
+	return memory
+}
+
+// NumaNode returns the NUMA node holding the most memory of the process,
+// and the percentage of its memory there, or -1 if that's unknown.
+func (p *Process) NumaNode() (int, float64) {
+	node, max, total := -1, uint64(0), uint64(0)
+	for i, memory := range p.NumaMemory {
+		total += memory
+		if memory > max {
+			node, max = i, memory
+		}
+	}
+	if node < 0 {
+		return -1, 0
+	}
+	return node, 100 * float64(max) / float64(total)
+}
+
+// numaRemote returns the memory in bytes of the process on the nodes other
+// than the one holding the most of it.
+func (p *Process) numaRemote() uint64 {
+	var max, total uint64
+	for _, memory := range p.NumaMemory {
+		total += memory
+		if memory > max {
+			max = memory
+		}
+	}
+	return total - max
+}
+
+// ByNumaRemote sorts the processes with the most memory outside of their
+// main NUMA node first.
+type ByNumaRemote []*Process
+
+func (p ByNumaRemote) Len() int      { return len(p) }
+func (p ByNumaRemote) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByNumaRemote) Less(i, j int) bool {
+	r1, r2 := p[i].numaRemote(), p[j].numaRemote()
+	if r1 == r2 {
+		return p[i].Pid < p[j].Pid
+	}
+	return r1 > r2
+}
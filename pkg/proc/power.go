@@ -0,0 +1,55 @@
+package proc
+
+import (
+	"path/filepath"
+)
+
+const powerSupplyClassDir = "/sys/class/power_supply"
+
+// Battery contains the state of a battery.
+type Battery struct {
+	Name     string  // BAT0
+	Capacity float64 // percentage of a full charge
+	Status   string  // Charging, Discharging, Full, ...
+
+	// Power is the power being drawn from or charged into the battery in
+	// watts, or -1 if it isn't reported.
+	Power float64
+}
+
+// updateBatteries reads the state of every battery from the power_supply
+// class.
+func (m *Monitor) updateBatteries() error {
+	supplies, err := filepath.Glob(powerSupplyClassDir + "/*")
+	if err != nil {
+		return err
+	}
+
+	m.Batteries = nil
+	for _, supply := range supplies {
+		if readSysfsString(filepath.Join(supply, "type")) != "Battery" {
+			continue
+		}
+		capacity, ok := readSysfsUint64(filepath.Join(supply, "capacity"))
+		if !ok {
+			continue // not present
+		}
+		b := &Battery{
+			Name:     filepath.Base(supply),
+			Capacity: float64(capacity),
+			Status:   readSysfsString(filepath.Join(supply, "status")),
+			Power:    -1,
+		}
+		// Batteries report either their power in µW, or their current
+		// in µA and voltage in µV.
+		if power, ok := readSysfsUint64(filepath.Join(supply, "power_now")); ok {
+			b.Power = float64(power) / 1e6
+		} else if current, ok := readSysfsUint64(filepath.Join(supply, "current_now")); ok {
+			if voltage, ok := readSysfsUint64(filepath.Join(supply, "voltage_now")); ok {
+				b.Power = float64(current) * float64(voltage) / 1e12
+			}
+		}
+		m.Batteries = append(m.Batteries, b)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// PressureResources are the resources with Pressure Stall Information, in
+// the order they're listed in Monitor.Pressure.
+var PressureResources = []string{"cpu", "memory", "io"}
+
+// Pressure is the Pressure Stall Information of a resource: the percentage
+// of time over the last 10 seconds that some or all non-idle tasks were
+// stalled waiting for it.
+type Pressure struct {
+	Resource string
+	Some     float64
+	Full     float64
+	// HasFull is false for the CPU on kernels before 5.13.
+	HasFull bool
+}
+
+// parsePressureFiles reads /proc/pressure, which only exists on kernels
+// built with PSI, so its absence isn't an error.
+func (m *Monitor) parsePressureFiles() error {
+	m.Pressure = nil
+	for _, resource := range PressureResources {
+		path := "/proc/pressure/" + resource
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		pressure, err := parsePressure(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		pressure.Resource = resource
+		m.Pressure = append(m.Pressure, pressure)
+	}
+	return nil
+}
+
+// parsePressure parses the avg10 values of a pressure file.
+func parsePressure(data []byte) (*Pressure, error) {
+	p := &Pressure{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// line = "some avg10=0.12 avg60=0.05 avg300=0.01 total=123456"
+		var kind string
+		var avg10 float64
+		if _, err := fmt.Sscanf(line, "%s avg10=%f", &kind, &avg10); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "some":
+			p.Some = avg10
+		case "full":
+			p.Full = avg10
+			p.HasFull = true
+		}
+	}
+	return p, nil
+}
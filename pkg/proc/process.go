@@ -0,0 +1,892 @@
+package proc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// The values in /proc/<pid>/stat
+	statState = iota
+	statPpid
+	statPgrp
+	statSession
+	statTtyNr
+	statTpgid
+	statFlags
+	statMinflt
+	statCminflt
+	statMajflt
+	statCmajflt
+	statUtime
+	statStime
+	statCutime
+	statCstime
+	statPriority
+	statNice
+	statNumThreads
+	statItrealvalue
+	statStartTime
+	statVsize
+	statRSS
+	statRSSLimit
+	statStartCode
+	statEndCode
+	statStartStack
+	statKstKesp
+	statKstKeip
+	statSignal
+	statBlocked
+	statSigIgnore
+	statSigCatch
+	statWchan
+	statNswap
+	statCnswap
+	statExitSignal
+	statProcessor
+	statRtPriority
+	statPolicy
+	statDelayActBlkioTicks
+	statGuestTime
+	statCguestTime
+)
+
+// Process represents an operating system process.
+type Process struct {
+	Pid     uint64
+	User    *user.User
+	Name    string // foo
+	Command string // /usr/bin/foo --args
+
+	// Comm is the name of the process in the kernel, which is truncated to
+	// 15 bytes, and Exe the path of its executable, or "" if it can't be
+	// read.
+	Comm string
+	Exe  string
+
+	// ContainerID is the ID of the container the process is running in, or
+	// "" if it isn't running in a container.
+	ContainerID string
+	// containerName is the name of the container, as resolved by the last
+	// update, see ContainerName.
+	containerName string
+
+	// Cgroup is the path of the process in the cgroup v2 hierarchy, or ""
+	// if cgroup v2 isn't in use.
+	Cgroup string
+
+	// Unit is the systemd unit the process is running in, or "" if it
+	// isn't running in one.
+	Unit string
+
+	// SecurityContext is the SELinux context or AppArmor profile of the
+	// process, or "" if no security module is enabled.
+	SecurityContext string
+
+	// Namespaces contains the inode numbers of the pid, net and mnt
+	// namespaces of the process, see Namespaces.
+	Namespaces [3]uint64
+
+	// Alive is a flag used by Monitor to determine if it should remove
+	// this process.
+	Alive bool
+
+	// Tree view
+	Parent      *Process
+	Children    []*Process
+	TreePrefix  string
+	isLastChild bool
+
+	// Data from /proc/<pid>/stat
+	State byte
+	Ppid  uint64
+	Pgrp  uint64
+	Utime uint64
+	Stime uint64
+	RSS   uint64
+
+	// StartTime is the number of clock ticks after system boot that the
+	// process started.
+	StartTime uint64
+
+	UtimeDiff uint64
+	StimeDiff uint64
+
+	// The CPU time of the children of the process that exited and were
+	// waited for, also from /proc/<pid>/stat.
+	Cutime     uint64
+	Cstime     uint64
+	CutimeDiff uint64
+	CstimeDiff uint64
+
+	// Recent samples of the CPU percentage and RSS in bytes
+	CPUHistory *Ring
+	RSSHistory *Ring
+
+	// Data from /proc/<pid>/io
+	ReadBytes  uint64
+	WriteBytes uint64
+
+	ReadBytesDiff  uint64
+	WriteBytesDiff uint64
+
+	// Nice is the nice value, between -20 (highest priority) and 19.
+	Nice int
+
+	// Threads is the number of threads of the process.
+	Threads uint64
+
+	// Minor and major page faults, the latter requiring a read from disk.
+	Minflt     uint64
+	Majflt     uint64
+	MinfltDiff uint64
+	MajfltDiff uint64
+
+	// Swap is the memory in bytes swapped out, from /proc/<pid>/status.
+	Swap uint64
+
+	// OOMScore is the badness of the process from /proc/<pid>/oom_score,
+	// the one with the highest is killed when the system runs out of
+	// memory.
+	OOMScore uint64
+
+	// FDs is the number of open file descriptors of the process, or -1 if
+	// they can't be listed, and FDLimit its soft limit of open files, or 0
+	// if it's unlimited. They're only set when Monitor.FDs is set.
+	FDs     int
+	FDLimit uint64
+
+	// CgroupMemoryMax is the memory limit of the cgroup of the process, or
+	// of its closest ancestor with one, and CgroupMemoryCurrent the memory
+	// used by the group with the limit. CgroupThrottledDiff is how long
+	// the group was throttled by its CPU limit since the last update, in
+	// microseconds. See Cgroup.
+	CgroupMemoryMax     uint64
+	CgroupMemoryCurrent uint64
+	CgroupThrottledDiff uint64
+
+	// Frozen is whether or not the cgroup of the process is frozen, see
+	// Monitor.FreezeCgroup.
+	Frozen bool
+
+	// Restricted is set when the files of the process can't be read,
+	// because /proc is mounted with hidepid=noaccess or jtop runs in a
+	// restricted container. Only its Pid and User are known then.
+	Restricted bool
+
+	// IOPriority is the I/O scheduling class and level of the main thread
+	// of the process.
+	IOPriority IOPriority
+
+	// Data from /proc/<pid>/status. A voluntary context switch is the
+	// process going to sleep, so each one is followed by a wakeup.
+	VoluntaryCtxtSwitches        uint64
+	NonvoluntaryCtxtSwitches     uint64
+	VoluntaryCtxtSwitchesDiff    uint64
+	NonvoluntaryCtxtSwitchesDiff uint64
+
+	// Traffic of the TCP sockets of the process since the last update,
+	// only when Monitor.NetTraffic is set.
+	NetRxBytesDiff uint64
+	NetTxBytesDiff uint64
+	sockets        []uint64
+
+	// Time in nanoseconds the GPU engines spent on the process and the GPU
+	// memory in bytes it uses, only when Monitor.GPU is set. GPUTime is
+	// only known from the DRM fdinfo files, whereas GPUTimeDiff includes
+	// the time the SMs of NVIDIA GPUs were busy with it, from nvidia-smi.
+	GPUTime     uint64
+	GPUTimeDiff uint64
+	GPUMemory   uint64
+
+	// Time in nanoseconds the process waited for a CPU and for block I/O,
+	// only when Monitor.Delays is set. blkioTicks is the latter in clock
+	// ticks, from /proc/<pid>/stat.
+	CPUDelay       uint64
+	BlkioDelay     uint64
+	CPUDelayDiff   uint64
+	BlkioDelayDiff uint64
+	blkioTicks     uint64
+	delaysSet      bool
+
+	// NumaMemory is the memory in bytes of the process on each NUMA node,
+	// indexed by the node ID, only when Monitor.Numa is set.
+	NumaMemory []uint64
+
+	// Members are the processes an aggregated Process sums, see
+	// Monitor.Aggregate.
+	Members []*Process
+
+	// Extra are the values of the columns that aren't built into jtop, set
+	// by the user of the Monitor after each Update. The list can be sorted
+	// by them with SortByExtra.
+	Extra []string
+
+	initializing bool
+}
+
+// NewProcess returns a new Process for the process currently running on the
+// system with the passed in Pid. It returns an error if the process couldn't
+// be read, see Vanished.
+func NewProcess(pid uint64) (*Process, error) {
+	return newProcess(pid, &readBuffer{})
+}
+
+func newProcess(pid uint64, buf *readBuffer) (*Process, error) {
+	p := &Process{
+		Pid:          pid,
+		initializing: true,
+	}
+
+	if err := p.update(buf); err != nil {
+		return nil, err
+	}
+
+	if !p.hasEmptyCmdlineFile() {
+		if err := p.parseCmdlineFile(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	// Like the command line, the cgroup, security context and namespaces
+	// of a process rarely change.
+	if err := p.parseCgroupFile(buf); err != nil {
+		return nil, err
+	}
+	p.parseSecurityContext(buf)
+	p.parseNamespaces()
+	p.readExe()
+
+	p.initializing = false
+	return p, nil
+}
+
+// Vanished returns whether or not err, returned while reading the process
+// with the passed in Pid, is because the process is no longer running. A
+// process can exit at any time, including halfway through reading one of
+// its files.
+func Vanished(pid uint64, err error) bool {
+	if os.IsNotExist(err) || errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	_, statErr := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	return os.IsNotExist(statErr)
+}
+
+func (p *Process) String() string {
+	return fmt.Sprintf("%d (%s)", p.Pid, p.Name)
+}
+
+// Update updates Process from various files in /proc/<pid>. It returns an
+// error if Process was unable to be updated (probably because the actual OS
+// process is no longer running).
+func (p *Process) Update() error {
+	return p.update(&readBuffer{})
+}
+
+func (p *Process) update(buf *readBuffer) error {
+	if err := p.statProcDir(); err != nil {
+		return err
+	}
+
+	if err := p.parseStatFile(buf); err != nil {
+		return err
+	}
+
+	if p.IsKernelThread() {
+		if err := p.parseCommFile(buf); err != nil {
+			return err
+		}
+	}
+
+	// /proc/<pid>/io is only readable by the owner of the process (or
+	// root), so failing to read it isn't an error.
+	p.parseIoFile(buf)
+
+	if err := p.parseOOMScoreFile(buf); err != nil {
+		return err
+	}
+	p.readIOPriority()
+
+	if err := p.parseStatusFile(buf); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsKernelThread returns whether or not Process is a kernel thread. init is
+// in process group 0 too in some containers.
+func (p *Process) IsKernelThread() bool {
+	return p.Pgrp == 0 && p.Pid != InitPid && !p.Restricted
+}
+
+// Stopped returns whether or not Process is stopped by a signal like
+// SIGSTOP or by a debugger, or frozen with its cgroup.
+func (p *Process) Stopped() bool {
+	return p.State == 'T' || p.State == 't' || p.Frozen
+}
+
+// TreeList returns a Process slice in "tree order" such that iterating
+// over it and printing out the TreePrefix and Command will display a
+// nice overview of the process hierarchy.
+func (p *Process) TreeList(level uint) []*Process {
+	const defaultEnd = "├─ "
+	const lastChildEnd = "└─ "
+	const defaultSegment = "│  "
+	const lastChildSegment = "   "
+
+	end := defaultEnd
+	if p.isLastChild {
+		end = lastChildEnd
+	}
+
+	switch level {
+	case 0:
+		p.TreePrefix = ""
+	case 1:
+		p.TreePrefix = end
+	default:
+		p.TreePrefix = ""
+		for parent := p.Parent; parent != nil && parent.Pid != InitPid; parent = parent.Parent {
+			if parent.isLastChild {
+				p.TreePrefix = lastChildSegment + p.TreePrefix
+			} else {
+				p.TreePrefix = defaultSegment + p.TreePrefix
+			}
+		}
+		p.TreePrefix = p.TreePrefix + end
+	}
+
+	var treeList []*Process
+	treeList = append(treeList, p)
+	for i, process := range p.Children {
+		if i == len(p.Children)-1 {
+			process.isLastChild = true
+		} else {
+			process.isLastChild = false
+		}
+		treeList = append(treeList, process.TreeList(level+1)...)
+	}
+	return treeList
+}
+
+func (p *Process) parseStatFile(buf *readBuffer) error {
+	path := fmt.Sprintf("/proc/%d/stat", p.Pid)
+
+	data, err := buf.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	line := string(data)
+
+	commStart := strings.IndexByte(line, '(') + 1
+	commEnd := strings.LastIndexByte(line, ')')
+	if commStart == 0 || commEnd < commStart || commEnd+2 > len(line) {
+		return fmt.Errorf("%s: malformed", path)
+	}
+
+	values := buf.split(line[commEnd+2:])
+	if len(values) <= statRSS || values[statState] == "" {
+		return fmt.Errorf("%s: malformed", path)
+	}
+
+	// Parse every value before changing the Process, so it's left as is
+	// if the file is malformed.
+	var stat [statRSS + 1]uint64
+	for _, i := range []int{statPpid, statPgrp, statMinflt, statMajflt, statUtime, statStime, statCutime, statCstime, statNumThreads, statStartTime, statRSS} {
+		if stat[i], err = ParseUint64(values[i]); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	nice, err := strconv.Atoi(values[statNice])
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	p.Comm = line[commStart:commEnd]
+	if p.hasEmptyCmdlineFile() {
+		p.Command = p.Comm
+		p.Name = p.Command
+	}
+
+	// One character from the string "RSDZTW" where R
+	// is running, S is sleeping in an interruptible wait,
+	// D is waiting in uninterruptible disk sleep, Z is
+	// zombie, T is traced or stopped (on a signal), and W
+	// is paging.
+	p.State = values[statState][0]
+
+	p.Ppid = stat[statPpid]
+
+	p.Pgrp = stat[statPgrp]
+
+	lastUtime := p.Utime
+	p.Utime = stat[statUtime]
+	p.UtimeDiff = p.Utime - lastUtime
+
+	lastStime := p.Stime
+	p.Stime = stat[statStime]
+	p.StimeDiff = p.Stime - lastStime
+
+	lastCutime, lastCstime := p.Cutime, p.Cstime
+	p.Cutime, p.Cstime = stat[statCutime], stat[statCstime]
+	p.CutimeDiff, p.CstimeDiff = p.Cutime-lastCutime, p.Cstime-lastCstime
+
+	p.RSS = stat[statRSS]
+
+	p.Nice = nice
+	p.Threads = stat[statNumThreads]
+	if len(values) > statDelayActBlkioTicks {
+		p.blkioTicks, _ = ParseUint64(values[statDelayActBlkioTicks])
+	}
+
+	lastMinflt, lastMajflt := p.Minflt, p.Majflt
+	p.Minflt, p.Majflt = stat[statMinflt], stat[statMajflt]
+	p.MinfltDiff, p.MajfltDiff = p.Minflt-lastMinflt, p.Majflt-lastMajflt
+
+	p.StartTime = stat[statStartTime]
+
+	// The state will only be running if it's running at the exact
+	// moment this file was read. That's probably not what the
+	// average user wants, even though it's what top and htop do.
+	// Set it to be running if it's used any CPU since the last update.
+	if !p.initializing && p.State == 'S' && (p.UtimeDiff > 0 || p.StimeDiff > 0) {
+		p.State = 'R'
+	}
+
+	return nil
+}
+
+func (p *Process) parseIoFile(buf *readBuffer) error {
+	path := fmt.Sprintf("/proc/%d/io", p.Pid)
+
+	data, err := buf.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	lastReadBytes, lastWriteBytes := p.ReadBytes, p.WriteBytes
+
+	for len(data) > 0 {
+		// line = "read_bytes: 4096"
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		colon := bytes.IndexByte(line, ':')
+		if colon == -1 || colon+2 > len(line) {
+			continue
+		}
+		var field *uint64
+		switch string(line[:colon]) {
+		case "read_bytes":
+			field = &p.ReadBytes
+		case "write_bytes":
+			field = &p.WriteBytes
+		default:
+			continue
+		}
+		if value, err := ParseUint64(string(line[colon+2:])); err == nil {
+			*field = value
+		}
+	}
+
+	if !p.initializing {
+		p.ReadBytesDiff = p.ReadBytes - lastReadBytes
+		p.WriteBytesDiff = p.WriteBytes - lastWriteBytes
+	}
+	return nil
+}
+
+func (p *Process) parseOOMScoreFile(buf *readBuffer) error {
+	path := fmt.Sprintf("/proc/%d/oom_score", p.Pid)
+
+	data, err := buf.readFile(path)
+	if err != nil {
+		return err
+	}
+	score, err := ParseUint64(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	p.OOMScore = score
+	return nil
+}
+
+func (p *Process) parseStatusFile(buf *readBuffer) error {
+	path := fmt.Sprintf("/proc/%d/status", p.Pid)
+
+	data, err := buf.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	lastVoluntary, lastNonvoluntary := p.VoluntaryCtxtSwitches, p.NonvoluntaryCtxtSwitches
+	// Kernel threads have no VmSwap line.
+	p.Swap = 0
+
+	for len(data) > 0 {
+		// line = "voluntary_ctxt_switches:\t150"
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		colon := bytes.IndexByte(line, ':')
+		if colon == -1 {
+			continue
+		}
+		var field *uint64
+		switch string(line[:colon]) {
+		case "voluntary_ctxt_switches":
+			field = &p.VoluntaryCtxtSwitches
+		case "nonvoluntary_ctxt_switches":
+			field = &p.NonvoluntaryCtxtSwitches
+		case "VmSwap":
+			field = &p.Swap
+		default:
+			continue
+		}
+		// line = "VmSwap:\t    1024 kB"
+		value := strings.TrimSuffix(strings.TrimSpace(string(line[colon+1:])), " kB")
+		if value, err := ParseUint64(value); err == nil {
+			*field = value
+		}
+	}
+
+	p.Swap *= KB
+	if !p.initializing {
+		p.VoluntaryCtxtSwitchesDiff = p.VoluntaryCtxtSwitches - lastVoluntary
+		p.NonvoluntaryCtxtSwitchesDiff = p.NonvoluntaryCtxtSwitches - lastNonvoluntary
+	}
+	return nil
+}
+
+func (p *Process) hasEmptyCmdlineFile() bool {
+	return p.IsKernelThread() || p.State == 'Z'
+}
+
+func (p *Process) parseCmdlineFile(buf *readBuffer) error {
+	path := fmt.Sprintf("/proc/%d/cmdline", p.Pid)
+
+	data, err := buf.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	cmdline := string(data)
+	p.Command = strings.TrimSpace(strings.Replace(cmdline, "\x00", " ", -1))
+	p.Name = commandToName(p.Command)
+	return nil
+}
+
+// parseCommFile sets the Name of a kernel thread, which has no command line,
+// and its Command to the name in brackets like ps does, such as
+// "[kworker/3:1]".
+func (p *Process) parseCommFile(buf *readBuffer) error {
+	data, err := buf.readFile(fmt.Sprintf("/proc/%d/comm", p.Pid))
+	if err != nil {
+		return err
+	}
+	p.Name = strings.TrimSuffix(string(data), "\n")
+	p.Command = "[" + p.Name + "]"
+	return nil
+}
+
+// KernelThreadKind returns the name of a kernel thread without the CPU or
+// the work it's bound to, such as "kworker" for "kworker/3:1-events".
+func KernelThreadKind(name string) string {
+	if i := strings.IndexByte(name, '/'); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// readExe sets Exe, which the owner of the process (or root) can read, and
+// which ends with " (deleted)" if the executable was replaced since.
+func (p *Process) readExe() {
+	p.Exe, _ = os.Readlink(fmt.Sprintf("/proc/%d/exe", p.Pid))
+}
+
+// commandToName takes a string in a format like "/usr/bin/foo --arguments"
+// and returns its base name without arguments, "foo".
+func commandToName(cmdline string) string {
+	command := strings.Split(cmdline, " ")[0]
+	if strings.HasSuffix(command, ":") {
+		// For processes that set their name in a format like
+		// "postgres: writer process" the value is returned as is.
+		return cmdline
+	}
+	return path.Base(command)
+}
+
+type ByPid []*Process
+
+func (p ByPid) Len() int      { return len(p) }
+func (p ByPid) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByPid) Less(i, j int) bool {
+	return p[i].Pid < p[j].Pid
+}
+
+type ByUser []*Process
+
+func (p ByUser) Len() int      { return len(p) }
+func (p ByUser) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByUser) Less(i, j int) bool {
+	return p[i].User.Username < p[j].User.Username
+}
+
+type ByRSS []*Process
+
+func (p ByRSS) Len() int      { return len(p) }
+func (p ByRSS) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByRSS) Less(i, j int) bool {
+	return p[i].RSS > p[j].RSS
+}
+
+type ByCPU []*Process
+
+func (p ByCPU) Len() int      { return len(p) }
+func (p ByCPU) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByCPU) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	p1Diff := p1.UtimeDiff + p1.StimeDiff
+	p2Diff := p2.UtimeDiff + p2.StimeDiff
+	if p1Diff == p2Diff {
+		return p1.Pid < p2.Pid
+	}
+	return p1Diff > p2Diff
+}
+
+// ByStart sorts the most recently started processes first.
+type ByStart []*Process
+
+func (p ByStart) Len() int      { return len(p) }
+func (p ByStart) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByStart) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.StartTime == p2.StartTime {
+		return p1.Pid < p2.Pid
+	}
+	return p1.StartTime > p2.StartTime
+}
+
+type ByTime []*Process
+
+func (p ByTime) Len() int      { return len(p) }
+func (p ByTime) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByTime) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	p1Total := p1.Utime + p1.Stime
+	p2Total := p2.Utime + p2.Stime
+	if p1Total == p2Total {
+		return p1.Pid < p2.Pid
+	}
+	return p1Total > p2Total
+}
+
+type ByState []*Process
+
+func (p ByState) Len() int      { return len(p) }
+func (p ByState) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByState) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.State == p2.State {
+		return p1.Pid < p2.Pid
+	}
+	return p1.State < p2.State
+}
+
+type ByName []*Process
+
+func (p ByName) Len() int      { return len(p) }
+func (p ByName) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByName) Less(i, j int) bool {
+	return p[i].Name < p[j].Name
+}
+
+type ByDiskRead []*Process
+
+func (p ByDiskRead) Len() int      { return len(p) }
+func (p ByDiskRead) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByDiskRead) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.ReadBytesDiff == p2.ReadBytesDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.ReadBytesDiff > p2.ReadBytesDiff
+}
+
+type ByDiskWrite []*Process
+
+func (p ByDiskWrite) Len() int      { return len(p) }
+func (p ByDiskWrite) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByDiskWrite) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.WriteBytesDiff == p2.WriteBytesDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.WriteBytesDiff > p2.WriteBytesDiff
+}
+
+type ByContainer []*Process
+
+func (p ByContainer) Len() int      { return len(p) }
+func (p ByContainer) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByContainer) Less(i, j int) bool {
+	p1, p2 := p[i].ContainerName(), p[j].ContainerName()
+	if p1 == p2 {
+		return p[i].Pid < p[j].Pid
+	}
+	return p1 < p2
+}
+
+type ByNetRx []*Process
+
+func (p ByNetRx) Len() int      { return len(p) }
+func (p ByNetRx) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByNetRx) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.NetRxBytesDiff == p2.NetRxBytesDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.NetRxBytesDiff > p2.NetRxBytesDiff
+}
+
+type ByNetTx []*Process
+
+func (p ByNetTx) Len() int      { return len(p) }
+func (p ByNetTx) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByNetTx) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.NetTxBytesDiff == p2.NetTxBytesDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.NetTxBytesDiff > p2.NetTxBytesDiff
+}
+
+type ByWakeups []*Process
+
+func (p ByWakeups) Len() int      { return len(p) }
+func (p ByWakeups) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByWakeups) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.VoluntaryCtxtSwitchesDiff == p2.VoluntaryCtxtSwitchesDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.VoluntaryCtxtSwitchesDiff > p2.VoluntaryCtxtSwitchesDiff
+}
+
+// ByNonvoluntaryCtxtSwitches sorts the processes preempted the most often
+// first, which are the ones competing for CPUs.
+type ByNonvoluntaryCtxtSwitches []*Process
+
+func (p ByNonvoluntaryCtxtSwitches) Len() int      { return len(p) }
+func (p ByNonvoluntaryCtxtSwitches) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByNonvoluntaryCtxtSwitches) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.NonvoluntaryCtxtSwitchesDiff == p2.NonvoluntaryCtxtSwitchesDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.NonvoluntaryCtxtSwitchesDiff > p2.NonvoluntaryCtxtSwitchesDiff
+}
+
+type ByThreads []*Process
+
+func (p ByThreads) Len() int      { return len(p) }
+func (p ByThreads) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByThreads) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.Threads == p2.Threads {
+		return p1.Pid < p2.Pid
+	}
+	return p1.Threads > p2.Threads
+}
+
+type ByOOMScore []*Process
+
+func (p ByOOMScore) Len() int      { return len(p) }
+func (p ByOOMScore) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByOOMScore) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.OOMScore == p2.OOMScore {
+		return p1.Pid < p2.Pid
+	}
+	return p1.OOMScore > p2.OOMScore
+}
+
+type BySwap []*Process
+
+func (p BySwap) Len() int      { return len(p) }
+func (p BySwap) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p BySwap) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.Swap == p2.Swap {
+		return p1.Pid < p2.Pid
+	}
+	return p1.Swap > p2.Swap
+}
+
+type ByMajflt []*Process
+
+func (p ByMajflt) Len() int      { return len(p) }
+func (p ByMajflt) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByMajflt) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.MajfltDiff == p2.MajfltDiff {
+		if p1.MinfltDiff == p2.MinfltDiff {
+			return p1.Pid < p2.Pid
+		}
+		return p1.MinfltDiff > p2.MinfltDiff
+	}
+	return p1.MajfltDiff > p2.MajfltDiff
+}
+
+type ByMinflt []*Process
+
+func (p ByMinflt) Len() int      { return len(p) }
+func (p ByMinflt) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByMinflt) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.MinfltDiff == p2.MinfltDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.MinfltDiff > p2.MinfltDiff
+}
+
+type BySecurityContext []*Process
+
+func (p BySecurityContext) Len() int      { return len(p) }
+func (p BySecurityContext) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p BySecurityContext) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.SecurityContext == p2.SecurityContext {
+		return p1.Pid < p2.Pid
+	}
+	return p1.SecurityContext < p2.SecurityContext
+}
+
+// ByNice sorts the processes with the highest priority first.
+type ByNice []*Process
+
+func (p ByNice) Len() int      { return len(p) }
+func (p ByNice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByNice) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.Nice == p2.Nice {
+		return p1.Pid < p2.Pid
+	}
+	return p1.Nice < p2.Nice
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+)
+
+func (p *Process) statProcDir() error {
+	path := fmt.Sprintf("/proc/%d", p.Pid)
+
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return err
+	}
+
+	p.User = UserByUid(strconv.FormatUint(uint64(stat.Uid), 10))
+	return nil
+}
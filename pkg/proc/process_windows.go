@@ -0,0 +1,485 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// On Windows, the processes are listed with a Toolhelp snapshot and read
+// through handles opened with PROCESS_QUERY_LIMITED_INFORMATION, which every
+// user has to the processes that aren't protected. Only the fields with an
+// equivalent are set: the CPU times, working set, page faults, I/O, threads,
+// priority, user, command line and start time of the processes, and the CPU
+// times and memory of the system.
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	ntdll    = syscall.NewLazyDLL("ntdll.dll")
+
+	procGetSystemTimes             = kernel32.NewProc("GetSystemTimes")
+	procGetTickCount64             = kernel32.NewProc("GetTickCount64")
+	procGlobalMemoryStatusEx       = kernel32.NewProc("GlobalMemoryStatusEx")
+	procGetProcessIoCounters       = kernel32.NewProc("GetProcessIoCounters")
+	procK32GetProcessMemoryInfo    = kernel32.NewProc("K32GetProcessMemoryInfo")
+	procQueryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
+	procSetPriorityClass           = kernel32.NewProc("SetPriorityClass")
+	procNtQueryInformationProcess  = ntdll.NewProc("NtQueryInformationProcess")
+)
+
+// The following are from the Windows SDK.
+const (
+	processQueryLimitedInformation = 0x1000
+	processSetInformation          = 0x0200
+
+	errorInvalidParameter syscall.Errno = 87
+
+	processCommandLineInformation = 60
+	statusInfoLengthMismatch      = 0xc0000004
+
+	idlePriorityClass        = 0x40
+	belowNormalPriorityClass = 0x4000
+	normalPriorityClass      = 0x20
+	aboveNormalPriorityClass = 0x8000
+	highPriorityClass        = 0x80
+	realtimePriorityClass    = 0x100
+
+	// filetimeTicks is the number of 100-nanosecond intervals, the unit of
+	// a FILETIME, in a second.
+	filetimeTicks = 10000000
+)
+
+// The System Idle Process and System, whose threads are the kernel's, can't
+// be opened.
+const (
+	idlePid   uint64 = 0
+	systemPid uint64 = 4
+
+	// localSystemSid is the SID of the account they run as.
+	localSystemSid = "S-1-5-18"
+)
+
+// unknownUser is the User of the processes whose token can't be opened,
+// those of the other users without administrator rights.
+var unknownUser = &user.User{Username: "-"}
+
+// errPidReused is returned when reading a Process whose Pid was reused by
+// another process since the last update, which happens quickly on Windows.
+var errPidReused = errors.New("pid reused")
+
+// struct MEMORYSTATUSEX
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// struct PROCESS_MEMORY_COUNTERS
+type processMemoryCounters struct {
+	Cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// struct IO_COUNTERS
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+// struct UNICODE_STRING
+type unicodeString struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        *uint16
+}
+
+// Update updates the Monitor state from a snapshot of the processes and the
+// system wide counters. Processes that exit while being read are skipped, any
+// other failure is returned as an *UpdateError.
+func (m *Monitor) Update() error {
+	var errs []error
+
+	lastCPUTimeTotal, lastCPUIdleTotal := m.CPUTimeTotal, m.CPUIdleTotal
+	if err := m.readSystemTimes(); err != nil {
+		errs = append(errs, err)
+	}
+	m.CPUTimeDiff = m.CPUTimeTotal - lastCPUTimeTotal
+	m.CPUIdleDiff = m.CPUIdleTotal - lastCPUIdleTotal
+	if err := m.readMemoryStatus(); err != nil {
+		errs = append(errs, err)
+	}
+
+	now := time.Now()
+	if !m.LastUpdate.IsZero() {
+		m.Interval = now.Sub(m.LastUpdate)
+	}
+	m.LastUpdate = now
+	if m.BootTime.IsZero() {
+		// The milliseconds since boot.
+		uptime, _, _ := procGetTickCount64.Call()
+		m.BootTime = now.Add(-time.Duration(uptime) * time.Millisecond)
+	}
+
+	entries, err := snapshotProcesses()
+	if err != nil {
+		// Keep the processes of the last update rather than removing
+		// all of them.
+		return &UpdateError{append(errs, err)}
+	}
+
+	for _, p := range m.List {
+		p.Alive = false
+	}
+
+	m.RestrictedProcesses = 0
+	for i := range entries {
+		entry := &entries[i]
+		pid := uint64(entry.ProcessID)
+		if !m.pidWhitelisted(pid) {
+			continue
+		}
+
+		p, ok := m.Map[pid]
+		if !ok {
+			p = &Process{Pid: pid, initializing: true}
+		}
+		err := m.readProcess(p, entry)
+		if err == errPidReused {
+			m.removeProcess(p)
+			p, ok = &Process{Pid: pid, initializing: true}, false
+			err = m.readProcess(p, entry)
+		}
+		if err == errorInvalidParameter {
+			continue // exited since the snapshot
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", p, err))
+			continue
+		}
+		p.initializing = false
+
+		if !ok {
+			if p.IsKernelThread() && !m.KernelThreads {
+				continue
+			}
+			if !m.userWhitelisted(p) || !m.containerWhitelisted(p) || !m.namespaceWhitelisted(p) || m.excluded(p) {
+				continue
+			}
+			m.addProcess(p)
+			m.logStarted(p)
+		} else if !m.userWhitelisted(p) || m.excluded(p) {
+			continue
+		}
+		p.Alive = true
+		if p.Restricted {
+			m.RestrictedProcesses++
+		}
+	}
+
+	if m.GPU {
+		if err := m.updateGPUs(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	m.removeDeadProcesses()
+	m.recordHistory()
+	m.Sort()
+
+	if len(errs) > 0 {
+		return &UpdateError{errs}
+	}
+	return nil
+}
+
+// snapshotProcesses returns the Toolhelp entries of every running process.
+func snapshotProcesses() ([]syscall.ProcessEntry32, error) {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("CreateToolhelp32Snapshot: %v", err)
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var entries []syscall.ProcessEntry32
+	entry := syscall.ProcessEntry32{Size: uint32(unsafe.Sizeof(syscall.ProcessEntry32{}))}
+	err = syscall.Process32First(snapshot, &entry)
+	for err == nil {
+		entries = append(entries, entry)
+		err = syscall.Process32Next(snapshot, &entry)
+	}
+	if err != syscall.ERROR_NO_MORE_FILES {
+		return nil, fmt.Errorf("Process32Next: %v", err)
+	}
+	return entries, nil
+}
+
+// removeProcess removes a Process whose Pid was reused before the next call
+// to removeDeadProcesses.
+func (m *Monitor) removeProcess(p *Process) {
+	for i, q := range m.List {
+		if q == p {
+			m.List = append(m.List[:i], m.List[i+1:]...)
+			break
+		}
+	}
+	delete(m.Map, p.Pid)
+	m.logExited(p)
+}
+
+// readProcess updates a Process from its Toolhelp entry and the information
+// of the process. A process that can't be opened is Restricted, and one with
+// a token that can't be opened has the unknownUser.
+func (m *Monitor) readProcess(p *Process, entry *syscall.ProcessEntry32) error {
+	p.Ppid = uint64(entry.ParentProcessID)
+	p.Threads = uint64(entry.Threads)
+	p.Nice = priorityNice(entry.PriClassBase)
+	p.Comm = syscall.UTF16ToString(entry.ExeFile[:])
+	if p.initializing {
+		p.Name, p.Command = p.Comm, p.Comm
+		p.User = unknownUser
+		// Windows has handles rather than file descriptors.
+		p.FDs = -1
+	}
+
+	if p.Pid == idlePid || p.Pid == systemPid {
+		// In process group 0 like the kernel threads of Linux.
+		p.Pgrp = 0
+		p.Command = "[" + p.Name + "]"
+		p.User = UserByUid(localSystemSid)
+		return nil
+	}
+	p.Pgrp = p.Pid
+
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(p.Pid))
+	if err == syscall.ERROR_ACCESS_DENIED {
+		if p.initializing {
+			p.Restricted = true
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return err
+	}
+	// The boot time is only known to the millisecond.
+	var startTime uint64
+	if since := creation.Nanoseconds() - m.BootTime.UnixNano(); since > 0 {
+		startTime = m.filetimeToTicks(uint64(since) / 100)
+	}
+	if !p.initializing && startTime != p.StartTime {
+		return errPidReused
+	}
+	p.StartTime = startTime
+
+	lastUtime, lastStime := p.Utime, p.Stime
+	p.Utime = m.filetimeToTicks(filetimeValue(user))
+	p.Stime = m.filetimeToTicks(filetimeValue(kernel))
+	if !p.initializing && p.Utime >= lastUtime && p.Stime >= lastStime {
+		p.UtimeDiff, p.StimeDiff = p.Utime-lastUtime, p.Stime-lastStime
+	} else {
+		p.UtimeDiff, p.StimeDiff = 0, 0
+	}
+	// Windows has no process states, so like on Linux a process is
+	// running if it used any CPU since the last update.
+	p.State = 'S'
+	if p.UtimeDiff > 0 || p.StimeDiff > 0 {
+		p.State = 'R'
+	}
+
+	counters := processMemoryCounters{Cb: uint32(unsafe.Sizeof(processMemoryCounters{}))}
+	if r, _, _ := procK32GetProcessMemoryInfo.Call(uintptr(h), uintptr(unsafe.Pointer(&counters)), uintptr(counters.Cb)); r != 0 {
+		p.RSS = uint64(counters.WorkingSetSize) / m.PageSize
+		lastMinflt := p.Minflt
+		p.Minflt = uint64(counters.PageFaultCount)
+		if !p.initializing && p.Minflt >= lastMinflt {
+			p.MinfltDiff = p.Minflt - lastMinflt
+		}
+	}
+
+	var io ioCounters
+	if r, _, _ := procGetProcessIoCounters.Call(uintptr(h), uintptr(unsafe.Pointer(&io))); r != 0 {
+		lastReadBytes, lastWriteBytes := p.ReadBytes, p.WriteBytes
+		p.ReadBytes, p.WriteBytes = io.ReadTransferCount, io.WriteTransferCount
+		if !p.initializing {
+			p.ReadBytesDiff = p.ReadBytes - lastReadBytes
+			p.WriteBytesDiff = p.WriteBytes - lastWriteBytes
+		}
+	}
+
+	// Like on Linux, the user, executable and command line of a process
+	// rarely change.
+	if p.initializing {
+		if u := tokenUser(h); u != nil {
+			p.User = u
+		}
+		p.Exe = imageName(h)
+		if command := commandLine(h); command != "" {
+			p.Command = command
+		} else if p.Exe != "" {
+			p.Command = p.Exe
+		}
+	}
+	return nil
+}
+
+// filetimeValue returns the 100-nanosecond intervals of a FILETIME.
+func filetimeValue(ft syscall.Filetime) uint64 {
+	return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
+}
+
+// filetimeToTicks converts 100-nanosecond intervals to clock ticks.
+func (m *Monitor) filetimeToTicks(value uint64) uint64 {
+	return value / (filetimeTicks / m.ClockTicks)
+}
+
+// readSystemTimes sets CPUTimeTotal and CPUIdleTotal, the kernel time
+// including the idle time.
+func (m *Monitor) readSystemTimes() error {
+	var idle, kernel, user syscall.Filetime
+	r, _, err := procGetSystemTimes.Call(uintptr(unsafe.Pointer(&idle)), uintptr(unsafe.Pointer(&kernel)), uintptr(unsafe.Pointer(&user)))
+	if r == 0 {
+		return fmt.Errorf("GetSystemTimes: %v", err)
+	}
+	m.CPUTimeTotal = m.filetimeToTicks(filetimeValue(kernel) + filetimeValue(user))
+	m.CPUIdleTotal = m.filetimeToTicks(filetimeValue(idle))
+	return nil
+}
+
+// readMemoryStatus sets MemTotal, MemAvailable and their Meminfo values.
+func (m *Monitor) readMemoryStatus() error {
+	status := memoryStatusEx{Length: uint32(unsafe.Sizeof(memoryStatusEx{}))}
+	r, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
+	if r == 0 {
+		return fmt.Errorf("GlobalMemoryStatusEx: %v", err)
+	}
+	m.MemTotal, m.MemAvailable = status.TotalPhys, status.AvailPhys
+	m.Meminfo = map[string]uint64{
+		"MemTotal":     status.TotalPhys,
+		"MemAvailable": status.AvailPhys,
+	}
+	return nil
+}
+
+// tokenUser returns the User of the token of a process, or nil if it can't be
+// opened.
+func tokenUser(h syscall.Handle) *user.User {
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(h, syscall.TOKEN_QUERY, &token); err != nil {
+		return nil
+	}
+	defer token.Close()
+	tu, err := token.GetTokenUser()
+	if err != nil {
+		return nil
+	}
+	sid, err := tu.User.Sid.String()
+	if err != nil {
+		return nil
+	}
+	// The Uid of a User is its SID on Windows.
+	return UserByUid(sid)
+}
+
+// imageName returns the path of the executable of a process, or "" if it
+// can't be queried.
+func imageName(h syscall.Handle) string {
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	r, _, _ := procQueryFullProcessImageNameW.Call(uintptr(h), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if r == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf[:size])
+}
+
+// commandLine returns the command line of a process, or "" if it can't be
+// queried, which requires Windows 8.1.
+func commandLine(h syscall.Handle) string {
+	var size uint32
+	status, _, _ := procNtQueryInformationProcess.Call(uintptr(h), processCommandLineInformation, 0, 0, uintptr(unsafe.Pointer(&size)))
+	if status != statusInfoLengthMismatch || size < uint32(unsafe.Sizeof(unicodeString{})) {
+		return ""
+	}
+	// A UNICODE_STRING followed by the characters it points to, which
+	// have to be aligned like it.
+	buf := make([]uintptr, (uintptr(size)+unsafe.Sizeof(uintptr(0))-1)/unsafe.Sizeof(uintptr(0)))
+	status, _, _ = procNtQueryInformationProcess.Call(uintptr(h), processCommandLineInformation, uintptr(unsafe.Pointer(&buf[0])), uintptr(size), uintptr(unsafe.Pointer(&size)))
+	if status != 0 {
+		return ""
+	}
+	s := (*unicodeString)(unsafe.Pointer(&buf[0]))
+	if s.Buffer == nil || s.Length == 0 {
+		return ""
+	}
+	n := int(s.Length / 2)
+	return syscall.UTF16ToString((*[1 << 29]uint16)(unsafe.Pointer(s.Buffer))[:n:n])
+}
+
+// priorityNice returns the nice value closest to the base priority of the
+// priority class of a process, from 4 for the idle class to 24 for the
+// realtime one, 8 being the normal one.
+func priorityNice(base int32) int {
+	switch {
+	case base <= 4:
+		return MaxNice
+	case base <= 6:
+		return 10
+	case base <= 8:
+		return 0
+	case base <= 10:
+		return -10
+	case base <= 13:
+		return -15
+	}
+	return MinNice
+}
+
+// priorityClass returns the priority class of a nice value, the reverse of
+// priorityNice.
+func priorityClass(nice int) uintptr {
+	switch {
+	case nice >= 15:
+		return idlePriorityClass
+	case nice >= 5:
+		return belowNormalPriorityClass
+	case nice > -5:
+		return normalPriorityClass
+	case nice > -13:
+		return aboveNormalPriorityClass
+	case nice > -18:
+		return highPriorityClass
+	}
+	return realtimePriorityClass
+}
+
+// statProcDir returns errUnsupported, there's no proc filesystem to read the
+// restricted processes from.
+func (p *Process) statProcDir() error {
+	return errUnsupported
+}
@@ -0,0 +1,674 @@
+package proc
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+)
+
+// Snapshot is the state of a Monitor at a point in time, as written by a
+// Recorder and read by a Player.
+type Snapshot struct {
+	Time time.Time
+
+	NumCPUs      int
+	MemTotal     uint64
+	MemAvailable uint64
+	Meminfo      map[string]uint64
+	PageSize     uint64
+	ClockTicks   uint64
+	BootTime     time.Time
+
+	CPUTimeTotal uint64
+	CPUTimeDiff  uint64
+	CPUIdleTotal uint64
+	CPUIdleDiff  uint64
+	LoadAvg      [3]float64
+	Interval     time.Duration
+
+	Processes  []ProcessSnapshot
+	Interfaces []NetInterface
+	Disks      []Disk
+	GPUs       []GPU
+	Temps      []Sensor
+	Fans       []Sensor
+	Batteries  []Battery
+	Pressure   []Pressure
+	NumaNodes  []NumaNode
+
+	// The following are only set in the Snapshots of a Sampler, so the
+	// Monitor loading them displays everything the sampled one has.
+	Log                 []ProcessEvent
+	Interrupts          []Interrupt
+	Cgroups             []CgroupSnapshot
+	Cgroup2Root         string
+	HidePid             string
+	ProcSubset          string
+	RestrictedProcesses int
+	UnreadableFiles     []string
+}
+
+// ProcessSnapshot is the state of a Process at a point in time. Unlike
+// Process it has no pointers, so it can be encoded.
+type ProcessSnapshot struct {
+	Pid             uint64
+	Uid             string
+	Username        string
+	Name            string
+	Command         string
+	Comm            string
+	Exe             string
+	ContainerID     string
+	ContainerName   string
+	Cgroup          string
+	Unit            string
+	SecurityContext string
+	Namespaces      [3]uint64
+
+	State     byte
+	Ppid      uint64
+	Pgrp      uint64
+	Utime     uint64
+	Stime     uint64
+	UtimeDiff uint64
+	StimeDiff uint64
+	RSS       uint64
+	StartTime uint64
+	Nice      int
+
+	ReadBytes      uint64
+	WriteBytes     uint64
+	ReadBytesDiff  uint64
+	WriteBytesDiff uint64
+	NetRxBytesDiff uint64
+	NetTxBytesDiff uint64
+	GPUTimeDiff    uint64
+	GPUMemory      uint64
+
+	VoluntaryCtxtSwitchesDiff    uint64
+	NonvoluntaryCtxtSwitchesDiff uint64
+
+	Swap       uint64
+	MinfltDiff uint64
+	MajfltDiff uint64
+	Threads    uint64
+	OOMScore   uint64
+	IOPriority IOPriority
+	Frozen     bool
+	FDs        int
+	FDLimit    uint64
+	Restricted bool
+
+	CgroupMemoryMax     uint64
+	CgroupMemoryCurrent uint64
+	CgroupThrottledDiff uint64
+
+	CPUDelayDiff   uint64
+	BlkioDelayDiff uint64
+
+	Cutime     uint64
+	Cstime     uint64
+	CutimeDiff uint64
+	CstimeDiff uint64
+
+	NumaMemory []uint64
+
+	Extra []string
+}
+
+// CgroupSnapshot is the state of a Cgroup at a point in time, without its
+// processes so it can be encoded.
+type CgroupSnapshot struct {
+	Path               string
+	UsageUsec          uint64
+	UsageUsecDiff      uint64
+	MemoryCurrent      uint64
+	MemoryMax          uint64
+	MemoryLimitCurrent uint64
+	ThrottledUsec      uint64
+	ThrottledUsecDiff  uint64
+	Frozen             bool
+}
+
+// Snapshot returns the current state of the Monitor.
+func (m *Monitor) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Time:         m.LastUpdate,
+		NumCPUs:      m.NumCPUs,
+		MemTotal:     m.MemTotal,
+		MemAvailable: m.MemAvailable,
+		Meminfo:      m.Meminfo,
+		PageSize:     m.PageSize,
+		ClockTicks:   m.ClockTicks,
+		BootTime:     m.BootTime,
+		CPUTimeTotal: m.CPUTimeTotal,
+		CPUTimeDiff:  m.CPUTimeDiff,
+		CPUIdleTotal: m.CPUIdleTotal,
+		CPUIdleDiff:  m.CPUIdleDiff,
+		LoadAvg:      m.LoadAvg,
+		Interval:     m.Interval,
+	}
+	for _, p := range m.List {
+		s.Processes = append(s.Processes, ProcessSnapshot{
+			Pid:             p.Pid,
+			Uid:             p.User.Uid,
+			Username:        p.User.Username,
+			Name:            p.Name,
+			Command:         p.Command,
+			Comm:            p.Comm,
+			Exe:             p.Exe,
+			ContainerID:     p.ContainerID,
+			ContainerName:   p.containerName,
+			Cgroup:          p.Cgroup,
+			Unit:            p.Unit,
+			SecurityContext: p.SecurityContext,
+			Namespaces:      p.Namespaces,
+			State:           p.State,
+			Ppid:            p.Ppid,
+			Pgrp:            p.Pgrp,
+			Utime:           p.Utime,
+			Stime:           p.Stime,
+			UtimeDiff:       p.UtimeDiff,
+			StimeDiff:       p.StimeDiff,
+			RSS:             p.RSS,
+			StartTime:       p.StartTime,
+			Nice:            p.Nice,
+			ReadBytes:       p.ReadBytes,
+			WriteBytes:      p.WriteBytes,
+			ReadBytesDiff:   p.ReadBytesDiff,
+			WriteBytesDiff:  p.WriteBytesDiff,
+			NetRxBytesDiff:  p.NetRxBytesDiff,
+			NetTxBytesDiff:  p.NetTxBytesDiff,
+			GPUTimeDiff:     p.GPUTimeDiff,
+			GPUMemory:       p.GPUMemory,
+
+			VoluntaryCtxtSwitchesDiff:    p.VoluntaryCtxtSwitchesDiff,
+			NonvoluntaryCtxtSwitchesDiff: p.NonvoluntaryCtxtSwitchesDiff,
+
+			Swap:       p.Swap,
+			MinfltDiff: p.MinfltDiff,
+			MajfltDiff: p.MajfltDiff,
+			Threads:    p.Threads,
+			OOMScore:   p.OOMScore,
+			IOPriority: p.IOPriority,
+			Frozen:     p.Frozen,
+			FDs:        p.FDs,
+			FDLimit:    p.FDLimit,
+			Restricted: p.Restricted,
+
+			CgroupMemoryMax:     p.CgroupMemoryMax,
+			CgroupMemoryCurrent: p.CgroupMemoryCurrent,
+			CgroupThrottledDiff: p.CgroupThrottledDiff,
+
+			CPUDelayDiff:   p.CPUDelayDiff,
+			BlkioDelayDiff: p.BlkioDelayDiff,
+			Cutime:         p.Cutime,
+			Cstime:         p.Cstime,
+			CutimeDiff:     p.CutimeDiff,
+			CstimeDiff:     p.CstimeDiff,
+			NumaMemory:     p.NumaMemory,
+			Extra:          p.Extra,
+		})
+	}
+	for _, iface := range m.Interfaces {
+		s.Interfaces = append(s.Interfaces, *iface)
+	}
+	for _, disk := range m.Disks {
+		s.Disks = append(s.Disks, *disk)
+	}
+	for _, gpu := range m.GPUs {
+		s.GPUs = append(s.GPUs, *gpu)
+	}
+	for _, sensor := range m.Temps {
+		s.Temps = append(s.Temps, *sensor)
+	}
+	for _, sensor := range m.Fans {
+		s.Fans = append(s.Fans, *sensor)
+	}
+	for _, battery := range m.Batteries {
+		s.Batteries = append(s.Batteries, *battery)
+	}
+	for _, pressure := range m.Pressure {
+		s.Pressure = append(s.Pressure, *pressure)
+	}
+	for _, node := range m.NumaNodes {
+		s.NumaNodes = append(s.NumaNodes, *node)
+	}
+	return s
+}
+
+// liveSnapshot returns a Snapshot of the Monitor along with its Log, its
+// Interrupts, its Cgroups and its restrictions, which a Monitor loading it
+// displays like its own.
+func (m *Monitor) liveSnapshot() *Snapshot {
+	s := m.Snapshot()
+	s.Log = append([]ProcessEvent{}, m.Log...)
+	for _, irq := range m.Interrupts {
+		s.Interrupts = append(s.Interrupts, *irq)
+	}
+	for _, cg := range m.Cgroups {
+		s.Cgroups = append(s.Cgroups, CgroupSnapshot{
+			Path:               cg.Path,
+			UsageUsec:          cg.UsageUsec,
+			UsageUsecDiff:      cg.UsageUsecDiff,
+			MemoryCurrent:      cg.MemoryCurrent,
+			MemoryMax:          cg.MemoryMax,
+			MemoryLimitCurrent: cg.MemoryLimitCurrent,
+			ThrottledUsec:      cg.ThrottledUsec,
+			ThrottledUsecDiff:  cg.ThrottledUsecDiff,
+			Frozen:             cg.Frozen,
+		})
+	}
+	s.Cgroup2Root = m.Cgroup2Root
+	s.HidePid, s.ProcSubset = m.HidePid, m.ProcSubset
+	s.RestrictedProcesses = m.RestrictedProcesses
+	s.UnreadableFiles = append([]string(nil), m.UnreadableFiles...)
+	return s
+}
+
+// Load replaces the state of the Monitor with a Snapshot.
+func (m *Monitor) Load(s *Snapshot) {
+	m.NumCPUs = s.NumCPUs
+	m.MemTotal = s.MemTotal
+	m.MemAvailable = s.MemAvailable
+	m.Meminfo = s.Meminfo
+	m.PageSize = s.PageSize
+	m.ClockTicks = s.ClockTicks
+	m.BootTime = s.BootTime
+	m.CPUTimeTotal = s.CPUTimeTotal
+	m.CPUTimeDiff = s.CPUTimeDiff
+	m.CPUIdleTotal = s.CPUIdleTotal
+	m.CPUIdleDiff = s.CPUIdleDiff
+	m.LoadAvg = s.LoadAvg
+	m.Interval = s.Interval
+	m.LastUpdate = s.Time
+
+	// The history of the processes is kept from the previous Snapshot.
+	previous := m.Map
+	m.List = nil
+	m.Map = make(map[uint64]*Process)
+	users := make(map[string]*user.User)
+	for _, ps := range s.Processes {
+		u := users[ps.Uid]
+		if u == nil {
+			u = m.loadedUsers[ps.Uid]
+		}
+		if u == nil || u.Username != ps.Username {
+			u = &user.User{Uid: ps.Uid, Username: ps.Username}
+		}
+		users[ps.Uid] = u
+		m.addProcess(&Process{
+			Pid:             ps.Pid,
+			User:            u,
+			Name:            ps.Name,
+			Command:         ps.Command,
+			Comm:            ps.Comm,
+			Exe:             ps.Exe,
+			ContainerID:     ps.ContainerID,
+			containerName:   ps.ContainerName,
+			Cgroup:          ps.Cgroup,
+			Unit:            ps.Unit,
+			SecurityContext: ps.SecurityContext,
+			Namespaces:      ps.Namespaces,
+			Alive:           true,
+			State:           ps.State,
+			Ppid:            ps.Ppid,
+			Pgrp:            ps.Pgrp,
+			Utime:           ps.Utime,
+			Stime:           ps.Stime,
+			UtimeDiff:       ps.UtimeDiff,
+			StimeDiff:       ps.StimeDiff,
+			RSS:             ps.RSS,
+			StartTime:       ps.StartTime,
+			Nice:            ps.Nice,
+			ReadBytes:       ps.ReadBytes,
+			WriteBytes:      ps.WriteBytes,
+			ReadBytesDiff:   ps.ReadBytesDiff,
+			WriteBytesDiff:  ps.WriteBytesDiff,
+			NetRxBytesDiff:  ps.NetRxBytesDiff,
+			NetTxBytesDiff:  ps.NetTxBytesDiff,
+			GPUTimeDiff:     ps.GPUTimeDiff,
+			GPUMemory:       ps.GPUMemory,
+
+			VoluntaryCtxtSwitchesDiff:    ps.VoluntaryCtxtSwitchesDiff,
+			NonvoluntaryCtxtSwitchesDiff: ps.NonvoluntaryCtxtSwitchesDiff,
+
+			Swap:       ps.Swap,
+			MinfltDiff: ps.MinfltDiff,
+			MajfltDiff: ps.MajfltDiff,
+			Threads:    ps.Threads,
+			OOMScore:   ps.OOMScore,
+			IOPriority: ps.IOPriority,
+			Frozen:     ps.Frozen,
+			FDs:        ps.FDs,
+			FDLimit:    ps.FDLimit,
+			Restricted: ps.Restricted,
+
+			CgroupMemoryMax:     ps.CgroupMemoryMax,
+			CgroupMemoryCurrent: ps.CgroupMemoryCurrent,
+			CgroupThrottledDiff: ps.CgroupThrottledDiff,
+
+			CPUDelayDiff:   ps.CPUDelayDiff,
+			BlkioDelayDiff: ps.BlkioDelayDiff,
+			Cutime:         ps.Cutime,
+			Cstime:         ps.Cstime,
+			CutimeDiff:     ps.CutimeDiff,
+			CstimeDiff:     ps.CstimeDiff,
+			NumaMemory:     ps.NumaMemory,
+			Extra:          ps.Extra,
+		})
+	}
+	m.loadedUsers = users
+	for _, p := range m.List {
+		if old, ok := previous[p.Pid]; ok && old.StartTime == p.StartTime {
+			p.CPUHistory, p.RSSHistory = old.CPUHistory, old.RSSHistory
+		}
+	}
+
+	m.Interfaces = nil
+	for i := range s.Interfaces {
+		m.Interfaces = append(m.Interfaces, &s.Interfaces[i])
+	}
+	m.Disks = nil
+	for i := range s.Disks {
+		m.Disks = append(m.Disks, &s.Disks[i])
+	}
+	m.GPUs = nil
+	for i := range s.GPUs {
+		m.GPUs = append(m.GPUs, &s.GPUs[i])
+	}
+	m.Temps, m.Fans = nil, nil
+	for i := range s.Temps {
+		m.Temps = append(m.Temps, &s.Temps[i])
+	}
+	for i := range s.Fans {
+		m.Fans = append(m.Fans, &s.Fans[i])
+	}
+	m.Batteries = nil
+	for i := range s.Batteries {
+		m.Batteries = append(m.Batteries, &s.Batteries[i])
+	}
+	m.Pressure = nil
+	for i := range s.Pressure {
+		m.Pressure = append(m.Pressure, &s.Pressure[i])
+	}
+	m.NumaNodes = nil
+	for i := range s.NumaNodes {
+		m.NumaNodes = append(m.NumaNodes, &s.NumaNodes[i])
+	}
+
+	if s.Log != nil {
+		m.Log = s.Log
+	}
+	m.Interrupts = nil
+	for i := range s.Interrupts {
+		m.Interrupts = append(m.Interrupts, &s.Interrupts[i])
+	}
+	m.Cgroups = nil
+	m.cgroupMap = make(map[string]*Cgroup)
+	for _, cs := range s.Cgroups {
+		cg := &Cgroup{
+			Path:               cs.Path,
+			UsageUsec:          cs.UsageUsec,
+			UsageUsecDiff:      cs.UsageUsecDiff,
+			MemoryCurrent:      cs.MemoryCurrent,
+			MemoryMax:          cs.MemoryMax,
+			MemoryLimitCurrent: cs.MemoryLimitCurrent,
+			ThrottledUsec:      cs.ThrottledUsec,
+			ThrottledUsecDiff:  cs.ThrottledUsecDiff,
+			Frozen:             cs.Frozen,
+		}
+		m.Cgroups = append(m.Cgroups, cg)
+		m.cgroupMap[cg.Path] = cg
+	}
+	for _, p := range m.List {
+		if cg, ok := m.cgroupMap[p.Cgroup]; ok {
+			cg.Processes = append(cg.Processes, p)
+		}
+	}
+	// The cgroup2 filesystem of a recorded system isn't available.
+	m.Cgroup2Root = s.Cgroup2Root
+	m.HidePid, m.ProcSubset = s.HidePid, s.ProcSubset
+	m.RestrictedProcesses = s.RestrictedProcesses
+	m.UnreadableFiles = s.UnreadableFiles
+
+	m.recordHistory()
+	m.Sort()
+}
+
+// Clone returns a Monitor with the same options, loaded with the Snapshot of
+// the Monitor, so later updates of the Monitor don't change it. Unlike
+// NewMonitor it doesn't probe the system, which is the Monitor's.
+func (m *Monitor) Clone() *Monitor {
+	c := &Monitor{
+		Map:          make(map[uint64]*Process),
+		NumCPUs:      m.NumCPUs,
+		PageSize:     m.PageSize,
+		ClockTicks:   m.ClockTicks,
+		interfaceMap: make(map[string]*NetInterface),
+		diskMap:      make(map[string]*Disk),
+		interruptMap: make(map[string]*Interrupt),
+		cgroupMap:    make(map[string]*Cgroup),
+		Cgroup2Root:  m.Cgroup2Root,
+		HidePid:      m.HidePid,
+		ProcSubset:   m.ProcSubset,
+		nvidiaSMI:    m.nvidiaSMI,
+		containers:   newContainerCache(),
+	}
+	c.KernelThreads = m.KernelThreads
+	c.Pids = m.Pids
+	c.Users = m.Users
+	c.Container = m.Container
+	c.Namespace = m.Namespace
+	c.ExcludeUsers = m.ExcludeUsers
+	c.ExcludeCommand = m.ExcludeCommand
+	c.NetTraffic = m.NetTraffic
+	c.GPU = m.GPU
+	c.Delays = m.Delays
+	c.Numa = m.Numa
+	c.FDs = m.FDs
+	c.Sensors = m.Sensors
+	c.Power = m.Power
+	c.IRQs = m.IRQs
+	c.Tree = m.Tree
+	c.SortKey = m.SortKey
+	c.Reverse = m.Reverse
+	c.KernelThreadsLast = m.KernelThreadsLast
+	c.CPULifetime = m.CPULifetime
+	c.CPUChildren = m.CPUChildren
+	c.ProcEvents = false
+	c.Load(m.liveSnapshot())
+	return c
+}
+
+// Recorder writes Snapshots to a file.
+type Recorder struct {
+	file    *os.File
+	w       *bufio.Writer
+	encoder *gob.Encoder
+}
+
+// NewRecorder returns a Recorder that writes to the file at path, which is
+// truncated if it already exists, or to the standard output if path is "-".
+func NewRecorder(path string) (*Recorder, error) {
+	file := os.Stdout
+	if path != "-" {
+		var err error
+		if file, err = os.Create(path); err != nil {
+			return nil, err
+		}
+	}
+	w := bufio.NewWriter(file)
+	return &Recorder{file, w, gob.NewEncoder(w)}, nil
+}
+
+// Record writes a Snapshot of the Monitor. The file is flushed after every
+// Snapshot so a recording is usable even if jtop doesn't exit cleanly.
+func (r *Recorder) Record(m *Monitor) error {
+	if err := r.encoder.Encode(m.Snapshot()); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+func (r *Recorder) Close() error {
+	if err := r.w.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// Player drives a Monitor from the Snapshots of a recording. Only the
+// Snapshots of the file of the current one are kept in memory, so the hours
+// of a SnapshotLog can be replayed.
+type Player struct {
+	monitor *Monitor
+	files   []recordingFile
+	total   int
+	current int
+	Paused  bool
+
+	// snapshots are the Snapshots of files[file].
+	file      int
+	snapshots []*Snapshot
+}
+
+// recordingFile is a file of a recording with the index of its first
+// Snapshot in the recording.
+type recordingFile struct {
+	path  string
+	first int
+	count int
+}
+
+// Position returns the index of the current Snapshot and the number of
+// Snapshots in the recording.
+func (p *Player) Position() (int, int) {
+	return p.current, p.total
+}
+
+// Current returns the current Snapshot.
+func (p *Player) Current() *Snapshot {
+	return p.snapshots[p.current-p.files[p.file].first]
+}
+
+// NewPlayer indexes the recording at path, or the files of the SnapshotLog
+// of a directory, and loads its first Snapshot into the Monitor.
+func NewPlayer(m *Monitor, path string) (*Player, error) {
+	paths := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if paths, err = logFiles(path); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Player{monitor: m}
+	for _, path := range paths {
+		count := 0
+		if err := decodeRecording(path, func(*Snapshot) { count++ }); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			p.files = append(p.files, recordingFile{path, p.total, count})
+			p.total += count
+		}
+	}
+	if p.total == 0 {
+		return nil, fmt.Errorf("%s: recording is empty", path)
+	}
+
+	if err := p.load(0); err != nil {
+		return nil, err
+	}
+	m.Load(p.snapshots[0])
+	return p, nil
+}
+
+// load reads the Snapshots of files[i].
+func (p *Player) load(i int) error {
+	var snapshots []*Snapshot
+	err := decodeRecording(p.files[i].path, func(s *Snapshot) { snapshots = append(snapshots, s) })
+	if err != nil {
+		return err
+	}
+	// The last file of a SnapshotLog may have been written to since it
+	// was indexed.
+	if len(snapshots) < p.files[i].count {
+		return fmt.Errorf("%s: recording was truncated", p.files[i].path)
+	}
+	p.file, p.snapshots = i, snapshots[:p.files[i].count]
+	return nil
+}
+
+// decodeRecording calls f with every Snapshot of a file written by a
+// Recorder or a SnapshotLog.
+func decodeRecording(path string, f func(s *Snapshot)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, err := recordingReader(file)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// The gzip header of a SnapshotLog file was never written.
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	decoder := gob.NewDecoder(r)
+	for {
+		s := &Snapshot{}
+		if err := decoder.Decode(s); err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			// The last Snapshot was only partially written, or the
+			// SnapshotLog file is still being written to.
+			break
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		f(s)
+	}
+	return nil
+}
+
+// Tick advances to the next Snapshot unless the Player is paused.
+func (p *Player) Tick() {
+	if !p.Paused {
+		p.Step(1)
+	}
+}
+
+// Step moves delta Snapshots forward (or backward if negative), reading
+// the file of the Snapshot if it's another one. It doesn't move if the file
+// can't be read anymore, e.g. because the SnapshotLog removed it.
+func (p *Player) Step(delta int) {
+	i := p.current + delta
+	if i < 0 || i >= p.total {
+		return
+	}
+	file := p.file
+	for i < p.files[file].first {
+		file--
+	}
+	for i >= p.files[file].first+p.files[file].count {
+		file++
+	}
+	if file != p.file {
+		if err := p.load(file); err != nil {
+			return
+		}
+	}
+	p.current = i
+	p.monitor.Load(p.Current())
+}
+
+func (p *Player) TogglePause() {
+	p.Paused = !p.Paused
+}
@@ -0,0 +1,149 @@
+package proc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Remote receives the Snapshots of a jtop running on another machine, either
+// over ssh or from an Agent.
+type Remote struct {
+	Name string
+
+	// Monitor is loaded with the Snapshots received.
+	Monitor *Monitor
+
+	mu       sync.Mutex
+	latest   *Snapshot
+	fresh    bool
+	received time.Time
+	err      error
+}
+
+// The delay before ssh is run again after it exits starts at sshRetryMin and
+// doubles up to sshRetryMax while no Snapshot is received, e.g. while the
+// host is down.
+const (
+	sshRetryMin = time.Second
+	sshRetryMax = time.Minute
+)
+
+// DialSSH runs command on host via ssh and returns a Remote receiving the
+// Snapshots it writes to its standard output, e.g. by running
+// "jtop --headless --record -". ssh is run again whenever it exits.
+func DialSSH(host string, command []string) (*Remote, error) {
+	// ssh passes the command to the remote shell as a single string.
+	var quoted []string
+	for _, arg := range command {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	args := []string{"-T", "-o", "BatchMode=yes", "--", host, strings.Join(quoted, " ")}
+
+	// Only ssh not being installed is final.
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, err
+	}
+
+	r := newRemote(host)
+	go func() {
+		retry := sshRetryMin
+		for {
+			received, err := r.runSSH(args)
+			r.setErr(err)
+			if received {
+				retry = sshRetryMin
+			}
+			time.Sleep(retry)
+			if retry *= 2; retry > sshRetryMax {
+				retry = sshRetryMax
+			}
+		}
+	}()
+	return r, nil
+}
+
+// runSSH runs ssh with args until it exits, and returns whether it sent a
+// Snapshot and why it exited.
+func (r *Remote) runSSH(args []string) (bool, error) {
+	cmd := exec.Command("ssh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+
+	received, err := r.receive(stdout)
+	if waitErr := cmd.Wait(); waitErr != nil {
+		err = waitErr
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s: %s", waitErr, msg)
+		}
+	}
+	return received, err
+}
+
+func newRemote(name string) *Remote {
+	return &Remote{Name: name, Monitor: NewMonitor()}
+}
+
+func (r *Remote) set(s *Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latest, r.fresh, r.received, r.err = s, true, time.Now(), nil
+}
+
+func (r *Remote) setErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// receive decodes Snapshots until the stream ends, and returns whether any
+// was received.
+func (r *Remote) receive(rd io.Reader) (bool, error) {
+	decoder := gob.NewDecoder(rd)
+	for received := false; ; received = true {
+		s := &Snapshot{}
+		if err := decoder.Decode(s); err == io.EOF {
+			return received, fmt.Errorf("connection closed")
+		} else if err != nil {
+			return received, err
+		}
+		r.set(s)
+	}
+}
+
+// Latest returns the most recent Snapshot if one has been received since
+// the last call, or nil.
+func (r *Remote) Latest() *Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.fresh {
+		return nil
+	}
+	r.fresh = false
+	return r.latest
+}
+
+// State returns the time the latest Snapshot was received at, which is zero
+// until the first one is, and the error the connection failed with, if any.
+func (r *Remote) State() (received time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.received, r.err
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,102 @@
+package proc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// hidepidNames are the names of the numeric values of the hidepid option of
+// proc, which kernels before 5.8 display.
+var hidepidNames = map[string]string{"0": "off", "1": "noaccess", "2": "invisible", "4": "ptraceable"}
+
+// readProcMount returns the hidepid and subset options /proc is mounted with,
+// or "" for the options it isn't mounted with.
+func readProcMount() (hidepid, subset string) {
+	file, err := os.Open("/proc/mounts")
+	if err != nil {
+		return "", ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line = "proc /proc proc rw,relatime,hidepid=invisible 0 0"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "/proc" || fields[2] != "proc" {
+			continue
+		}
+		// The last mount of /proc hides the others.
+		hidepid, subset = "", ""
+		for _, option := range strings.Split(fields[3], ",") {
+			switch {
+			case strings.HasPrefix(option, "hidepid="):
+				hidepid = strings.TrimPrefix(option, "hidepid=")
+				if name, ok := hidepidNames[hidepid]; ok {
+					hidepid = name
+				}
+			case strings.HasPrefix(option, "subset="):
+				subset = strings.TrimPrefix(option, "subset=")
+			}
+		}
+	}
+	if hidepid == "off" {
+		hidepid = ""
+	}
+	return hidepid, subset
+}
+
+// isPermission returns whether or not err is because a file of /proc can't be
+// read, usually because of hidepid or of a restricted container.
+func isPermission(err error) bool {
+	return errors.Is(err, os.ErrPermission)
+}
+
+// newRestrictedProcess returns the Process of a directory of /proc whose
+// files can't be read, of which only the Pid and the User are known.
+func newRestrictedProcess(pid uint64) (*Process, error) {
+	p := &Process{Pid: pid, Restricted: true, FDs: -1}
+	if err := p.statProcDir(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// systemFileError returns the error of reading a file of /proc outside of the
+// directories of the processes, unless it's because the file can't be read or
+// is hidden by the subset option, in which case it's added to the
+// UnreadableFiles and its values stay unknown.
+func (m *Monitor) systemFileError(err error) error {
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) || !(isPermission(err) || (m.ProcSubset != "" && os.IsNotExist(err))) {
+		return err
+	}
+	for _, path := range m.UnreadableFiles {
+		if path == pathErr.Path {
+			return nil
+		}
+	}
+	m.UnreadableFiles = append(m.UnreadableFiles, pathErr.Path)
+	return nil
+}
+
+// Restrictions returns what can't be read of /proc, or "" if every process
+// and system file can be read.
+func (m *Monitor) Restrictions() string {
+	var parts []string
+	switch m.HidePid {
+	case "invisible":
+		parts = append(parts, "/proc is mounted with hidepid=invisible, the processes of the other users aren't listed")
+	case "ptraceable":
+		parts = append(parts, "/proc is mounted with hidepid=ptraceable, only the processes jtop can trace are listed")
+	}
+	if m.RestrictedProcesses > 0 {
+		parts = append(parts, fmt.Sprintf("%d processes can't be read, their fields are displayed as -", m.RestrictedProcesses))
+	}
+	if len(m.UnreadableFiles) > 0 {
+		parts = append(parts, strings.Join(m.UnreadableFiles, ", ")+" can't be read")
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,209 @@
+package proc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Fields is a set of the optional data collected by a Sampler, besides the
+// processes and the system wide counters which are always collected.
+type Fields uint
+
+const (
+	// FieldNetTraffic is Monitor.NetTraffic.
+	FieldNetTraffic Fields = 1 << iota
+	// FieldGPU is Monitor.GPU.
+	FieldGPU
+	// FieldDelays is Monitor.Delays.
+	FieldDelays
+	// FieldNuma is Monitor.Numa.
+	FieldNuma
+	// FieldFDs is Monitor.FDs.
+	FieldFDs
+	// FieldSensors is Monitor.Sensors.
+	FieldSensors
+	// FieldPower is Monitor.Power.
+	FieldPower
+	// FieldInterrupts is Monitor.IRQs.
+	FieldInterrupts
+)
+
+// Sampler updates a Monitor every Interval, either by calling Next or in the
+// background between Start and the cancellation of its context. Interval
+// and Fields must be changed by SetInterval and SetFields once it's started.
+type Sampler struct {
+	Monitor  *Monitor
+	Interval time.Duration
+
+	// Fields are the optional data collected at every update, which set
+	// the options of the Monitor. Collecting less makes updates cheaper
+	// at high frequencies.
+	Fields Fields
+
+	// Buffer is the number of Snapshots that wait for a slow receiver of
+	// Snapshots, 1 if it's 0. When it's full, the oldest one is dropped so
+	// the memory used is bounded.
+	Buffer int
+
+	// Hook is called after every update if it isn't nil, before the
+	// Snapshot is taken. Its error is reported like one of the update.
+	Hook func(m *Monitor) error
+
+	last      time.Time
+	snapshots chan *Snapshot
+	// wake interrupts the wait for the next update when the Interval
+	// changes.
+	wake chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	dropped int
+}
+
+// NewSampler returns a Sampler updating a new Monitor every interval.
+func NewSampler(interval time.Duration) *Sampler {
+	return &Sampler{Monitor: NewMonitor(), Interval: interval}
+}
+
+// Next waits until Interval has elapsed since the previous update, updates
+// the Monitor and returns its Snapshot. The first Snapshot is returned
+// immediately, without any rates since there is no previous update. The
+// Snapshot is returned along with the error of Monitor.Update, if any.
+func (s *Sampler) Next() (*Snapshot, error) {
+	if !s.last.IsZero() {
+		time.Sleep(s.interval() - time.Since(s.last))
+	}
+	return s.update()
+}
+
+func (s *Sampler) update() (*Snapshot, error) {
+	s.mu.Lock()
+	fields := s.Fields
+	s.mu.Unlock()
+
+	m := s.Monitor
+	m.NetTraffic = fields&FieldNetTraffic != 0
+	m.GPU = fields&FieldGPU != 0
+	m.Delays = fields&FieldDelays != 0
+	m.Numa = fields&FieldNuma != 0
+	m.FDs = fields&FieldFDs != 0
+	m.Sensors = fields&FieldSensors != 0
+	m.Power = fields&FieldPower != 0
+	m.IRQs = fields&FieldInterrupts != 0
+
+	err := m.Update()
+	if s.Hook != nil {
+		if hookErr := s.Hook(m); err == nil {
+			err = hookErr
+		}
+	}
+	s.last = time.Now()
+	return m.liveSnapshot(), err
+}
+
+// SetInterval changes the Interval, which takes effect immediately if the
+// Sampler was started.
+func (s *Sampler) SetInterval(interval time.Duration) {
+	s.mu.Lock()
+	s.Interval = interval
+	s.mu.Unlock()
+	if s.wake != nil {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SetFields changes the Fields, which take effect at the next update.
+func (s *Sampler) SetFields(fields Fields) {
+	s.mu.Lock()
+	s.Fields = fields
+	s.mu.Unlock()
+}
+
+func (s *Sampler) interval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Interval
+}
+
+// Start updates the Monitor in a goroutine until ctx is done, sending a
+// Snapshot of every update to the channel returned by Snapshots, which is
+// closed then. The Monitor must not be used while the Sampler runs, and Start
+// must only be called once.
+func (s *Sampler) Start(ctx context.Context) {
+	buffer := s.Buffer
+	if buffer <= 0 {
+		buffer = 1
+	}
+	s.snapshots = make(chan *Snapshot, buffer)
+	s.wake = make(chan struct{}, 1)
+
+	go func() {
+		defer close(s.snapshots)
+		for {
+			for waiting := !s.last.IsZero(); waiting; {
+				wait := time.NewTimer(s.interval() - time.Since(s.last))
+				select {
+				case <-ctx.Done():
+					wait.Stop()
+					return
+				case <-s.wake:
+					wait.Stop()
+				case <-wait.C:
+					waiting = false
+				}
+			}
+			snapshot, err := s.update()
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			s.send(snapshot)
+		}
+	}()
+}
+
+// send sends a Snapshot without blocking, dropping the oldest one waiting if
+// the buffer is full. The Sampler is the only sender, so there is room once
+// one is dropped.
+func (s *Sampler) send(snapshot *Snapshot) {
+	select {
+	case s.snapshots <- snapshot:
+		return
+	default:
+	}
+	select {
+	case <-s.snapshots:
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+	default:
+	}
+	s.snapshots <- snapshot
+}
+
+// Snapshots returns the channel of the Snapshots of the updates done since
+// Start, which must be called first.
+func (s *Sampler) Snapshots() <-chan *Snapshot {
+	return s.snapshots
+}
+
+// Err returns the error of the last update done since Start, if any.
+func (s *Sampler) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped returns the number of Snapshots dropped since Start because they
+// weren't received in time.
+func (s *Sampler) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
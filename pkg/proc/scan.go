@@ -0,0 +1,204 @@
+package proc
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+const readBufferSize = 4096
+
+// readBuffer is reused to read the files of many processes, so reading them
+// doesn't allocate on every update. A readBuffer must only be used by one
+// goroutine at a time.
+type readBuffer struct {
+	data   []byte
+	fields []string
+}
+
+// readFile reads the file at path into the buffer. The returned slice is
+// only valid until the next call.
+func (b *readBuffer) readFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if b.data == nil {
+		b.data = make([]byte, readBufferSize)
+	}
+	n := 0
+	for {
+		if n == len(b.data) {
+			b.data = append(b.data, make([]byte, len(b.data))...)
+		}
+		m, err := file.Read(b.data[n:])
+		n += m
+		if err == io.EOF {
+			return b.data[:n], nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// split splits s around each space. The returned slice is only valid until
+// the next call.
+func (b *readBuffer) split(s string) []string {
+	b.fields = b.fields[:0]
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' {
+			b.fields = append(b.fields, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(b.fields, s[start:])
+}
+
+// scanJob reads one process for updateProcesses.
+type scanJob struct {
+	pid    uint64
+	p      *Process // nil until a new process has been read
+	isNew  bool
+	execed bool
+	// netTraffic reads the sockets of the process, see Monitor.NetTraffic.
+	netTraffic bool
+	// gpu reads the DRM clients of the process, see Monitor.GPU.
+	gpu bool
+	err error
+}
+
+func (job *scanJob) run(buf *readBuffer) {
+	switch {
+	case job.isNew:
+		job.p, job.err = newProcess(job.pid, buf)
+		if isPermission(job.err) {
+			job.p, job.err = newRestrictedProcess(job.pid)
+		}
+	case job.p.Restricted:
+		// Only the directory of the process can be read, to tell it's
+		// still running.
+		job.err = job.p.statProcDir()
+		return
+	default:
+		job.err = job.p.update(buf)
+		if isPermission(job.err) {
+			// The process exec'd a setuid program, its last values
+			// are displayed as unknown.
+			job.p.Restricted, job.err = true, nil
+			return
+		}
+		if job.err == nil && job.execed && !job.p.hasEmptyCmdlineFile() {
+			job.p.parseCmdlineFile(buf)
+		}
+	}
+	if job.err == nil && job.p.Restricted {
+		return
+	}
+	if job.err == nil && job.netTraffic {
+		// The file descriptors of other users' processes can't be read
+		// without privileges, their traffic is simply unknown.
+		job.p.readSocketInodes()
+	}
+	if job.err == nil && job.gpu {
+		job.p.readDRMFdinfo(buf)
+	}
+}
+
+// updateProcesses reads the processes with the passed in Pids using up to
+// Workers goroutines, then marks the ones that are still running as Alive and
+// adds the new ones. It returns the errors of the processes that couldn't be
+// read, except the ones that vanished.
+func (m *Monitor) updateProcesses(pids []uint64, execed map[uint64]bool) []error {
+	jobs := m.jobs[:0]
+	for _, pid := range pids {
+		if !m.pidWhitelisted(pid) {
+			continue
+		}
+		p, ok := m.Map[pid]
+		jobs = append(jobs, scanJob{
+			pid:        pid,
+			p:          p,
+			isNew:      !ok,
+			execed:     execed[pid],
+			netTraffic: m.NetTraffic,
+			gpu:        m.GPU,
+		})
+	}
+	m.jobs = jobs
+
+	workers := m.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	for len(m.buffers) < workers {
+		m.buffers = append(m.buffers, &readBuffer{})
+	}
+
+	next := int64(-1)
+	var wg sync.WaitGroup
+	for _, buf := range m.buffers[:workers] {
+		wg.Add(1)
+		go func(buf *readBuffer) {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(jobs) {
+					return
+				}
+				jobs[i].run(buf)
+			}
+		}(buf)
+	}
+	wg.Wait()
+
+	var errs []error
+	m.RestrictedProcesses = 0
+	for i := range jobs {
+		job := &jobs[i]
+		if job.err != nil {
+			if !Vanished(job.pid, job.err) {
+				errs = append(errs, job.err)
+			}
+			continue
+		}
+
+		p := job.p
+		if p.ContainerID != "" {
+			p.containerName = m.containers.name(p.ContainerID)
+		}
+		if job.isNew {
+			if p.IsKernelThread() && !m.KernelThreads {
+				continue
+			}
+			if !m.userWhitelisted(p) || !m.containerWhitelisted(p) || !m.namespaceWhitelisted(p) || m.excluded(p) {
+				continue
+			}
+			m.addProcess(p)
+			m.logStarted(p)
+		} else if !m.userWhitelisted(p) || m.excluded(p) {
+			continue
+		}
+		p.Alive = true
+		if p.Restricted {
+			m.RestrictedProcesses++
+		}
+	}
+
+	m.containers.prune()
+
+	// Don't keep the processes that exited from being garbage collected
+	// until the next update.
+	for i := range jobs {
+		jobs[i] = scanJob{}
+	}
+	return errs
+}
@@ -0,0 +1,54 @@
+package proc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseSecurityContext sets SecurityContext from /proc/<pid>/attr/current,
+// which can't be read when no security module is enabled.
+func (p *Process) parseSecurityContext(buf *readBuffer) {
+	data, err := buf.readFile(fmt.Sprintf("/proc/%d/attr/current", p.Pid))
+	if err != nil {
+		p.SecurityContext = ""
+		return
+	}
+	// data = "system_u:system_r:sshd_t:s0-s0:c0.c1023\x00" with SELinux or
+	// "/usr/sbin/cupsd (enforce)\n" with AppArmor.
+	p.SecurityContext = strings.TrimRight(string(data), "\x00\n")
+}
+
+// capabilityNames are the names of the capabilities, by bit number, see
+// capabilities(7).
+var capabilityNames = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill",
+	"setgid", "setuid", "setpcap", "linux_immutable", "net_bind_service",
+	"net_broadcast", "net_admin", "net_raw", "ipc_lock", "ipc_owner",
+	"sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct",
+	"sys_admin", "sys_boot", "sys_nice", "sys_resource", "sys_time",
+	"sys_tty_config", "mknod", "lease", "audit_write", "audit_control",
+	"setfcap", "mac_override", "mac_admin", "syslog", "wake_alarm",
+	"block_suspend", "audit_read", "perfmon", "bpf", "checkpoint_restore",
+}
+
+// CapabilityNames returns the names of the capabilities of a mask, such as
+// the CapEff line of /proc/<pid>/status, or "all" if it contains every known
+// capability.
+func CapabilityNames(mask uint64) []string {
+	all := uint64(1)<<uint(len(capabilityNames)) - 1
+	if mask&all == all {
+		return []string{"all"}
+	}
+	var names []string
+	for i := uint(0); i < 64; i++ {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		if int(i) < len(capabilityNames) {
+			names = append(names, capabilityNames[i])
+		} else {
+			names = append(names, fmt.Sprintf("cap_%d", i))
+		}
+	}
+	return names
+}
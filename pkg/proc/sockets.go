@@ -0,0 +1,195 @@
+package proc
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tcpStates are the states in /proc/net/tcp, see include/net/tcp_states.h.
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// Socket represents an entry of one of the socket tables in /proc/net.
+type Socket struct {
+	Proto string // tcp, tcp6, udp, udp6 or unix
+	Inode uint64
+
+	LocalIP    net.IP
+	LocalPort  uint16
+	RemoteIP   net.IP
+	RemotePort uint16
+	State      string
+
+	// Path is only set for unix sockets.
+	Path string
+}
+
+func (s *Socket) String() string {
+	if s.Proto == "unix" {
+		if s.Path == "" {
+			return "unix (unnamed)"
+		}
+		return "unix " + s.Path
+	}
+	local := net.JoinHostPort(s.LocalIP.String(), strconv.Itoa(int(s.LocalPort)))
+	if s.State == "LISTEN" || s.RemotePort == 0 {
+		return fmt.Sprintf("%s %s %s", s.Proto, local, s.State)
+	}
+	remote := net.JoinHostPort(s.RemoteIP.String(), strconv.Itoa(int(s.RemotePort)))
+	return fmt.Sprintf("%s %s->%s %s", s.Proto, local, remote, s.State)
+}
+
+// ReadSockets returns every socket in the tcp, tcp6, udp, udp6 and unix
+// tables of /proc/net keyed by inode. Missing tables (e.g. when IPv6 is
+// disabled) are skipped.
+func ReadSockets() map[uint64]*Socket {
+	sockets := make(map[uint64]*Socket)
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		parseInetSocketFile(proto, sockets)
+	}
+	parseUnixSocketFile(sockets)
+	return sockets
+}
+
+func parseInetSocketFile(proto string, sockets map[uint64]*Socket) {
+	file, err := os.Open("/proc/net/" + proto)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // skip header
+	for scanner.Scan() {
+		// fields = ["0:", "0100007F:0035", "00000000:0000", "0A", ...]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		s := &Socket{Proto: proto}
+		var err1, err2 error
+		s.LocalIP, s.LocalPort, err1 = parseHexAddr(fields[1])
+		s.RemoteIP, s.RemotePort, err2 = parseHexAddr(fields[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		s.State = tcpStates[fields[3]]
+		if strings.HasPrefix(proto, "udp") {
+			// UDP sockets reuse the TCP states, but only "listening"
+			// (unconnected) and "established" (connected) make sense.
+			if s.State == "CLOSE" {
+				s.State = "UNCONN"
+			}
+		}
+		if s.Inode, err = ParseUint64(fields[9]); err == nil && s.Inode != 0 {
+			sockets[s.Inode] = s
+		}
+	}
+}
+
+// parseHexAddr parses an address such as "0100007F:0035" from /proc/net/tcp.
+// The IP address is stored as a sequence of 32 bit words in host (little
+// endian) byte order.
+func parseHexAddr(s string) (net.IP, uint16, error) {
+	colon := strings.IndexByte(s, ':')
+	if colon == -1 {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	ip, err := hex.DecodeString(s[:colon])
+	if err != nil || len(ip)%4 != 0 {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+	port, err := strconv.ParseUint(s[colon+1:], 16, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+	return net.IP(ip), uint16(port), nil
+}
+
+func parseUnixSocketFile(sockets map[uint64]*Socket) {
+	file, err := os.Open("/proc/net/unix")
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // skip header
+	for scanner.Scan() {
+		// Num RefCount Protocol Flags Type St Inode Path
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		inode, err := ParseUint64(fields[6])
+		if err != nil {
+			continue
+		}
+		s := &Socket{Proto: "unix", Inode: inode}
+		if len(fields) > 7 {
+			s.Path = fields[7]
+		}
+		sockets[s.Inode] = s
+	}
+}
+
+// SocketInode returns the inode of a file descriptor link target such as
+// "socket:[12345]".
+func SocketInode(target string) (uint64, bool) {
+	if !strings.HasPrefix(target, "socket:[") || !strings.HasSuffix(target, "]") {
+		return 0, false
+	}
+	inode, err := ParseUint64(target[len("socket:[") : len(target)-1])
+	return inode, err == nil
+}
+
+// SocketOwners returns the processes of a Monitor that have each socket
+// open, keyed by inode. A socket shared by several processes, like the
+// listening socket of a preforking server, is owned by the one with the
+// lowest Pid.
+func SocketOwners(m *Monitor) map[uint64]*Process {
+	owners := make(map[uint64]*Process)
+	for _, p := range m.List {
+		if p.Members != nil {
+			continue
+		}
+		dir := fmt.Sprintf("/proc/%d/fd", p.Pid)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			target, err := os.Readlink(dir + "/" + entry.Name())
+			if err != nil {
+				continue
+			}
+			inode, ok := SocketInode(target)
+			if !ok {
+				continue
+			}
+			if owner, ok := owners[inode]; !ok || p.Pid < owner.Pid {
+				owners[inode] = p
+			}
+		}
+	}
+	return owners
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import "log/syslog"
+
+// newSyslog opens the system log to write the alerts of the rules with the
+// syslog hook.
+func newSyslog() (syslogWriter, error) {
+	w, err := syslog.New(syslog.LOG_WARNING|syslog.LOG_DAEMON, "jtop")
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
@@ -0,0 +1,6 @@
+package proc
+
+// newSyslog fails on Windows, which has the event log instead of syslog.
+func newSyslog() (syslogWriter, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,114 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"syscall"
+)
+
+// taskstatsConn queries the delay accounting of processes via the taskstats
+// generic netlink family. It requires CAP_NET_ADMIN.
+type taskstatsConn struct {
+	fd     int
+	family uint16
+	seq    uint32
+	buf    []byte
+}
+
+// updateDelays sets the delays of every process, from taskstats if it's
+// available and otherwise from /proc/<pid>/schedstat and the
+// delayacct_blkio_ticks of /proc/<pid>/stat. Block I/O delays are only
+// accounted for when the kernel.task_delayacct sysctl is set.
+func (m *Monitor) updateDelays() {
+	if m.taskstats == nil && !m.taskstatsFailed {
+		c, err := openTaskstats()
+		m.taskstats, m.taskstatsFailed = c, err != nil
+	}
+
+	for _, p := range m.List {
+		if m.taskstats != nil {
+			cpu, blkio, err := m.taskstats.delays(p.Pid)
+			if err == nil {
+				p.setDelays(cpu, blkio)
+				continue
+			}
+			if err != syscall.ESRCH {
+				// Resolving the family doesn't require
+				// CAP_NET_ADMIN, so this is usually EPERM.
+				m.closeTaskstats()
+				m.taskstatsFailed = true
+			}
+		}
+		data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/schedstat", p.Pid))
+		if err != nil {
+			continue // exited since it was read
+		}
+		// data = "<time on CPU> <time waiting for a CPU> <timeslices>"
+		fields := strings.Fields(string(data))
+		if len(fields) < 2 {
+			continue
+		}
+		cpu, err := ParseUint64(fields[1])
+		if err != nil {
+			continue
+		}
+		p.setDelays(cpu, p.blkioTicks*1e9/m.ClockTicks)
+	}
+}
+
+func (m *Monitor) closeTaskstats() error {
+	if m.taskstats == nil {
+		return nil
+	}
+	err := m.taskstats.Close()
+	m.taskstats = nil
+	return err
+}
+
+// setDelays sets the total delays of the Process, and the delays since they
+// were last set.
+func (p *Process) setDelays(cpu, blkio uint64) {
+	if p.delaysSet && cpu >= p.CPUDelay && blkio >= p.BlkioDelay {
+		p.CPUDelayDiff, p.BlkioDelayDiff = cpu-p.CPUDelay, blkio-p.BlkioDelay
+	} else {
+		p.CPUDelayDiff, p.BlkioDelayDiff = 0, 0
+	}
+	p.CPUDelay, p.BlkioDelay, p.delaysSet = cpu, blkio, true
+}
+
+// DelayPercent returns the percentage of the last interval that a delay
+// in nanoseconds over it represents.
+func (m *Monitor) DelayPercent(delay uint64) float64 {
+	if m.Interval <= 0 {
+		return 0
+	}
+	return 100 * float64(delay) / float64(m.Interval.Nanoseconds())
+}
+
+// ByCPUDelay sorts the processes that waited the longest for a CPU first.
+type ByCPUDelay []*Process
+
+func (p ByCPUDelay) Len() int      { return len(p) }
+func (p ByCPUDelay) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByCPUDelay) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.CPUDelayDiff == p2.CPUDelayDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.CPUDelayDiff > p2.CPUDelayDiff
+}
+
+// ByBlkioDelay sorts the processes that waited the longest for block I/O
+// first.
+type ByBlkioDelay []*Process
+
+func (p ByBlkioDelay) Len() int      { return len(p) }
+func (p ByBlkioDelay) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByBlkioDelay) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.BlkioDelayDiff == p2.BlkioDelayDiff {
+		return p1.Pid < p2.Pid
+	}
+	return p1.BlkioDelayDiff > p2.BlkioDelayDiff
+}
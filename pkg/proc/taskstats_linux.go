@@ -0,0 +1,161 @@
+package proc
+
+import (
+	"errors"
+	"syscall"
+)
+
+// The following are from linux/netlink.h, linux/genetlink.h and
+// linux/taskstats.h.
+const (
+	netlinkGeneric = 16
+
+	genlIDCtrl         = 0x10
+	ctrlCmdGetFamily   = 3
+	ctrlAttrFamilyID   = 1
+	ctrlAttrFamilyName = 2
+
+	taskstatsCmdGet       = 1
+	taskstatsCmdAttrTgid  = 2
+	taskstatsTypeStats    = 3
+	taskstatsTypeAggrTgid = 5
+
+	genlHdrLen  = 4
+	nlaHdrLen   = 4
+	nlaTypeMask = 0x3fff
+
+	// The offsets of cpu_delay_total and blkio_delay_total in struct
+	// taskstats, which haven't changed since its first version.
+	taskstatsCPUDelayTotal   = 24
+	taskstatsBlkioDelayTotal = 40
+)
+
+// openTaskstats opens a netlink socket and resolves the ID of the taskstats
+// family.
+func openTaskstats() (*taskstatsConn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkGeneric)
+	if err != nil {
+		return nil, err
+	}
+	// Don't wait forever for a reply that was lost.
+	syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &syscall.Timeval{Sec: 1})
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	c := &taskstatsConn{fd: fd, buf: make([]byte, syscall.Getpagesize())}
+	attrs, err := c.request(genlIDCtrl, ctrlCmdGetFamily, netlinkAttr(ctrlAttrFamilyName, []byte("TASKSTATS\x00")))
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	id := attrs[ctrlAttrFamilyID]
+	if len(id) < 2 {
+		syscall.Close(fd)
+		return nil, errors.New("taskstats: no family ID")
+	}
+	c.family = nativeEndian.Uint16(id)
+	return c, nil
+}
+
+// delays returns the time in nanoseconds the threads of a process waited for
+// a CPU and for block I/O.
+func (c *taskstatsConn) delays(pid uint64) (cpu, blkio uint64, err error) {
+	tgid := make([]byte, 4)
+	nativeEndian.PutUint32(tgid, uint32(pid))
+	attrs, err := c.request(c.family, taskstatsCmdGet, netlinkAttr(taskstatsCmdAttrTgid, tgid))
+	if err != nil {
+		return 0, 0, err
+	}
+	stats := parseNetlinkAttrs(attrs[taskstatsTypeAggrTgid])[taskstatsTypeStats]
+	if len(stats) < taskstatsBlkioDelayTotal+8 {
+		return 0, 0, errors.New("taskstats: malformed reply")
+	}
+	return nativeEndian.Uint64(stats[taskstatsCPUDelayTotal:]), nativeEndian.Uint64(stats[taskstatsBlkioDelayTotal:]), nil
+}
+
+// request sends a generic netlink request with one attribute to a family,
+// and returns the attributes of the reply.
+func (c *taskstatsConn) request(family uint16, cmd uint8, attr []byte) (map[uint16][]byte, error) {
+	c.seq++
+	msg := make([]byte, nlmsgHdrLen+genlHdrLen, nlmsgHdrLen+genlHdrLen+len(attr))
+	msg = append(msg, attr...)
+	// struct nlmsghdr
+	nativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	nativeEndian.PutUint16(msg[4:], family)
+	nativeEndian.PutUint16(msg[6:], syscall.NLM_F_REQUEST)
+	nativeEndian.PutUint32(msg[8:], c.seq)
+	// struct genlmsghdr
+	msg[nlmsgHdrLen] = cmd
+	msg[nlmsgHdrLen+1] = 1 // version
+
+	if err := syscall.Sendto(c.fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+	for {
+		n, _, err := syscall.Recvfrom(c.fd, c.buf, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(c.buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			// Replies to requests that timed out are skipped.
+			if msg.Header.Seq != c.seq {
+				continue
+			}
+			if msg.Header.Type == syscall.NLMSG_ERROR {
+				if len(msg.Data) >= 4 {
+					if errno := int32(nativeEndian.Uint32(msg.Data)); errno < 0 {
+						return nil, syscall.Errno(-errno)
+					}
+				}
+				return nil, errors.New("netlink: malformed error")
+			}
+			if len(msg.Data) < genlHdrLen {
+				return nil, errors.New("netlink: malformed reply")
+			}
+			return parseNetlinkAttrs(msg.Data[genlHdrLen:]), nil
+		}
+	}
+}
+
+func (c *taskstatsConn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// netlinkAttr returns a struct nlattr followed by its value and padding.
+func netlinkAttr(typ uint16, value []byte) []byte {
+	attr := make([]byte, netlinkAlign(nlaHdrLen+len(value)))
+	nativeEndian.PutUint16(attr[0:], uint16(nlaHdrLen+len(value)))
+	nativeEndian.PutUint16(attr[2:], typ)
+	copy(attr[nlaHdrLen:], value)
+	return attr
+}
+
+// parseNetlinkAttrs returns the values of a list of struct nlattr, by type.
+func parseNetlinkAttrs(data []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(data) >= nlaHdrLen {
+		n := int(nativeEndian.Uint16(data[0:]))
+		if n < nlaHdrLen || n > len(data) {
+			break
+		}
+		attrs[nativeEndian.Uint16(data[2:])&nlaTypeMask] = data[nlaHdrLen:n]
+		if n = netlinkAlign(n); n > len(data) {
+			break
+		}
+		data = data[n:]
+	}
+	return attrs
+}
+
+func netlinkAlign(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,34 @@
+package proc
+
+import "strings"
+
+// unitSuffixes are the suffixes of the systemd units processes run in.
+var unitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap"}
+
+// cgroupUnit returns the systemd unit of a cgroup path created by systemd,
+// which is its innermost service or scope: "/system.slice/ssh.service" is
+// in ssh.service, and "/user.slice/user-1000.slice/user@1000.service/app.slice/foo.scope"
+// in foo.scope. It returns "" if the path isn't in a unit.
+func cgroupUnit(path string) string {
+	parts := strings.Split(path, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		for _, suffix := range unitSuffixes {
+			if strings.HasSuffix(parts[i], suffix) {
+				return parts[i]
+			}
+		}
+	}
+	return ""
+}
+
+type ByUnit []*Process
+
+func (p ByUnit) Len() int      { return len(p) }
+func (p ByUnit) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p ByUnit) Less(i, j int) bool {
+	p1, p2 := p[i], p[j]
+	if p1.Unit == p2.Unit {
+		return p1.Pid < p2.Pid
+	}
+	return p1.Unit < p2.Unit
+}
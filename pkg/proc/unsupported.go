@@ -0,0 +1,49 @@
+//go:build !linux
+// +build !linux
+
+package proc
+
+// The following are only available on Linux and fail with errUnsupported on
+// the other systems. The Monitor handles the proc connector, taskstats and
+// sock_diag failing to open like a lack of privileges.
+
+func listenProcConnector() (*procConnector, error) {
+	return nil, errUnsupported
+}
+
+func (c *procConnector) Close() error {
+	return errUnsupported
+}
+
+func openTaskstats() (*taskstatsConn, error) {
+	return nil, errUnsupported
+}
+
+func (c *taskstatsConn) delays(pid uint64) (cpu, blkio uint64, err error) {
+	return 0, 0, errUnsupported
+}
+
+func (c *taskstatsConn) Close() error {
+	return errUnsupported
+}
+
+func readTCPTraffic() (map[uint64]socketTraffic, error) {
+	return nil, errUnsupported
+}
+
+// SetAffinity allows every thread of the process to run only on the passed
+// in CPUs.
+func (p *Process) SetAffinity(cpus []int) error {
+	return errUnsupported
+}
+
+func (p *Process) readIOPriority() {}
+
+// SetIOPriority sets the I/O priority of every thread of the process.
+func (p *Process) SetIOPriority(prio IOPriority) error {
+	return errUnsupported
+}
+
+func (mount *Mount) statfs() bool {
+	return false
+}
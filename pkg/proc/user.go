@@ -0,0 +1,48 @@
+package proc
+
+import (
+	"os/user"
+	"sync"
+	"time"
+)
+
+// userTTL is how long looking up a Uid is cached for, so users being renamed
+// or added show up eventually without looking them up at every update.
+const userTTL = 5 * time.Minute
+
+type cachedUser struct {
+	user    *user.User
+	expires time.Time
+}
+
+var (
+	// users is a cache to prevent unnecessary calls to `LookupId`, which
+	// can block on NSS or LDAP.
+	users   = map[string]cachedUser{}
+	usersMu sync.Mutex
+)
+
+// UserByUid returns a User for a particular Uid. When the Uid has no entry in
+// the user database, like the users of containers or the deleted ones, or it
+// can't be looked up, the Username of the User is the Uid.
+func UserByUid(uid string) *user.User {
+	now := time.Now()
+	usersMu.Lock()
+	cached, ok := users[uid]
+	usersMu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.user
+	}
+
+	// The lock isn't held while looking up, so a slow lookup doesn't block
+	// the processes of the other users being scanned.
+	u, err := user.LookupId(uid)
+	if err != nil {
+		u = &user.User{Uid: uid, Username: uid}
+	}
+
+	usersMu.Lock()
+	users[uid] = cachedUser{u, now.Add(userTTL)}
+	usersMu.Unlock()
+	return u
+}
@@ -0,0 +1,15 @@
+package proc
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+)
+
+// errUnsupported is returned by what only Linux has, like the netlink
+// interfaces, on the other systems.
+var errUnsupported = errors.New("not supported on " + runtime.GOOS)
+
+func ParseUint64(s string) (uint64, error) {
+	return strconv.ParseUint(s, 10, 64)
+}
@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// affinityCellWidth is the width of a CPU on the affinity screen, which
+// lays them out in a grid.
+const affinityCellWidth = 8
+
+// affinityScreen is the screen used to choose the CPUs processes are allowed
+// to run on. back is the screen displayed once it's closed.
+type affinityScreen struct {
+	targets  []*proc.Process
+	allowed  []bool
+	selected int
+	back     Screen
+}
+
+// HandleAffinity displays the screen to change the CPU affinity of the tagged
+// processes, or the selected one.
+func (ui *UI) HandleAffinity() {
+	if !ui.canControl() {
+		return
+	}
+	if targets := ui.targets(); len(targets) > 0 {
+		ui.screen = newAffinityScreen(ui.monitor, targets, nil)
+	}
+}
+
+// newAffinityScreen returns an affinityScreen starting with the affinity of
+// the first target.
+func newAffinityScreen(m *proc.Monitor, targets []*proc.Process, back Screen) *affinityScreen {
+	s := &affinityScreen{targets: targets, back: back}
+	cpus, _ := targets[0].Affinity()
+	n := m.NumCPUs
+	if len(cpus) > 0 && cpus[len(cpus)-1] >= n {
+		n = cpus[len(cpus)-1] + 1
+	}
+	s.allowed = make([]bool, n)
+	for _, cpu := range cpus {
+		s.allowed[cpu] = true
+	}
+	return s
+}
+
+func (s *affinityScreen) cpus() []int {
+	var cpus []int
+	for cpu, allowed := range s.allowed {
+		if allowed {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+// perRow returns the number of CPUs on each row of the grid.
+func (s *affinityScreen) perRow(ui *UI) int {
+	if n := ui.width / affinityCellWidth; n > 0 {
+		return n
+	}
+	return 1
+}
+
+func (s *affinityScreen) Draw(ui *UI) {
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	target := fmt.Sprintf("%d processes", len(s.targets))
+	if len(s.targets) == 1 {
+		target = s.targets[0].String()
+	}
+	ui.writeLastColumn(fmt.Sprintf(tr("CPU affinity of %s - space: toggle, a: all, e: edit list, enter: apply, q: cancel"), target))
+	ui.y++
+
+	ui.x = 0
+	ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+	cpus := s.cpus()
+	ui.writeLastColumn(fmt.Sprintf(tr("Allowed: %s (%d of %d CPUs)"), proc.FormatCPUList(cpus), len(cpus), len(s.allowed)))
+	ui.y++
+
+	perRow := s.perRow(ui)
+	for cpu, allowed := range s.allowed {
+		if cpu > 0 && cpu%perRow == 0 {
+			ui.y++
+		}
+		if ui.y >= ui.height {
+			break
+		}
+		ui.x = (cpu % perRow) * affinityCellWidth
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		if cpu == s.selected {
+			ui.fg, ui.bg = theme.SelectedFG, theme.SelectedBG
+		}
+		mark := "[ ]"
+		if allowed {
+			mark = "[x]"
+		}
+		ui.writeColumn(fmt.Sprintf("%s %d", mark, cpu), affinityCellWidth-1, false)
+	}
+}
+
+func (s *affinityScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	perRow := s.perRow(ui)
+	switch {
+	case ev.Ch == 'q' || ev.Key == termbox.KeyEsc:
+		return s.close(ui)
+	case ev.Ch == 'l' || ev.Key == termbox.KeyArrowRight:
+		s.move(1)
+	case ev.Ch == 'h' || ev.Key == termbox.KeyArrowLeft:
+		s.move(-1)
+	case ev.Ch == 'j' || ev.Key == termbox.KeyArrowDown:
+		s.move(perRow)
+	case ev.Ch == 'k' || ev.Key == termbox.KeyArrowUp:
+		s.move(-perRow)
+	case ev.Key == termbox.KeySpace:
+		s.allowed[s.selected] = !s.allowed[s.selected]
+	case ev.Ch == 'a':
+		for cpu := range s.allowed {
+			s.allowed[cpu] = true
+		}
+	case ev.Ch == 'e':
+		ui.prompt = &prompt{label: tr("CPUs (e.g. 0-3,8): "), text: []rune(proc.FormatCPUList(s.cpus())), done: s.edit}
+	case ev.Key == termbox.KeyEnter:
+		cpus := s.cpus()
+		if len(cpus) == 0 {
+			ui.SetStatus(tr("Processes must be allowed to run on at least one CPU"))
+			return true
+		}
+		var errs []error
+		for _, process := range s.targets {
+			if err := process.SetAffinity(cpus); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", process, err))
+			}
+		}
+		ui.setActionStatus(fmt.Sprintf(tr("Set the affinity to %s of"), proc.FormatCPUList(cpus)), s.targets, errs)
+		return s.close(ui)
+	}
+	return true
+}
+
+func (s *affinityScreen) move(delta int) {
+	if cpu := s.selected + delta; cpu >= 0 && cpu < len(s.allowed) {
+		s.selected = cpu
+	}
+}
+
+// edit sets the allowed CPUs to a list typed in the prompt.
+func (s *affinityScreen) edit(ui *UI, text string) {
+	cpus, err := proc.ParseCPUList(text)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	for _, cpu := range cpus {
+		if cpu >= len(s.allowed) {
+			ui.SetStatus(fmt.Sprintf(tr("CPU %d doesn't exist"), cpu))
+			return
+		}
+	}
+	for cpu := range s.allowed {
+		s.allowed[cpu] = false
+	}
+	for _, cpu := range cpus {
+		s.allowed[cpu] = true
+	}
+}
+
+// close displays the screen the affinity screen was opened from, if any.
+func (s *affinityScreen) close(ui *UI) bool {
+	if s.back == nil {
+		return false
+	}
+	ui.screen = s.back
+	return true
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// newCgroupsScreen returns a Screen that displays the processes grouped by
+// cgroup, along with the usage of each group.
+func newCgroupsScreen(m *proc.Monitor) Screen {
+	return &textScreen{
+		title: tr("Processes by cgroup - q: back"),
+		lines: func() []string { return cgroupLines(m) },
+	}
+}
+
+func cgroupLines(m *proc.Monitor) []string {
+	if m.Cgroup2Root == "" {
+		return []string{"The cgroup2 filesystem isn't mounted"}
+	}
+
+	cgroups := append([]*proc.Cgroup{}, m.Cgroups...)
+	sort.Sort(proc.ByCgroupCPU(cgroups))
+
+	lines := []string{fmt.Sprintf("%6s %6s %6s %7s %6s  %s", "%CPU", "MEM", "LIMIT", "%THROTL", "PROCS", "CGROUP")}
+	for _, cg := range cgroups {
+		// The limit can be the one of an ancestor, see Cgroup.MemoryMax.
+		limit := "-"
+		if cg.MemoryMax != 0 {
+			limit = formatBytes(cg.MemoryMax)
+		}
+		lines = append(lines, fmt.Sprintf("%6.1f %6s %6s %7.1f %6d  %s",
+			cg.CPUPercent(m), formatBytes(cg.MemoryCurrent), limit,
+			m.ThrottledPercent(cg.ThrottledUsecDiff), len(cg.Processes), cg.Path))
+
+		processes := append([]*proc.Process{}, cg.Processes...)
+		sort.Sort(proc.ByCPU(processes))
+		for _, p := range processes {
+			lines = append(lines, fmt.Sprintf("%6s %6s %6s %7s %6d    %s",
+				formatCPUPercent(m, p), formatRSS(m, p), "", "", p.Pid, p.Name))
+		}
+	}
+	return lines
+}
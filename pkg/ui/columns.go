@@ -0,0 +1,419 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+const (
+	// DefaultColumns is the comma-separated list of columns displayed by
+	// default.
+	DefaultColumns = "PID,USER,RSS,%MEM,%CPU,TIME+,S,COMMAND"
+
+	userColumnWidth = 8
+
+	securityColumnWidth = 24
+
+	unitColumnWidth = 24
+)
+
+// The percentages of a limit, like the soft limit of open files of the FD
+// column or the memory limit of the CG_MEM column, from which the columns are
+// displayed in the warning and critical colors of the theme.
+var (
+	LimitWarning  = 80.0
+	LimitCritical = 95.0
+)
+
+// Column describes a column of the process table.
+type Column struct {
+	Title      string
+	Width      int
+	RightAlign bool
+
+	// Sort is the key processes are sorted by when sorting by this column.
+	Sort proc.SortKey
+
+	// Format returns the value of this column for a particular Process.
+	Format func(m *proc.Monitor, p *proc.Process) string
+}
+
+// Value returns the value of this column for a particular Process, or "-" if
+// it's unknown because the files of the process can't be read.
+func (c *Column) Value(m *proc.Monitor, p *proc.Process) string {
+	if p.Restricted && c != PidColumn && c != UserColumn {
+		return "-"
+	}
+	return c.Format(m, p)
+}
+
+var (
+	PidColumn        = &Column{"PID", 5, true, proc.SortByPid, formatPid}
+	UserColumn       = &Column{"USER", userColumnWidth, false, proc.SortByUser, formatUser}
+	RSSColumn        = &Column{"RSS", 5, true, proc.SortByRSS, formatRSS}
+	MemPercentColumn = &Column{"%MEM", 5, true, proc.SortByRSS, formatMemPercent}
+	CPUPercentColumn = &Column{"%CPU", 5, true, proc.SortByCPU, formatCPUPercent}
+	CPUTimeColumn    = &Column{"TIME+", 9, true, proc.SortByTime, formatCPUTime}
+	StartColumn      = &Column{"START", 5, true, proc.SortByStart, formatStart}
+	StateColumn      = &Column{"S", 1, false, proc.SortByState, formatState}
+	NiceColumn       = &Column{"NI", 3, true, proc.SortByNice, formatNice}
+	CommandColumn    = &Column{"COMMAND", -1, false, proc.SortByName, formatCommand}
+	DiskReadColumn   = &Column{"DISK_R/s", 8, true, proc.SortByDiskRead, formatDiskRead}
+	DiskWriteColumn  = &Column{"DISK_W/s", 8, true, proc.SortByDiskWrite, formatDiskWrite}
+	ContainerColumn  = &Column{"CONTAINER", proc.ShortContainerIDLen, false, proc.SortByContainer, formatContainer}
+	NetRxColumn      = &Column{"NET_RX/s", 8, true, proc.SortByNetRx, formatNetRx}
+	NetTxColumn      = &Column{"NET_TX/s", 8, true, proc.SortByNetTx, formatNetTx}
+	GPUPercentColumn = &Column{"%GPU", 5, true, proc.SortByGPU, formatGPUPercent}
+	GPUMemoryColumn  = &Column{"GPU_MEM", 7, true, proc.SortByGPUMemory, formatGPUMemory}
+	WakeupsColumn    = &Column{"WAKEUP/s", 8, true, proc.SortByWakeups, formatWakeups}
+	SwapColumn       = &Column{"SWAP", 5, true, proc.SortBySwap, formatSwap}
+	MajfltColumn     = &Column{"MAJFLT/s", 8, true, proc.SortByMajflt, formatMajflt}
+	MinfltColumn     = &Column{"MINFLT/s", 8, true, proc.SortByMinflt, formatMinflt}
+	SecurityColumn   = &Column{"SECURITY", securityColumnWidth, false, proc.SortBySecurityContext, formatSecurityContext}
+	PidNSColumn      = &Column{"PIDNS", 10, true, proc.SortByPidNamespace, formatNamespace(0)}
+	NetNSColumn      = &Column{"NETNS", 10, true, proc.SortByNetNamespace, formatNamespace(1)}
+	MntNSColumn      = &Column{"MNTNS", 10, true, proc.SortByMntNamespace, formatNamespace(2)}
+	UnitColumn       = &Column{"UNIT", unitColumnWidth, false, proc.SortByUnit, formatUnit}
+	ThreadsColumn    = &Column{"THR", 4, true, proc.SortByThreads, formatThreads}
+	VCSWColumn       = &Column{"VCSW/s", 8, true, proc.SortByWakeups, formatWakeups}
+	NVCSWColumn      = &Column{"NVCSW/s", 8, true, proc.SortByNonvoluntaryCtxtSwitches, formatNonvoluntaryCtxtSwitches}
+	CPUWaitColumn    = &Column{"%CPUWAIT", 8, true, proc.SortByCPUDelay, formatCPUWait}
+	IOWaitColumn     = &Column{"%IOWAIT", 7, true, proc.SortByBlkioDelay, formatIOWait}
+	NumaColumn       = &Column{"NUMA", 8, false, proc.SortByNumaRemote, formatNuma}
+	OOMColumn        = &Column{"OOM", 4, true, proc.SortByOOMScore, formatOOMScore}
+	IOPriorityColumn = &Column{"IOPRIO", 6, false, proc.SortByIOPriority, formatIOPriority}
+	FDColumn         = &Column{"FD", 5, true, proc.SortByFDs, formatFDs}
+	CgroupMemColumn  = &Column{"CG_MEM", 6, true, proc.SortByCgroupMemory, formatCgroupMemory}
+	ThrottledColumn  = &Column{"%THROTL", 7, true, proc.SortByThrottled, formatThrottled}
+
+	// AllColumns contains every column that can be displayed.
+	AllColumns = []*Column{
+		PidColumn,
+		UserColumn,
+		RSSColumn,
+		MemPercentColumn,
+		CPUPercentColumn,
+		CPUTimeColumn,
+		StartColumn,
+		StateColumn,
+		NiceColumn,
+		CommandColumn,
+		DiskReadColumn,
+		DiskWriteColumn,
+		ContainerColumn,
+		NetRxColumn,
+		NetTxColumn,
+		GPUPercentColumn,
+		GPUMemoryColumn,
+		WakeupsColumn,
+		SwapColumn,
+		MajfltColumn,
+		MinfltColumn,
+		SecurityColumn,
+		PidNSColumn,
+		NetNSColumn,
+		MntNSColumn,
+		UnitColumn,
+		ThreadsColumn,
+		VCSWColumn,
+		NVCSWColumn,
+		CPUWaitColumn,
+		IOWaitColumn,
+		NumaColumn,
+		OOMColumn,
+		IOPriorityColumn,
+		FDColumn,
+		CgroupMemColumn,
+		ThrottledColumn,
+	}
+
+	// Columns contains the columns currently displayed, in order.
+	Columns []*Column
+)
+
+// ColumnByTitle returns the Column with the passed in title, ignoring case,
+// or nil if there is no such column.
+func ColumnByTitle(title string) *Column {
+	for _, column := range AllColumns {
+		if strings.EqualFold(column.Title, title) {
+			return column
+		}
+	}
+	return nil
+}
+
+// ParseColumns parses a comma-separated list of column titles.
+func ParseColumns(s string) ([]*Column, error) {
+	var columns []*Column
+	for _, title := range strings.Split(s, ",") {
+		column := ColumnByTitle(title)
+		if column == nil {
+			return nil, fmt.Errorf("%s is not a valid column", title)
+		}
+		for _, c := range columns {
+			if c == column {
+				return nil, fmt.Errorf("column %s specified more than once", title)
+			}
+		}
+		columns = append(columns, column)
+	}
+	return CommandLast(columns), nil
+}
+
+// CommandLast moves the COMMAND column to the end since it takes up the
+// remaining width of the screen.
+func CommandLast(columns []*Column) []*Column {
+	for i, column := range columns {
+		if column == CommandColumn && i != len(columns)-1 {
+			columns = append(columns[:i], columns[i+1:]...)
+			return append(columns, CommandColumn)
+		}
+	}
+	return columns
+}
+
+// ColumnVisible returns whether or not a column is currently displayed.
+func ColumnVisible(column *Column) bool {
+	for _, c := range Columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func formatPid(m *proc.Monitor, p *proc.Process) string {
+	return formatThousands(p.Pid)
+}
+
+func formatUser(m *proc.Monitor, p *proc.Process) string {
+	return runewidth.Truncate(p.User.Username, userColumnWidth, "+")
+}
+
+func formatRSS(m *proc.Monitor, p *proc.Process) string {
+	rssB := p.RSS * m.PageSize
+	if SizeUnits != ShortUnits && rssB != 0 {
+		return formatBytes(rssB)
+	}
+	if rssB < proc.MB {
+		if rssB == 0 {
+			// As far as I've seen only kernel threads have 0 RSS.
+			return "0"
+		}
+		return fmt.Sprintf("%dK", rssB/proc.KB)
+	}
+	return fmt.Sprintf("%dM", rssB/proc.MB)
+}
+
+func formatMemPercent(m *proc.Monitor, p *proc.Process) string {
+	rssB := p.RSS * m.PageSize
+	memUsage := 100 * float64(rssB) / float64(m.MemTotal)
+	return fmt.Sprintf("%.1f", memUsage)
+}
+
+func formatCPUPercent(m *proc.Monitor, p *proc.Process) string {
+	return fmt.Sprintf("%.1f", m.CPUPercent(p))
+}
+
+func formatCPUTime(m *proc.Monitor, p *proc.Process) string {
+	return formatClockTicks(p.Utime+p.Stime, m.ClockTicks)
+}
+
+// formatClockTicks formats a CPU time in clock ticks like the TIME+ column.
+func formatClockTicks(totalJiffies, hertz uint64) string {
+	totalSeconds := totalJiffies / hertz
+
+	minutes := totalSeconds / 60
+	seconds := totalSeconds % 60
+	hundredths := (totalJiffies % hertz) * 100 / hertz
+
+	// Like top, drop the precision that doesn't fit in the column.
+	hours := minutes / 60
+	switch {
+	case minutes < 1000:
+		return fmt.Sprintf("%d:%02d.%02d", minutes, seconds, hundredths)
+	case hours < 1000:
+		return fmt.Sprintf("%dh%02dm", hours, minutes%60)
+	default:
+		return fmt.Sprintf("%dd%02dh", hours/24, hours%24)
+	}
+}
+
+// formatStart returns the time today the process was started, or the day
+// or year if it was longer ago, like ps.
+func formatStart(m *proc.Monitor, p *proc.Process) string {
+	started := m.StartTime(p)
+	now := m.LastUpdate
+	switch {
+	case now.Sub(started) < 24*time.Hour && started.Day() == now.Day():
+		return started.Format("15:04")
+	case started.Year() == now.Year():
+		return started.Format("Jan02")
+	default:
+		return started.Format("2006")
+	}
+}
+
+func formatState(m *proc.Monitor, p *proc.Process) string {
+	return string(p.State)
+}
+
+func formatNice(m *proc.Monitor, p *proc.Process) string {
+	return strconv.Itoa(p.Nice)
+}
+
+func formatSecurityContext(m *proc.Monitor, p *proc.Process) string {
+	if p.SecurityContext == "" {
+		return "-"
+	}
+	return runewidth.Truncate(p.SecurityContext, securityColumnWidth, "+")
+}
+
+func formatUnit(m *proc.Monitor, p *proc.Process) string {
+	if p.Unit == "" {
+		return "-"
+	}
+	return runewidth.Truncate(p.Unit, unitColumnWidth, "+")
+}
+
+// formatNamespace returns the formatter of the column of a namespace, by its
+// index in proc.Namespaces.
+func formatNamespace(i int) func(m *proc.Monitor, p *proc.Process) string {
+	return func(m *proc.Monitor, p *proc.Process) string {
+		if p.Namespaces[i] == 0 {
+			return "-"
+		}
+		return strconv.FormatUint(p.Namespaces[i], 10)
+	}
+}
+
+func formatCommand(m *proc.Monitor, p *proc.Process) string {
+	if p.Members != nil {
+		return fmt.Sprintf("%s (%d)", p.Name, len(p.Members))
+	}
+	if p.IsKernelThread() {
+		// The command of a kernel thread is its name in brackets.
+		return p.Command
+	}
+	return p.Name
+}
+
+func formatDiskRead(m *proc.Monitor, p *proc.Process) string {
+	return formatBytes(uint64(m.Rate(p.ReadBytesDiff)))
+}
+
+func formatDiskWrite(m *proc.Monitor, p *proc.Process) string {
+	return formatBytes(uint64(m.Rate(p.WriteBytesDiff)))
+}
+
+func formatNetRx(m *proc.Monitor, p *proc.Process) string {
+	return formatBytes(uint64(m.Rate(p.NetRxBytesDiff)))
+}
+
+func formatNetTx(m *proc.Monitor, p *proc.Process) string {
+	return formatBytes(uint64(m.Rate(p.NetTxBytesDiff)))
+}
+
+// formatGPUPercent returns the time the GPU engines spent on the process as a
+// percentage of the interval, which can exceed 100% when several engines are
+// in use. On NVIDIA GPUs, it's the SM utilization nvidia-smi pmon reports.
+func formatGPUPercent(m *proc.Monitor, p *proc.Process) string {
+	return fmt.Sprintf("%.1f", 100*m.Rate(p.GPUTimeDiff)/1e9)
+}
+
+func formatGPUMemory(m *proc.Monitor, p *proc.Process) string {
+	return formatBytes(p.GPUMemory)
+}
+
+// formatWakeups estimates the wakeups per second of the process as its
+// voluntary context switches.
+func formatWakeups(m *proc.Monitor, p *proc.Process) string {
+	return fmt.Sprintf("%.1f", m.Rate(p.VoluntaryCtxtSwitchesDiff))
+}
+
+// formatNonvoluntaryCtxtSwitches returns the number of times per second the
+// process was preempted.
+func formatNonvoluntaryCtxtSwitches(m *proc.Monitor, p *proc.Process) string {
+	return fmt.Sprintf("%.1f", m.Rate(p.NonvoluntaryCtxtSwitchesDiff))
+}
+
+// formatCPUWait returns the percentage of time the process waited for a CPU
+// while runnable, which is above 100 when several of its threads did.
+func formatCPUWait(m *proc.Monitor, p *proc.Process) string {
+	return fmt.Sprintf("%.1f", m.DelayPercent(p.CPUDelayDiff))
+}
+
+func formatIOWait(m *proc.Monitor, p *proc.Process) string {
+	return fmt.Sprintf("%.1f", m.DelayPercent(p.BlkioDelayDiff))
+}
+
+// formatNuma returns the NUMA node holding the most memory of a process and
+// the percentage of its memory there, like N1 75%.
+func formatNuma(m *proc.Monitor, p *proc.Process) string {
+	node, percent := p.NumaNode()
+	if node < 0 {
+		return ""
+	}
+	return fmt.Sprintf("N%d %.0f%%", node, percent)
+}
+
+func formatOOMScore(m *proc.Monitor, p *proc.Process) string {
+	return strconv.FormatUint(p.OOMScore, 10)
+}
+
+func formatIOPriority(m *proc.Monitor, p *proc.Process) string {
+	return p.IOPriority.String()
+}
+
+// formatFDs returns the number of open file descriptors of a process, which
+// is empty when they can't be listed.
+func formatFDs(m *proc.Monitor, p *proc.Process) string {
+	if p.FDs < 0 {
+		return ""
+	}
+	return strconv.Itoa(p.FDs)
+}
+
+// formatCgroupMemory returns the percentage of its memory limit the cgroup of
+// a process uses, which is empty when it has no limit.
+func formatCgroupMemory(m *proc.Monitor, p *proc.Process) string {
+	if p.CgroupMemoryMax == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", p.CgroupMemoryPercent())
+}
+
+// formatThrottled returns the percentage of time the cgroup of a process was
+// throttled by its CPU limit since the last update.
+func formatThrottled(m *proc.Monitor, p *proc.Process) string {
+	return fmt.Sprintf("%.1f", m.ThrottledPercent(p.CgroupThrottledDiff))
+}
+
+func formatThreads(m *proc.Monitor, p *proc.Process) string {
+	return strconv.FormatUint(p.Threads, 10)
+}
+
+func formatSwap(m *proc.Monitor, p *proc.Process) string {
+	return formatBytes(p.Swap)
+}
+
+func formatMajflt(m *proc.Monitor, p *proc.Process) string {
+	return formatCount(m.Rate(p.MajfltDiff))
+}
+
+func formatMinflt(m *proc.Monitor, p *proc.Process) string {
+	return formatCount(m.Rate(p.MinfltDiff))
+}
+
+func formatContainer(m *proc.Monitor, p *proc.Process) string {
+	name := p.ContainerName()
+	if name == "" {
+		return "-"
+	}
+	return runewidth.Truncate(name, proc.ShortContainerIDLen, "+")
+}
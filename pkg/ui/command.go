@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// CommandMode is what the COMMAND column displays of a process. Groups of
+// processes and kernel threads are displayed the same in every mode.
+type CommandMode int
+
+const (
+	// CommandName displays the name of the program in the command line.
+	CommandName CommandMode = iota
+	// CommandComm displays the name of the process in the kernel, which
+	// programs can change.
+	CommandComm
+	// CommandExe displays the base name of the executable, or the name if
+	// it can't be read.
+	CommandExe
+	// CommandLine displays the full command line.
+	CommandLine
+)
+
+var commandModeNames = []string{"names", "kernel names", "executables", "full command lines"}
+
+func (c CommandMode) String() string {
+	return commandModeNames[c]
+}
+
+// value returns the value of the COMMAND column of a process in the mode.
+func (c CommandMode) value(m *proc.Monitor, p *proc.Process) string {
+	if p.Restricted {
+		return "-"
+	}
+	if p.Members != nil || p.IsKernelThread() {
+		return formatCommand(m, p)
+	}
+	switch c {
+	case CommandComm:
+		if p.Comm != "" {
+			return p.Comm
+		}
+	case CommandExe:
+		if p.Exe != "" {
+			return path.Base(strings.TrimSuffix(p.Exe, " (deleted)"))
+		}
+	case CommandLine:
+		return p.Command
+	}
+	return formatCommand(m, p)
+}
+
+// HandleNextCommandMode switches the COMMAND column to the next CommandMode.
+func (ui *UI) HandleNextCommandMode() {
+	ui.Command = (ui.Command + 1) % CommandMode(len(commandModeNames))
+	ui.SetStatus(fmt.Sprintf(tr("Displaying the %s"), ui.Command))
+}
+
+// HandleToggleHighlightProgram highlights the program names of full command
+// lines, or stops highlighting them.
+func (ui *UI) HandleToggleHighlightProgram() {
+	ui.HighlightProgram = !ui.HighlightProgram
+}
+
+// programSpan returns the start and end indexes of the base name of the
+// program in the command of a process displayed in full, or 0 and 0.
+func (ui *UI) programSpan(p *proc.Process, command string) (int, int) {
+	if ui.Command != CommandLine || p.Members != nil || p.IsKernelThread() {
+		return 0, 0
+	}
+	end := strings.IndexByte(command, ' ')
+	if end == -1 {
+		end = len(command)
+	}
+	return strings.LastIndexByte(command[:end], '/') + 1, end
+}
+
+// writeCommand draws the rest of a command onto the rest of the row, from its
+// byte index start, with the program name in bold and the arguments dimmed if
+// they're highlighted. A command cut off by the edge of the screen ends with
+// a +, like the truncated values of the other columns.
+func (ui *UI) writeCommand(s string, start int) {
+	fg := ui.fg
+	for i, ch := range s {
+		if ui.programEnd > 0 {
+			switch i += start; {
+			case i >= ui.programStart && i < ui.programEnd:
+				ui.fg = fg | termbox.AttrBold
+			case i >= ui.programEnd && theme.ArgumentsFG != termbox.ColorDefault:
+				ui.fg = theme.ArgumentsFG
+			default:
+				ui.fg = fg
+			}
+		}
+		ui.setCell(ch)
+	}
+	ui.fg = fg
+	if ui.x-ui.offset > ui.width {
+		terminal.SetCell(ui.width-1, ui.y, '+', ui.fg, ui.bg)
+	}
+
+	for ui.x < ui.width {
+		ui.setCell(' ')
+	}
+}
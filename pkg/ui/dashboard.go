@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// dashboardScreen displays a summary of every remote machine being
+// monitored, one per row. Pressing Enter displays the processes of the
+// selected machine.
+type dashboardScreen struct {
+	remotes  []*proc.Remote
+	choose   func(int)
+	selected int
+}
+
+func (s *dashboardScreen) Draw(ui *UI) {
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	ui.writeLastColumn(fmt.Sprintf("%-20s %-16s %14s %6s %6s %6s  %s",
+		"HOST", "STATUS", "LOAD", "%CPU", "%MEM", "PROCS", "TOP PROCESS"))
+	ui.y++
+
+	for i, remote := range s.remotes {
+		ui.x = 0
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		if i == s.selected {
+			ui.fg, ui.bg = theme.SelectedFG, theme.SelectedBG
+		}
+		ui.writeLastColumn(hostSummary(remote))
+		ui.y++
+	}
+}
+
+// hostSummary returns the dashboard row of a remote machine.
+func hostSummary(remote *proc.Remote) string {
+	m := remote.Monitor
+
+	var top *proc.Process
+	for _, p := range m.List {
+		if top == nil || p.UtimeDiff+p.StimeDiff > top.UtimeDiff+top.StimeDiff {
+			top = p
+		}
+	}
+	topProcess := "-"
+	if top != nil {
+		topProcess = fmt.Sprintf("%s (%d) %.1f%%", top.Name, top.Pid, m.CPUPercent(top))
+	}
+
+	load := fmt.Sprintf("%.2f %.2f %.2f", m.LoadAvg[0], m.LoadAvg[1], m.LoadAvg[2])
+	return fmt.Sprintf("%s %-16s %14s %6.1f %6.1f %6d  %s",
+		padRight(runewidth.Truncate(remote.Name, 20, "+"), 20), RemoteStatus(remote), load,
+		m.SystemCPUPercent(), m.MemPercent(), len(m.List), topProcess)
+}
+
+// RemoteStatus returns a description of the state of the connection to a
+// remote machine.
+func RemoteStatus(remote *proc.Remote) string {
+	received, err := remote.State()
+	switch {
+	case err != nil:
+		return err.Error()
+	case received.IsZero():
+		return tr("connecting")
+	default:
+		return fmt.Sprintf(tr("updated %s"), received.Format("15:04:05"))
+	}
+}
+
+func (s *dashboardScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	switch {
+	case ev.Ch == 'q' || ev.Key == termbox.KeyEsc || ev.Ch == 'M':
+		return false
+	case ev.Ch == 'j' || ev.Key == termbox.KeyArrowDown:
+		if s.selected < len(s.remotes)-1 {
+			s.selected++
+		}
+	case ev.Ch == 'k' || ev.Key == termbox.KeyArrowUp:
+		if s.selected > 0 {
+			s.selected--
+		}
+	case ev.Key == termbox.KeyEnter:
+		s.choose(s.selected)
+		return false
+	}
+	return true
+}
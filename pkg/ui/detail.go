@@ -0,0 +1,228 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// SecretPattern matches the names of the environment variables whose values
+// are masked in the details of processes, unless they're revealed.
+var SecretPattern = regexp.MustCompile(`(?i)TOKEN|SECRET|PASSW(OR)?D|PASSPHRASE|API_?KEY|PRIVATE_?KEY|CREDENTIAL|AUTH`)
+
+const maskedSecret = "********"
+
+// detailScreen displays details about the process with the passed in Pid,
+// refreshed every time it's drawn.
+type detailScreen struct {
+	textScreen
+	monitor *proc.Monitor
+	pid     uint64
+	reveal  bool
+}
+
+func newDetailScreen(m *proc.Monitor, pid uint64) Screen {
+	s := &detailScreen{monitor: m, pid: pid}
+	s.setTitle()
+	s.lines = func() []string { return processDetails(m, pid, s.reveal) }
+	return s
+}
+
+// setTitle sets the title of the screen, which names what r does.
+func (s *detailScreen) setTitle() {
+	if s.reveal {
+		s.title = fmt.Sprintf(tr("Process %d - a: affinity, r: hide secrets, q: back"), s.pid)
+	} else {
+		s.title = fmt.Sprintf(tr("Process %d - a: affinity, r: reveal secrets, q: back"), s.pid)
+	}
+}
+
+func (s *detailScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	if ev.Ch == 'r' {
+		s.reveal = !s.reveal
+		s.setTitle()
+		return true
+	}
+	if ev.Ch == 'a' {
+		if p, ok := s.monitor.Map[s.pid]; ok && ui.canControl() {
+			ui.screen = newAffinityScreen(s.monitor, []*proc.Process{p}, s)
+		}
+		return true
+	}
+	return s.textScreen.HandleKey(ui, ev)
+}
+
+// processDetails returns a human readable description of a process, read
+// from various files in /proc/<pid>. The values of the environment variables
+// matching SecretPattern are masked unless reveal is set.
+func processDetails(m *proc.Monitor, pid uint64, reveal bool) []string {
+	p, ok := m.Map[pid]
+	if !ok {
+		return []string{tr("Process no longer exists")}
+	}
+
+	dir := fmt.Sprintf("/proc/%d", pid)
+	started := m.StartTime(p)
+
+	lines := []string{
+		"Command:  " + p.Command,
+		"User:     " + p.User.Username,
+		"State:    " + formatDetailState(p),
+		"Parent:   " + fmt.Sprint(p.Ppid),
+		"Started:  " + started.Format("2006-01-02 15:04:05") +
+			" (" + time.Since(started).Truncate(time.Second).String() + " ago)",
+		"Exe:      " + readLink(dir+"/exe"),
+		"Cwd:      " + readLink(dir+"/cwd"),
+		"Open fds: " + countDir(dir+"/fd") + formatFDLimit(p),
+		"Unit:     " + formatUnit(m, p),
+		"Security: " + securityContext(p),
+		"Caps:     " + readCapabilities(dir+"/status"),
+		"Affinity: " + formatAffinity(m, p),
+		"OOM:      " + formatOOM(p),
+		"I/O prio: " + p.IOPriority.String(),
+		"",
+		"CPU:      " + sparkline(p.CPUHistory.Values(), p.CPUHistory.Max()) +
+			fmt.Sprintf(" %.1f%% (max %.1f%%)", m.CPUPercent(p), p.CPUHistory.Max()),
+		"RSS:      " + sparkline(p.RSSHistory.Values(), p.RSSHistory.Max()) +
+			" " + formatRSS(m, p) + " (max " + formatBytes(uint64(p.RSSHistory.Max())) + ")",
+		"Ticks:    " + formatThousands(p.Utime) + " user, " + formatThousands(p.Stime) + " system",
+		"Faults:   " + formatThousands(p.Minflt) + " minor, " + formatThousands(p.Majflt) + " major",
+		"Switches: " + formatThousands(p.VoluntaryCtxtSwitches) + " voluntary, " +
+			formatThousands(p.NonvoluntaryCtxtSwitches) + " involuntary",
+		"",
+		"Cgroups:",
+	}
+	lines = append(lines, indent(readLines(dir+"/cgroup"))...)
+	lines = append(lines, "", "Limits:")
+	lines = append(lines, indent(readLines(dir+"/limits"))...)
+	lines = append(lines, "", "Environment:")
+	lines = append(lines, indent(readEnviron(dir+"/environ", reveal))...)
+	return lines
+}
+
+// The following helpers describe an unreadable file (usually because the
+// process belongs to another user) instead of returning an error, since the
+// details are only ever displayed.
+
+func readLink(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return unavailable(err)
+	}
+	return target
+}
+
+func countDir(path string) string {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return unavailable(err)
+	}
+	return fmt.Sprint(len(entries))
+}
+
+// formatFDLimit returns the soft limit of open files of a process, which is
+// only known when the FD column is displayed.
+func formatFDLimit(p *proc.Process) string {
+	if p.FDLimit == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (soft limit %d)", p.FDLimit)
+}
+
+func readLines(path string) []string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []string{unavailable(err)}
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func readEnviron(path string, reveal bool) []string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []string{unavailable(err)}
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+	if !reveal {
+		for i, line := range lines {
+			if name := strings.SplitN(line, "=", 2); len(name) == 2 && name[1] != "" && SecretPattern.MatchString(name[0]) {
+				lines[i] = name[0] + "=" + maskedSecret
+			}
+		}
+	}
+	return lines
+}
+
+func securityContext(p *proc.Process) string {
+	if p.SecurityContext == "" {
+		return "(no security module)"
+	}
+	return p.SecurityContext
+}
+
+// formatDetailState returns the state of a process, and whether its cgroup
+// is frozen.
+func formatDetailState(p *proc.Process) string {
+	if p.Frozen {
+		return string(p.State) + " (cgroup " + p.Cgroup + " frozen)"
+	}
+	return string(p.State)
+}
+
+// formatAffinity returns the CPUs a process is allowed to run on.
+func formatAffinity(m *proc.Monitor, p *proc.Process) string {
+	cpus, err := p.Affinity()
+	if err != nil {
+		return unavailable(err)
+	}
+	return fmt.Sprintf("%s (%d of %d CPUs)", proc.FormatCPUList(cpus), len(cpus), m.NumCPUs)
+}
+
+// formatOOM returns the OOM score of a process and its adjustment.
+func formatOOM(p *proc.Process) string {
+	adj, err := p.OOMScoreAdj()
+	if err != nil {
+		return unavailable(err)
+	}
+	return fmt.Sprintf("score %d, adjustment %d", p.OOMScore, adj)
+}
+
+// readCapabilities returns the effective capabilities of a status file.
+func readCapabilities(path string) string {
+	for _, line := range readLines(path) {
+		// line = "CapEff:\t000001ffffffffff"
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return "(unavailable)"
+		}
+		if mask == 0 {
+			return "none"
+		}
+		return strings.Join(proc.CapabilityNames(mask), ", ")
+	}
+	return "(unavailable)"
+}
+
+func unavailable(err error) string {
+	if os.IsPermission(err) {
+		return "(permission denied)"
+	}
+	return "(unavailable)"
+}
+
+func indent(lines []string) []string {
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return lines
+}
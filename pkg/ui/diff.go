@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// HandleMarkBaseline marks the current state of the processes as the
+// baseline HandleBaselineDiff compares with.
+func (ui *UI) HandleMarkBaseline() {
+	ui.baseline = ui.monitor.Snapshot()
+	ui.SetStatus(tr("Baseline marked, b shows what changed since"))
+}
+
+// HandleBaselineDiff displays the screen listing what changed for each
+// process since the baseline.
+func (ui *UI) HandleBaselineDiff() {
+	if ui.baseline == nil {
+		ui.SetStatus(tr("No baseline, m marks one"))
+		return
+	}
+	baseline := ui.baseline
+	ui.screen = &textScreen{
+		title: fmt.Sprintf(tr("Changes since %s - q: back"), baseline.Time.Format("15:04:05")),
+		lines: func() []string { return diffLines(ui.monitor, baseline) },
+	}
+}
+
+// diffLines returns the lines of the baseline diff screen: the processes that
+// started, exited, consumed CPU time or whose RSS changed since the
+// baseline, marked with + and - for the first two.
+func diffLines(m *proc.Monitor, baseline *proc.Snapshot) []string {
+	s := m.Snapshot()
+	diffs := s.Diff(baseline)
+
+	started, exited := 0, 0
+	var rows []string
+	for _, d := range diffs {
+		marker := " "
+		switch {
+		case d.New:
+			marker = "+"
+			started++
+		case d.Exited:
+			marker = "-"
+			exited++
+		case d.CPUTime == 0 && d.RSSDiff == 0:
+			continue
+		}
+		rows = append(rows, fmt.Sprintf("%s %9s %7s %7d  %s",
+			marker, formatClockTicks(d.CPUTime, s.ClockTicks), formatRSSDiff(d.RSSDiff*int64(s.PageSize)),
+			d.Process.Pid, d.Process.Command))
+	}
+
+	elapsed := s.Time.Sub(baseline.Time).Truncate(time.Second)
+	lines := []string{
+		fmt.Sprintf("%s elapsed, %d processes started, %d exited", elapsed, started, exited),
+		"",
+		fmt.Sprintf("  %9s %7s %7s  %s", "TIME+", "RSS+", "PID", "COMMAND"),
+	}
+	return append(lines, rows...)
+}
+
+// formatRSSDiff formats a change of RSS in bytes with its sign.
+func formatRSSDiff(diff int64) string {
+	switch {
+	case diff > 0:
+		return "+" + formatBytes(uint64(diff))
+	case diff < 0:
+		return "-" + formatBytes(uint64(-diff))
+	}
+	return "0"
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// Formats contains the formats the process table can be exported in, keyed
+// by name, with the field separator of each.
+var Formats = map[string]rune{
+	"csv": ',',
+	"tsv": '\t',
+}
+
+// WriteTable writes the process list of a Monitor in the displayed order, as
+// a header row and a row for every Process with the value of every column
+// in Columns. The full command line is written when verbose is set.
+func WriteTable(w io.Writer, m *proc.Monitor, format string, verbose bool) error {
+	comma, ok := Formats[format]
+	if !ok {
+		return fmt.Errorf("%s is not a valid format", format)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	record := make([]string, len(Columns))
+	for i, column := range Columns {
+		record[i] = column.Title
+	}
+	cw.Write(record)
+
+	for _, p := range processList(m) {
+		for i, column := range Columns {
+			record[i] = column.Value(m, p)
+			if column == CommandColumn && verbose {
+				record[i] = p.Command
+			}
+		}
+		cw.Write(record)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// HandleExport writes the process table to a file named after the current
+// time in the working directory.
+func (ui *UI) HandleExport(format string) {
+	path := "jtop-" + time.Now().Format("20060102-150405") + "." + format
+	if err := ui.export(path, format); err != nil {
+		ui.SetStatus(fmt.Sprintf(tr("Export failed: %s"), err))
+		return
+	}
+	ui.SetStatus(fmt.Sprintf(tr("Exported %d processes to %s"), len(processList(ui.monitor)), path))
+}
+
+func (ui *UI) export(path, format string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteTable(file, ui.monitor, format, ui.Command == CommandLine); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// newFilesScreen returns a Screen listing the open files of the process
+// with the passed in Pid, refreshed every time it's drawn.
+func newFilesScreen(pid uint64) Screen {
+	return &textScreen{
+		title: fmt.Sprintf(tr("Open files of process %d - q: back"), pid),
+		lines: func() []string { return openFiles(pid) },
+	}
+}
+
+// openFiles lists the file descriptors in /proc/<pid>/fd, resolving sockets
+// to their addresses.
+func openFiles(pid uint64) []string {
+	dir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{tr("Process no longer exists")}
+		}
+		return []string{unavailable(err)}
+	}
+
+	var fds []int
+	for _, entry := range entries {
+		if fd, err := strconv.Atoi(entry.Name()); err == nil {
+			fds = append(fds, fd)
+		}
+	}
+	sort.Ints(fds)
+
+	sockets := proc.ReadSockets()
+	lines := []string{padLeft("FD", 5) + "  NAME"}
+	for _, fd := range fds {
+		target, err := os.Readlink(fmt.Sprintf("%s/%d", dir, fd))
+		if err != nil {
+			continue // closed since ReadDir
+		}
+		if inode, ok := proc.SocketInode(target); ok {
+			if s, ok := sockets[inode]; ok {
+				target = s.String()
+			}
+		}
+		lines = append(lines, padLeft(strconv.Itoa(fd), 5)+"  "+target)
+	}
+	return lines
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+var graphBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// graphScreen displays graphs of the SystemHistory of the displayed Monitor.
+type graphScreen struct {
+	history *proc.SystemHistory
+}
+
+func (s *graphScreen) Draw(ui *UI) {
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	seconds := int(s.history.Span() / time.Second)
+	ui.writeLastColumn(fmt.Sprintf(tr("History (last %d seconds) - tab: back"), seconds))
+	ui.y++
+
+	cpu, mem, swap := s.history.Values()
+	graphs := []struct {
+		title  string
+		values []float64
+		color  termbox.Attribute
+	}{
+		{tr("CPU"), cpu, theme.CPUGraphFG},
+		{tr("Memory"), mem, theme.MemGraphFG},
+		{tr("Swap"), swap, theme.SwapGraphFG},
+	}
+
+	height := (ui.height - headerRows) / len(graphs)
+	for _, graph := range graphs {
+		values := graph.values
+		current := 0.0
+		if len(values) > 0 {
+			current = values[len(values)-1]
+		}
+
+		ui.x = 0
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		ui.writeLastColumn(fmt.Sprintf("%s %.1f%%", graph.title, current))
+		ui.y++
+
+		ui.fg = graph.color
+		ui.drawGraph(values, 100, height-1)
+	}
+}
+
+func (s *graphScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	return !(ev.Ch == 'q' || ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyTab)
+}
+
+// drawGraph draws values as vertical bars, rows high, with the most recent
+// value in the rightmost column.
+func (ui *UI) drawGraph(values []float64, max float64, rows int) {
+	if rows < 1 {
+		return
+	}
+	if len(values) > ui.width {
+		values = values[len(values)-ui.width:]
+	}
+	start := ui.width - len(values)
+	levels := len(graphBlocks) - 1
+
+	for row := 0; row < rows; row++ {
+		// Rows are drawn top to bottom, so row 0 is the highest.
+		base := (rows - 1 - row) * levels
+		for i, v := range values {
+			filled := int(v / max * float64(rows*levels))
+			block := filled - base
+			if block < 0 {
+				block = 0
+			} else if block > levels {
+				block = levels
+			}
+			terminal.SetCell(start+i, ui.y, graphBlocks[block], ui.fg, ui.bg)
+		}
+		ui.y++
+	}
+}
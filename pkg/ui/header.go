@@ -0,0 +1,360 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// HeaderSection is a toggleable section of the header displayed above the
+// process table.
+type HeaderSection struct {
+	Name    string
+	Enabled bool
+
+	// Lines returns the lines displayed in this section.
+	Lines func(m *proc.Monitor) []string
+	// Color returns the foreground color of the i-th line, if it isn't
+	// nil.
+	Color func(m *proc.Monitor, i int) termbox.Attribute
+}
+
+// headerLine is a line of the header and its foreground color.
+type headerLine struct {
+	text string
+	fg   termbox.Attribute
+}
+
+// The temperatures in degrees Celsius from which the sensors header section
+// is displayed in the warning and critical colors of the theme.
+var (
+	TempWarning  = 70.0
+	TempCritical = 90.0
+)
+
+const (
+	batteryMeterWidth = 20
+	// The charge below which a discharging battery is displayed in the
+	// warning and critical colors.
+	batteryWarning  = 20
+	batteryCritical = 10
+
+	// The percentage of time stalled from which a resource is displayed
+	// in the warning and critical colors.
+	pressureWarning  = 10
+	pressureCritical = 40
+)
+
+// MeminfoFields are the fields of /proc/meminfo the memory header section
+// displays after the used and total memory.
+var MeminfoFields = []string{"MemAvailable", "AnonPages", "Cached", "Shmem", "HugePages_Total", "HugePages_Free"}
+
+// meminfoLabels are the labels of the usual MeminfoFields, the others are
+// labeled with their names.
+var meminfoLabels = map[string]string{
+	"MemFree":         "free",
+	"MemAvailable":    "avail",
+	"Buffers":         "buffers",
+	"Cached":          "cache",
+	"SwapCached":      "swapcache",
+	"AnonPages":       "anon",
+	"Mapped":          "mapped",
+	"Shmem":           "shmem",
+	"Dirty":           "dirty",
+	"Writeback":       "writeback",
+	"Slab":            "slab",
+	"SReclaimable":    "reclaimable",
+	"SUnreclaim":      "unreclaim",
+	"PageTables":      "pagetables",
+	"AnonHugePages":   "anonhuge",
+	"HugePages_Total": "huge",
+	"HugePages_Free":  "huge free",
+	"HugePages_Rsvd":  "huge rsvd",
+	"HugePages_Surp":  "huge surp",
+}
+
+var (
+	MemorySection   = &HeaderSection{"Memory", true, memoryLines, nil}
+	NetSection      = &HeaderSection{"Network", false, netLines, nil}
+	DiskSection     = &HeaderSection{"Disk", false, diskLines, nil}
+	GPUSection      = &HeaderSection{"GPU", false, gpuLines, nil}
+	SensorsSection  = &HeaderSection{"Sensors", false, sensorLines, sensorColor}
+	BatterySection  = &HeaderSection{"Battery", true, batteryLines, batteryColor}
+	PressureSection = &HeaderSection{"Pressure", true, pressureLines, pressureColor}
+	NumaSection     = &HeaderSection{"NUMA", true, numaLines, numaColor}
+
+	// HeaderSections contains every section of the header, in the order
+	// they're displayed.
+	HeaderSections = []*HeaderSection{
+		MemorySection,
+		NetSection,
+		DiskSection,
+		GPUSection,
+		SensorsSection,
+		BatterySection,
+		PressureSection,
+		NumaSection,
+	}
+)
+
+// headerLines returns the lines of every enabled header section.
+func headerLines(m *proc.Monitor) []headerLine {
+	var lines []headerLine
+	for _, section := range HeaderSections {
+		if !section.Enabled {
+			continue
+		}
+		for i, text := range section.Lines(m) {
+			line := headerLine{text, termbox.ColorDefault}
+			if section.Color != nil {
+				line.fg = section.Color(m, i)
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// memoryLines returns the line of the memory header section.
+func memoryLines(m *proc.Monitor) []string {
+	if m.Meminfo == nil {
+		return nil
+	}
+	line := padRight("mem", 12) +
+		tr("used") + " " + padLeft(formatBytes(m.MemTotal-m.MemAvailable), sizeWidth) +
+		" / " + padLeft(formatBytes(m.MemTotal), sizeWidth)
+	for _, field := range MeminfoFields {
+		label, ok := meminfoLabels[field]
+		if !ok {
+			label = field
+		}
+		line += "  " + tr(label) + " " + meminfoValue(m, field)
+	}
+	return []string{line}
+}
+
+// meminfoValue returns a field of /proc/meminfo, where the counts of huge
+// pages are converted to bytes, or "-" if the kernel doesn't have it.
+func meminfoValue(m *proc.Monitor, field string) string {
+	value, ok := m.Meminfo[field]
+	if !ok {
+		return "-"
+	}
+	if strings.HasPrefix(field, "HugePages_") {
+		value *= m.Meminfo["Hugepagesize"]
+	}
+	return formatBytes(value)
+}
+
+// netLines returns the lines of the network header section.
+func netLines(m *proc.Monitor) []string {
+	var lines []string
+	var rxTotal, txTotal uint64
+	for _, iface := range m.Interfaces {
+		lines = append(lines, netLine(m, iface.Name, iface.RxBytesDiff, iface.TxBytesDiff))
+		if iface.Name != "lo" {
+			// Loopback traffic never leaves the machine.
+			rxTotal += iface.RxBytesDiff
+			txTotal += iface.TxBytesDiff
+		}
+	}
+	return append(lines, netLine(m, "total", rxTotal, txTotal))
+}
+
+func netLine(m *proc.Monitor, name string, rxDiff, txDiff uint64) string {
+	return padRight(name, 12) +
+		"RX " + padLeft(formatBytes(uint64(m.Rate(rxDiff))), sizeWidth) + "/s  " +
+		"TX " + padLeft(formatBytes(uint64(m.Rate(txDiff))), sizeWidth) + "/s"
+}
+
+// diskLines returns the lines of the disk header section.
+func diskLines(m *proc.Monitor) []string {
+	var lines []string
+	for _, disk := range m.Disks {
+		read := uint64(m.Rate(disk.SectorsReadDiff * proc.SectorSize))
+		written := uint64(m.Rate(disk.SectorsWrittenDiff * proc.SectorSize))
+
+		// TimeDoingIos is the number of milliseconds the device had
+		// I/O requests queued.
+		util := 0.0
+		if m.Interval > 0 {
+			util = 100 * float64(disk.TimeDoingIosDiff) / (m.Interval.Seconds() * 1000)
+			if util > 100 {
+				util = 100
+			}
+		}
+
+		lines = append(lines, padRight(disk.Name, 12)+
+			"R "+padLeft(formatBytes(read), sizeWidth)+"/s  "+
+			"W "+padLeft(formatBytes(written), sizeWidth)+"/s  "+
+			fmt.Sprintf("%5.1f%% util", util))
+	}
+	return lines
+}
+
+// gpuLines returns the lines of the GPU header section.
+func gpuLines(m *proc.Monitor) []string {
+	var lines []string
+	for _, gpu := range m.GPUs {
+		busy := "    -"
+		if gpu.Busy >= 0 {
+			busy = fmt.Sprintf("%5.1f%%", gpu.Busy)
+		}
+		line := padRight(runewidth.Truncate(gpu.Name, 23, "+"), 24) + busy + " busy"
+		if gpu.MemTotal > 0 {
+			line += "  MEM " + padLeft(formatBytes(gpu.MemUsed), sizeWidth) + " / " + formatBytes(gpu.MemTotal)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// sensorLines returns the lines of the sensors header section: the
+// temperatures of every chip, then the fan speeds.
+func sensorLines(m *proc.Monitor) []string {
+	var lines []string
+	for _, temps := range tempsByChip(m) {
+		line := padRight(temps[0].Chip, 12)
+		for i, s := range temps {
+			if i > 0 {
+				line += "  "
+			}
+			line += fmt.Sprintf("%s %.0f°C", s.Label, s.Value)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(m.Fans) > 0 {
+		line := padRight("fans", 12)
+		for i, s := range m.Fans {
+			if i > 0 {
+				line += "  "
+			}
+			line += fmt.Sprintf("%s %.0f RPM", s.Label, s.Value)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// sensorColor returns the color of a line of the sensors header section,
+// which depends on the hottest sensor of its chip.
+func sensorColor(m *proc.Monitor, i int) termbox.Attribute {
+	chips := tempsByChip(m)
+	if i >= len(chips) {
+		return termbox.ColorDefault // fans
+	}
+	hottest := 0.0
+	for _, s := range chips[i] {
+		if s.Value > hottest {
+			hottest = s.Value
+		}
+	}
+	switch {
+	case hottest >= TempCritical:
+		return theme.CriticalFG
+	case hottest >= TempWarning:
+		return theme.WarningFG
+	}
+	return termbox.ColorDefault
+}
+
+// tempsByChip groups the temperatures of the consecutive sensors of every
+// chip.
+func tempsByChip(m *proc.Monitor) [][]*proc.Sensor {
+	var chips [][]*proc.Sensor
+	for i, s := range m.Temps {
+		if i == 0 || s.Chip != m.Temps[i-1].Chip {
+			chips = append(chips, nil)
+		}
+		chips[len(chips)-1] = append(chips[len(chips)-1], s)
+	}
+	return chips
+}
+
+// batteryLines returns the lines of the battery header section, which is
+// empty on machines without a battery.
+func batteryLines(m *proc.Monitor) []string {
+	var lines []string
+	for _, b := range m.Batteries {
+		full := int(b.Capacity * batteryMeterWidth / 100)
+		if full > batteryMeterWidth {
+			full = batteryMeterWidth
+		}
+		line := padRight(b.Name, 12) +
+			"[" + strings.Repeat("|", full) + strings.Repeat(" ", batteryMeterWidth-full) + "] " +
+			fmt.Sprintf("%3.0f%%  %s", b.Capacity, strings.ToLower(b.Status))
+		if b.Power >= 0 {
+			line += fmt.Sprintf("  %.1f W", b.Power)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func batteryColor(m *proc.Monitor, i int) termbox.Attribute {
+	b := m.Batteries[i]
+	if b.Status != "Discharging" {
+		return termbox.ColorDefault
+	}
+	switch {
+	case b.Capacity <= batteryCritical:
+		return theme.CriticalFG
+	case b.Capacity <= batteryWarning:
+		return theme.WarningFG
+	}
+	return termbox.ColorDefault
+}
+
+// pressureLines returns the lines of the pressure header section, which is
+// empty if the kernel doesn't support PSI.
+func pressureLines(m *proc.Monitor) []string {
+	var lines []string
+	for _, p := range m.Pressure {
+		line := padRight("psi "+p.Resource, 12) + fmt.Sprintf("some %5.1f%%", p.Some)
+		if p.HasFull {
+			line += fmt.Sprintf("  full %5.1f%%", p.Full)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func pressureColor(m *proc.Monitor, i int) termbox.Attribute {
+	p := m.Pressure[i]
+	switch {
+	case p.Some >= pressureCritical:
+		return theme.CriticalFG
+	case p.Some >= pressureWarning:
+		return theme.WarningFG
+	}
+	return termbox.ColorDefault
+}
+
+// numaLines returns the lines of the NUMA header section, which is empty on
+// machines with a single node.
+func numaLines(m *proc.Monitor) []string {
+	if len(m.NumaNodes) < 2 {
+		return nil
+	}
+	var lines []string
+	for _, node := range m.NumaNodes {
+		lines = append(lines, padRight(fmt.Sprintf("node%d", node.ID), 12)+
+			"used "+padLeft(formatBytes(node.MemUsed), sizeWidth)+
+			" / "+padLeft(formatBytes(node.MemTotal), sizeWidth)+
+			fmt.Sprintf("  %5.1f%%  miss %s/s", node.MemPercent(), formatCount(m.Rate(node.MissDiff))))
+	}
+	return lines
+}
+
+// numaColor displays the nodes that had to allocate pages intended for
+// another node since the last update in the warning color.
+func numaColor(m *proc.Monitor, i int) termbox.Attribute {
+	if m.NumaNodes[i].MissDiff > 0 {
+		return theme.WarningFG
+	}
+	return termbox.ColorDefault
+}
@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// KeyHelp describes what a key does, for the help screen and the help bar.
+type KeyHelp struct {
+	Keys        string
+	Description string
+}
+
+// KeysHelp lists the keys of the process table, in the order the help screen
+// displays them.
+var KeysHelp = []KeyHelp{
+	{"? F1", "show this help"},
+	{"q Ctrl-C", "quit"},
+	{"j k ↓ ↑", "select the next or previous process"},
+	{"g G", "select the first or last process"},
+	{"Ctrl-D Ctrl-U", "scroll down or up half a page"},
+	{"h l ← →", "scroll the table to the previous or next column or word"},
+	{"0 ^", "scroll back to the first column"},
+	{"f", "keep the selection on the selected process"},
+	{"{ }", "select the parent of the selected process, or cycle through its children (not P, which toggles the pressure)"},
+	{"enter", "show the details of the selected process, or expand a group"},
+	{"L", "show the open files of the selected process"},
+	{"O", "show the TCP and UDP sockets and the processes they belong to"},
+	{"F", "show the size and usage of the mounted filesystems"},
+	{"s", "trace the selected process, see --tracer"},
+	{"!", "open a shell with $JTOP_PID set to the selected process"},
+	{"p", "profile the selected process with perf, see --profile-time"},
+	{"space Insert Ctrl-T", "tag or untag the selected process"},
+	{"U", "untag every process"},
+	{"x F9", "send a signal to the tagged or selected processes"},
+	{"[ F7", "lower the nice value of the tagged or selected processes"},
+	{"] F8", "raise the nice value of the tagged or selected processes"},
+	{"y", "choose the CPUs the tagged or selected processes run on"},
+	{"X", "adjust the OOM score of the tagged or selected processes"},
+	{"d", "set the I/O priority of the tagged or selected processes"},
+	{"z", "stop the tagged or selected processes, or continue them"},
+	{"Y", "freeze the cgroups of the tagged or selected processes, or thaw them"},
+	{"J", "pin the tagged or selected processes above the table, or unpin them"},
+	{"Ctrl-W", "pin the processes whose command line matches a pattern, see --watch"},
+	{"< >", "sort by the previous or next column"},
+	{"I", "reverse the sort order"},
+	{"t", "display the processes as a tree"},
+	{"a", "group the processes with the same name"},
+	{"A", "group the processes by systemd unit"},
+	{"K", "list the kernel threads after the other processes"},
+	{"T", "show the %CPU since the processes started rather than the last update"},
+	{"W", "include the CPU time of the children that exited in %CPU"},
+	{"v", "show the names, kernel names, executables or full command lines"},
+	{"V", "highlight the program names of full command lines"},
+	{"w", "wrap the commands that don't fit onto several rows"},
+	{"\\ F4", "filter the processes with an expression"},
+	{"|", "filter the processes by command line"},
+	{"r", "hide the idle processes, see --idle-cpu and --idle-io"},
+	{"/", "search the processes, and select the next match"},
+	{"n N", "select the next or previous match of the search"},
+	{"esc", "stop searching"},
+	{"c", "stop or start filtering by command line"},
+	{"C F2", "set up the columns"},
+	{"Ctrl-O", "switch to another profile, see --profile"},
+	{"R", "show or hide the memory breakdown, see --meminfo"},
+	{"D", "show or hide the disk I/O"},
+	{"n", "show or hide the network throughput, unless searching"},
+	{"S", "show or hide the temperatures and fan speeds"},
+	{"B", "show or hide the batteries"},
+	{"P", "show or hide the pressure stall information"},
+	{"E", "show or hide the log of the processes that started and exited"},
+	{"tab", "show the history graphs"},
+	{"o", "show the processes by cgroup"},
+	{"u", "show the usage of each user"},
+	{"i", "show the rates of the interrupts and softirqs on each CPU"},
+	{"e", "export the process table to a file"},
+	{"Z", "freeze or resume the display"},
+	{"m", "mark the current state of the processes as a baseline"},
+	{"b", "show what changed for each process since the baseline"},
+	{"+ -", "double or halve the delay between updates"},
+	{"H M", "switch to the next remote machine, or to the dashboard"},
+	{"space , .", "pause, step back or step forward when replaying"},
+	{"Ctrl-Z", "suspend jtop"},
+}
+
+// helpScreen lists the KeysHelp, and the ones matching a search.
+type helpScreen struct {
+	textScreen
+	search string
+}
+
+func newHelpScreen() *helpScreen {
+	s := &helpScreen{}
+	s.lines = s.helpLines
+	s.title = tr("Help - /: search, q: back")
+	return s
+}
+
+func (s *helpScreen) helpLines() []string {
+	search := strings.ToLower(s.search)
+	var lines []string
+	for _, key := range KeysHelp {
+		if search != "" && !strings.Contains(strings.ToLower(key.Keys+" "+tr(key.Description)), search) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-14s %s", key.Keys, tr(key.Description)))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, fmt.Sprintf(tr("No key matches %q"), s.search))
+	}
+	return lines
+}
+
+func (s *helpScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	if ev.Ch == '/' {
+		ui.prompt = &prompt{label: tr("Search: "), text: []rune(s.search), done: func(ui *UI, text string) {
+			s.search = text
+			s.start = 0
+			s.title = tr("Help - /: search, q: back")
+			if text != "" {
+				s.title = fmt.Sprintf(tr("Help matching %q - /: search, q: back"), text)
+			}
+		}}
+		return true
+	}
+	return s.textScreen.HandleKey(ui, ev)
+}
+
+// HandleHelp displays the help screen.
+func (ui *UI) HandleHelp() {
+	ui.screen = newHelpScreen()
+}
+
+// helpBar returns the keys displayed on the last row when there's no status,
+// depending on what's displayed.
+func (ui *UI) helpBar() []KeyHelp {
+	keys := []KeyHelp{{"F1", "Help"}}
+	switch {
+	case len(ui.tagged) > 0:
+		keys = append(keys, KeyHelp{"space", "Tag"}, KeyHelp{"U", "Untag all"})
+	case Aggregate == proc.GroupByName:
+		keys = append(keys, KeyHelp{"enter", "Expand"}, KeyHelp{"a", "Ungroup"})
+	case Aggregate == proc.GroupByUnit:
+		keys = append(keys, KeyHelp{"enter", "Expand"}, KeyHelp{"A", "Ungroup"})
+	default:
+		keys = append(keys, KeyHelp{"enter", "Details"}, KeyHelp{"space", "Tag"})
+	}
+	if !ui.ReadOnly {
+		keys = append(keys, KeyHelp{"F9", "Signal"}, KeyHelp{"F7", "Nice-"}, KeyHelp{"F8", "Nice+"})
+	}
+	filter := KeyHelp{"F4", "Filter"}
+	if Filter != nil {
+		filter = KeyHelp{"F4", "Edit filter"}
+	}
+	keys = append(keys, filter, KeyHelp{"t", "Tree"}, KeyHelp{"F2", "Setup"}, KeyHelp{"q", "Quit"})
+	return keys
+}
+
+// drawHelpBar draws the help bar on the last row.
+func (ui *UI) drawHelpBar() {
+	ui.x, ui.y = 0, ui.height-statusRows
+	for _, key := range ui.helpBar() {
+		// The keys that don't fit are left out rather than cut off.
+		if ui.x+stringWidth(key.Keys+tr(key.Description)) > ui.width {
+			break
+		}
+		ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+		for _, ch := range key.Keys {
+			ui.setCell(ch)
+		}
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		for _, ch := range tr(key.Description) + "  " {
+			ui.setCell(ch)
+		}
+	}
+	ui.writeLastColumn("")
+}
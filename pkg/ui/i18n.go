@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Catalog maps the English messages of jtop to their translation in another
+// language. The messages including values are the format strings passed to
+// fmt, so their translations must keep the same verbs in the same order.
+type Catalog map[string]string
+
+// catalog is the Catalog of the language set by SetLanguage, or nil for
+// English.
+var catalog Catalog
+
+// LocaleDirs are the directories searched for the catalogs of the languages,
+// in order. The catalog of a language is the file named after it with the
+// .po extension, like de.po or pt_BR.po.
+var LocaleDirs = []string{"/usr/local/share/jtop/locale", "/usr/share/jtop/locale"}
+
+// tr returns the translation of a message in the language set by
+// SetLanguage, or the message itself if it isn't translated. The column
+// titles aren't translated, since they're typed in --columns and --filter.
+func tr(message string) string {
+	if translation, ok := catalog[message]; ok {
+		return translation
+	}
+	return message
+}
+
+// Translate returns the translation of a message of the jtop command, like
+// tr does for the ones of the UI.
+func Translate(message string) string {
+	return tr(message)
+}
+
+// DetectLanguage returns the language of the messages of the locale, from
+// $LANGUAGE, $LC_ALL, $LC_MESSAGES or $LANG, like gettext.
+func DetectLanguage() string {
+	if languages := os.Getenv("LANGUAGE"); languages != "" && os.Getenv("LC_ALL") != "C" {
+		return trimLocale(strings.Split(languages, ":")[0])
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			return trimLocale(locale)
+		}
+	}
+	return ""
+}
+
+// trimLocale returns the language and the territory of a locale, which is
+// "language[_territory][.codeset][@modifier]".
+func trimLocale(locale string) string {
+	return strings.SplitN(strings.SplitN(locale, ".", 2)[0], "@", 2)[0]
+}
+
+// SetLanguage translates the messages to a language with the first catalog
+// of the LocaleDirs found for it, or for the language without its territory.
+// English, C and POSIX don't need a catalog.
+func SetLanguage(language string) error {
+	catalog = nil
+	if language == "" || language == "C" || language == "POSIX" || strings.HasPrefix(language, "en") {
+		return nil
+	}
+	names := []string{language}
+	if i := strings.Index(language, "_"); i > 0 {
+		names = append(names, language[:i])
+	}
+	for _, name := range names {
+		for _, dir := range LocaleDirs {
+			c, err := ReadCatalog(filepath.Join(dir, name+".po"))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			catalog = c
+			return nil
+		}
+	}
+	return fmt.Errorf("no catalog for %s in %s", language, strings.Join(LocaleDirs, ", "))
+}
+
+// ReadCatalog reads a catalog in the PO format of gettext, so translators can
+// use its tools, starting from the template written by jtop-messages:
+//
+//	#: pkg/ui/help.go:21
+//	msgid "quit"
+//	msgstr "beenden"
+//
+// The entries marked as fuzzy and the ones without a translation are
+// ignored. Contexts and plural forms aren't supported.
+func ReadCatalog(path string) (Catalog, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(Catalog)
+	var id, str string
+	var field *string
+	fuzzy := false
+	add := func() {
+		if id != "" && str != "" && !fuzzy {
+			c[id] = str
+		}
+		id, str, field, fuzzy = "", "", nil, false
+	}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			// The comments and the flags of an entry precede its
+			// msgid, so they end the previous one.
+			if field != nil {
+				add()
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				fuzzy = true
+			}
+			continue
+		case strings.HasPrefix(line, "msgid "):
+			if field != nil {
+				add()
+			}
+			field, line = &id, strings.TrimPrefix(line, "msgid ")
+		case strings.HasPrefix(line, "msgstr "):
+			field, line = &str, strings.TrimPrefix(line, "msgstr ")
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, fmt.Errorf("%s:%d: string outside of an entry", path, n+1)
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: unsupported line %s", path, n+1, strings.Fields(line)[0])
+		}
+		s, err := strconv.Unquote(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid string %s", path, n+1, line)
+		}
+		*field += s
+	}
+	add()
+	return c, nil
+}
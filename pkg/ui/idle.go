@@ -0,0 +1,37 @@
+package ui
+
+import "github.com/mayhewj/gtop/pkg/proc"
+
+// idleStatus is displayed on the last row while idle processes are hidden.
+const idleStatus = "%d idle hidden (r: show)"
+
+// HideIdle hides the idle processes, whose %CPU is below IdleCPU and whose
+// disk reads and writes per second add up to IdleIO at most. The processes
+// running or waiting for I/O are never idle.
+var (
+	HideIdle bool
+	IdleCPU  = 0.5
+	IdleIO   = 0.0
+)
+
+// idle returns whether or not a process is idle, see HideIdle.
+func idle(m *proc.Monitor, p *proc.Process) bool {
+	if p.State == 'R' || p.State == 'D' {
+		return false
+	}
+	return m.CPUPercent(p) < IdleCPU && m.Rate(p.ReadBytesDiff+p.WriteBytesDiff) <= IdleIO
+}
+
+// idleHidden returns the number of processes that match the filters but
+// are hidden because they're idle.
+func idleHidden(m *proc.Monitor) int {
+	if !HideIdle {
+		return 0
+	}
+	return len(filterList(m, m.List, false)) - len(filterList(m, m.List, true))
+}
+
+// HandleToggleIdle hides the idle processes, or shows them again.
+func (ui *UI) HandleToggleIdle() {
+	HideIdle = !HideIdle
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// interruptSorts are the orders the interrupts screen can list the
+// interrupts in, cycled through with s.
+var interruptSorts = []struct {
+	name string
+	sort func([]*proc.Interrupt)
+}{
+	{"rate", func(irqs []*proc.Interrupt) { sort.Stable(proc.ByInterruptRate(irqs)) }},
+	{"total", func(irqs []*proc.Interrupt) { sort.Stable(proc.ByInterruptTotal(irqs)) }},
+	{"number", func(irqs []*proc.Interrupt) {}},
+}
+
+const interruptDescriptionWidth = 32
+
+// interruptsScreen lists the hardware interrupts and the softirqs with their
+// rates on each CPU, to diagnose interrupt storms.
+type interruptsScreen struct {
+	textScreen
+	monitor *proc.Monitor
+	sort    int
+
+	// opened is when the screen was opened, before which the Monitor
+	// may not have read the interrupts.
+	opened time.Time
+}
+
+func newInterruptsScreen(m *proc.Monitor) *interruptsScreen {
+	s := &interruptsScreen{monitor: m, opened: time.Now()}
+	s.lines = s.interruptLines
+	s.setTitle()
+	return s
+}
+
+func (s *interruptsScreen) setTitle() {
+	s.title = fmt.Sprintf(tr("Interrupts by %s - s: sort, q: back"), interruptSorts[s.sort].name)
+}
+
+func (s *interruptsScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	if ev.Ch == 's' {
+		s.sort = (s.sort + 1) % len(interruptSorts)
+		s.setTitle()
+		return true
+	}
+	return s.textScreen.HandleKey(ui, ev)
+}
+
+func (s *interruptsScreen) interruptLines() []string {
+	m := s.monitor
+	if len(m.Interrupts) == 0 && m.LastUpdate.Before(s.opened) {
+		return []string{tr("Reading the interrupts at the next update")}
+	}
+	if len(m.Interrupts) == 0 {
+		return []string{tr("No interrupts, /proc/interrupts can't be read")}
+	}
+
+	var irqs, softirqs []*proc.Interrupt
+	for _, irq := range m.Interrupts {
+		if irq.Softirq {
+			softirqs = append(softirqs, irq)
+		} else {
+			irqs = append(irqs, irq)
+		}
+	}
+
+	var lines []string
+	for _, section := range []struct {
+		title string
+		irqs  []*proc.Interrupt
+	}{{"IRQ", irqs}, {"SOFTIRQ", softirqs}} {
+		if len(section.irqs) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		interruptSorts[s.sort].sort(section.irqs)
+
+		var title strings.Builder
+		title.WriteString(padLeft("RATE/s", 8) + " " + padLeft("TOTAL", 13) + " " + padRight(section.title, 8) + " ")
+		title.WriteString(padRight("DESCRIPTION", interruptDescriptionWidth))
+		for i := range section.irqs[0].Counts {
+			title.WriteString(" " + padLeft(fmt.Sprintf("CPU%d", i), 7))
+		}
+		lines = append(lines, title.String())
+
+		for _, irq := range section.irqs {
+			var line strings.Builder
+			line.WriteString(padLeft(formatCount(m.Rate(irq.TotalDiff)), 8) + " ")
+			line.WriteString(padLeft(formatThousands(irq.Total), 13) + " ")
+			line.WriteString(padRight(irq.Name, 8) + " ")
+			description := runewidth.Truncate(irq.Description, interruptDescriptionWidth, "+")
+			line.WriteString(padRight(description, interruptDescriptionWidth))
+			for _, diff := range irq.CountsDiff {
+				line.WriteString(" " + padLeft(formatCount(m.Rate(diff)), 7))
+			}
+			lines = append(lines, line.String())
+		}
+	}
+	return lines
+}
+
+// HandleInterrupts displays the rates of the interrupts on each CPU, which
+// the Monitor reads from then on.
+func (ui *UI) HandleInterrupts() {
+	ui.monitor.IRQs = true
+	if ui.live != nil {
+		ui.live.IRQs = true
+	}
+	ui.screen = newInterruptsScreen(ui.monitor)
+}
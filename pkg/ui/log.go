@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// logRows is the number of rows of the log panel, including its title.
+const logRows = 8
+
+// HandleToggleLog shows or hides the panel logging the processes that
+// started and exited below the process table.
+func (ui *UI) HandleToggleLog() {
+	ui.showLog = !ui.showLog
+}
+
+// tableEnd returns the row below the last row of the process table.
+func (ui *UI) tableEnd() int {
+	end := ui.height - statusRows
+	if ui.showLog {
+		end -= logRows
+	}
+	return end
+}
+
+// drawLog draws the log panel, which isn't scrolled with the table.
+func (ui *UI) drawLog() {
+	offset := ui.offset
+	ui.offset = 0
+	defer func() { ui.offset = offset }()
+
+	ui.x, ui.y = 0, ui.tableEnd()
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	ui.writeLastColumn(tr("Started (+) and exited (-) processes - E: hide"))
+	ui.y++
+
+	lines := logLines(ui.monitor.Log)
+	if n := logRows - 1; len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+	for _, line := range lines {
+		ui.x = 0
+		ui.writeLastColumn(line)
+		ui.y++
+	}
+}
+
+// logLines returns the lines of the log panel, oldest first. The processes
+// that started and exited between two updates are marked with ±.
+func logLines(events []proc.ProcessEvent) []string {
+	var lines []string
+	for _, e := range events {
+		marker := "+"
+		switch {
+		case e.Started && e.Exited:
+			marker = "±"
+		case e.Exited:
+			marker = "-"
+		}
+		line := fmt.Sprintf("%s %s %7d  %s", e.Time.Format("15:04:05"), marker, e.Pid, e.Command)
+
+		var details []string
+		if e.Exited && !e.Started {
+			lifetime := e.Lifetime.String()
+			if e.Lifetime < time.Second {
+				lifetime = "<1s"
+			}
+			details = append(details, "ran "+lifetime)
+		}
+		if exit := e.ExitString(); exit != "" {
+			details = append(details, exit)
+		}
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
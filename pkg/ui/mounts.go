@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// mountsRefresh is how often the mounts screen reads the mounts and their
+// usage again.
+const mountsRefresh = time.Second
+
+// mountFullPercent is the use percentage above which a mount is highlighted
+// as almost full.
+const mountFullPercent = 90
+
+const mountDeviceWidth = 24
+
+// mountsScreen lists the mounted filesystems with their size and usage like
+// df, and highlights the ones that are almost full.
+type mountsScreen struct {
+	textScreen
+	mounts []*proc.Mount
+	err    error
+	read   time.Time
+}
+
+func newMountsScreen() *mountsScreen {
+	s := &mountsScreen{}
+	s.title = tr("Filesystems - q: back")
+	s.lines = s.mountLines
+	s.color = s.mountColor
+	return s
+}
+
+func (s *mountsScreen) mountLines() []string {
+	if time.Since(s.read) >= mountsRefresh {
+		s.mounts, s.err = proc.ReadMounts()
+		s.read = time.Now()
+	}
+	if s.err != nil {
+		return []string{fmt.Sprintf(tr("The mounts can't be read: %s"), s.err)}
+	}
+
+	lines := []string{fmt.Sprintf("%s %8s %8s %8s %4s  %-10s %s",
+		padRight("FILESYSTEM", mountDeviceWidth), "SIZE", "USED", "AVAIL", "USE%", "TYPE", "MOUNTED ON")}
+	for _, mount := range s.mounts {
+		device := runewidth.Truncate(mount.Device, mountDeviceWidth, "+")
+		lines = append(lines, fmt.Sprintf("%s %8s %8s %8s %3d%%  %-10s %s",
+			padRight(device, mountDeviceWidth), formatBytes(mount.Size), formatBytes(mount.Used),
+			formatBytes(mount.Avail), mount.UsePercent(), mount.Type, mount.Path))
+	}
+	return lines
+}
+
+// mountColor highlights the lines of the mounts that are almost full. The
+// first line is the titles of the columns.
+func (s *mountsScreen) mountColor(i int) termbox.Attribute {
+	if s.err == nil && i > 0 && i <= len(s.mounts) && s.mounts[i-1].UsePercent() > mountFullPercent {
+		return theme.CriticalFG
+	}
+	return termbox.ColorDefault
+}
+
+// HandleMounts displays the usage of the mounted filesystems.
+func (ui *UI) HandleMounts() {
+	if ui.ReadOnly {
+		ui.SetStatus(tr("The filesystems of a recording or a remote machine can't be displayed"))
+		return
+	}
+	ui.screen = newMountsScreen()
+}
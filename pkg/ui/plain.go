@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// WritePlain writes the header lines and as many processes of the table as
+// fit in height rows as plain text, truncated to width columns after dropping
+// the columns that don't fit like the UI does. It's used in place of the UI
+// when termbox can't be initialized.
+func WritePlain(w io.Writer, m *proc.Monitor, width, height int, mode CommandMode) error {
+	bw := bufio.NewWriter(w)
+	var rows []string
+	for _, line := range stackHeaderLines(headerLines(m), width) {
+		rows = append(rows, line.text)
+	}
+
+	columns := fitColumns(Columns, width, m.SortKey)
+	var title strings.Builder
+	for _, column := range columns {
+		title.WriteString(plainColumn(column, column.Title))
+	}
+	rows = append(rows, strings.TrimRight(title.String(), " "))
+
+	for _, p := range processList(m) {
+		if height > 0 && len(rows) >= height {
+			break
+		}
+		var row strings.Builder
+		for _, column := range columns {
+			if column != CommandColumn {
+				row.WriteString(plainColumn(column, column.Value(m, p)))
+				continue
+			}
+			command := mode.value(m, p)
+			if m.Tree || Aggregate != proc.Ungrouped {
+				command = p.TreePrefix + command
+			}
+			row.WriteString(command + " ")
+		}
+		rows = append(rows, strings.TrimRight(row.String(), " "))
+	}
+
+	for _, row := range rows {
+		row = plainText(row)
+		if width > 0 && stringWidth(row) > width {
+			row = runewidth.Truncate(row, width, "")
+		}
+		bw.WriteString(row + "\n")
+	}
+	return bw.Flush()
+}
+
+// plainColumn returns a value padded to the width of its column, followed by
+// the space separating it from the next one, like writeColumn draws it.
+func plainColumn(column *Column, s string) string {
+	width := column.Width
+	if stringWidth(s) > width && !column.RightAlign {
+		s = runewidth.Truncate(s, width, "+")
+	}
+	if column.RightAlign {
+		return padLeft(s, width) + " "
+	}
+	return padRight(s, width) + " "
+}
+
+// plainText replaces the control characters of s with '?', like setCell, so
+// they don't move the cursor of the terminal.
+func plainText(s string) string {
+	return strings.Map(func(ch rune) rune {
+		if unicode.IsControl(ch) {
+			return '?'
+		}
+		return ch
+	}, s)
+}
@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// ColumnProvider provides columns that aren't built into jtop, like the
+// heap of the JVMs or the depth of the queue of a server.
+type ColumnProvider interface {
+	// Titles returns the titles of the columns.
+	Titles() []string
+	// Values returns the values of the columns for the processes of a
+	// Monitor by PID, in the order of Titles. The processes it returns
+	// no values for display nothing.
+	Values(m *proc.Monitor) (map[uint64][]string, error)
+}
+
+// minExtraColumnWidth is the width of the columns of ColumnProviders with
+// short titles.
+const minExtraColumnWidth = 8
+
+var (
+	columnProviders []ColumnProvider
+	extraColumns    int
+)
+
+// AddColumnProvider adds the columns of a ColumnProvider to AllColumns, so
+// they can be displayed and sorted by like the built-in ones.
+func AddColumnProvider(provider ColumnProvider) {
+	for _, title := range provider.Titles() {
+		title = strings.ToUpper(title)
+		width := minExtraColumnWidth
+		if len(title) > width {
+			width = len(title)
+		}
+		column := &Column{title, width, true, proc.SortByExtra + proc.SortKey(extraColumns), formatExtra(extraColumns)}
+		AllColumns = append(AllColumns, column)
+		extraColumns++
+	}
+	columnProviders = append(columnProviders, provider)
+}
+
+func formatExtra(i int) func(m *proc.Monitor, p *proc.Process) string {
+	return func(m *proc.Monitor, p *proc.Process) string {
+		if i < len(p.Extra) {
+			return p.Extra[i]
+		}
+		return ""
+	}
+}
+
+// UpdateColumnProviders sets the Extra values of the processes of a Monitor
+// to those of the ColumnProviders after an update, and sorts them again in
+// case they're sorted by one of them. It returns the first error of a
+// ColumnProvider, whose values are then left empty.
+func UpdateColumnProviders(m *proc.Monitor) error {
+	if len(columnProviders) == 0 {
+		return nil
+	}
+	for _, p := range m.List {
+		p.Extra = make([]string, extraColumns)
+	}
+
+	var firstErr error
+	offset := 0
+	for _, provider := range columnProviders {
+		values, err := provider.Values(m)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		for _, p := range m.List {
+			copy(p.Extra[offset:offset+len(provider.Titles())], values[p.Pid])
+		}
+		offset += len(provider.Titles())
+	}
+	m.Sort()
+	return firstErr
+}
+
+// scriptTimeout is how long a column script has to print its values.
+const scriptTimeout = time.Second
+
+// scriptColumn is a column whose values are printed by an executable, which
+// is passed the PIDs of the processes on its standard input, one per line,
+// and prints a PID and a value on each line of its output:
+//
+//	#!/bin/sh
+//	# Prints the number of open file descriptors of each process.
+//	while read pid; do
+//		echo "$pid $(ls /proc/$pid/fd 2>/dev/null | wc -l)"
+//	done
+type scriptColumn struct {
+	title string
+	path  string
+}
+
+func (c *scriptColumn) Titles() []string {
+	return []string{c.title}
+}
+
+func (c *scriptColumn) Values(m *proc.Monitor) (map[uint64][]string, error) {
+	var pids bytes.Buffer
+	for _, p := range m.List {
+		fmt.Fprintln(&pids, p.Pid)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.path)
+	cmd.Stdin = &pids
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", c.path, err)
+	}
+
+	values := make(map[uint64][]string)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(fields) < 2 {
+			continue
+		}
+		pid, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[pid] = []string{strings.TrimSpace(fields[1])}
+	}
+	return values, nil
+}
+
+// AddColumnScripts adds a column for each executable of a directory, titled
+// with its name in uppercase without its extension, e.g. JVM_HEAP for
+// jvm_heap.sh. See scriptColumn for what they print.
+func AddColumnScripts(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			continue
+		}
+		name := entry.Name()
+		title := strings.TrimSuffix(name, filepath.Ext(name))
+		if ColumnByTitle(title) != nil {
+			return fmt.Errorf("%s: column %s already exists", filepath.Join(dir, name), strings.ToUpper(title))
+		}
+		AddColumnProvider(&scriptColumn{title, filepath.Join(dir, name)})
+	}
+	return nil
+}
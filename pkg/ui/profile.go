@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProfileDuration is how long the p key samples the stacks of the selected
+// process for.
+var ProfileDuration = 5 * time.Second
+
+// profileFrequency is the number of stack samples perf records per second,
+// off 100 so it doesn't run in lockstep with timers.
+const profileFrequency = 99
+
+// profileLines is the number of functions and stacks the profile screen
+// lists.
+const profileLines = 20
+
+// HandleProfile samples the stacks of the selected process on the CPUs with
+// perf for ProfileDuration, and displays the functions and stacks that were
+// sampled the most.
+func (ui *UI) HandleProfile() {
+	process := ui.SelectedProcess()
+	if process == nil {
+		return
+	}
+	switch {
+	case ui.ReadOnly:
+		ui.SetStatus(tr("Processes of a recording or a remote machine can't be profiled"))
+		return
+	case os.Geteuid() != 0:
+		ui.SetStatus(tr("Profiling requires root"))
+		return
+	}
+	perf, err := exec.LookPath("perf")
+	if err != nil {
+		ui.SetStatus(tr("Profiling requires perf"))
+		return
+	}
+
+	s := &profileScreen{pid: process.Pid, started: time.Now()}
+	s.title = fmt.Sprintf(tr("Profile of process %s - q: back"), process)
+	s.lines = s.profileLines
+	go s.run(perf)
+	ui.screen = s
+}
+
+// profileScreen displays the progress of a profile until it's done, and then
+// its result.
+type profileScreen struct {
+	textScreen
+	pid     uint64
+	started time.Time
+
+	mu     sync.Mutex
+	done   bool
+	err    error
+	stacks map[string]int
+}
+
+// run records the profile, and parses it once it's done.
+func (s *profileScreen) run(perf string) {
+	stacks, err := recordProfile(perf, s.pid, ProfileDuration)
+	s.mu.Lock()
+	s.done, s.stacks, s.err = true, stacks, err
+	s.mu.Unlock()
+}
+
+func (s *profileScreen) profileLines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		left := ProfileDuration - time.Since(s.started)
+		if left < 0 {
+			return []string{tr("Reading the samples...")}
+		}
+		return []string{fmt.Sprintf(tr("Sampling the stacks for %s..."), left.Round(time.Second))}
+	}
+	if s.err != nil {
+		return []string{s.err.Error()}
+	}
+	return foldedSummary(s.stacks)
+}
+
+// recordProfile runs perf record on a process for a duration, and returns
+// the number of samples of each stack, folded into the names of its
+// functions from the outermost to the innermost separated by semicolons.
+func recordProfile(perf string, pid uint64, duration time.Duration) (map[string]int, error) {
+	dir, err := ioutil.TempDir("", "jtop-profile")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	data := dir + "/perf.data"
+
+	record := exec.Command(perf, "record", "--quiet", "-F", strconv.Itoa(profileFrequency), "-g",
+		"-p", strconv.FormatUint(pid, 10), "-o", data,
+		"--", "sleep", strconv.FormatFloat(duration.Seconds(), 'f', -1, 64))
+	if output, err := record.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("perf record: %s: %s", err, firstLine(output))
+	}
+	script := exec.Command(perf, "script", "-i", data, "-F", "ip,sym")
+	output, err := script.Output()
+	if err != nil {
+		return nil, fmt.Errorf("perf script: %s", err)
+	}
+	return foldStacks(bytes.NewReader(output)), nil
+}
+
+// foldStacks folds the stacks printed by perf script -F ip,sym, which are
+// separated by blank lines and list the innermost function first, one
+// "address function+offset" per line.
+func foldStacks(r io.Reader) map[string]int {
+	stacks := make(map[string]int)
+	var frames []string
+	fold := func() {
+		if len(frames) == 0 {
+			return
+		}
+		for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+			frames[i], frames[j] = frames[j], frames[i]
+		}
+		stacks[strings.Join(frames, ";")]++
+		frames = frames[:0]
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			fold()
+			continue
+		}
+		function := "[unknown]"
+		if len(fields) > 1 {
+			function = strings.Join(fields[1:], " ")
+			if i := strings.LastIndex(function, "+0x"); i > 0 {
+				function = function[:i]
+			}
+		}
+		frames = append(frames, function)
+	}
+	fold()
+	return stacks
+}
+
+// foldedSummary lists the functions that were sampled the most on the CPU
+// themselves, and the stacks that were sampled the most.
+func foldedSummary(stacks map[string]int) []string {
+	total := 0
+	self := make(map[string]int)
+	for stack, n := range stacks {
+		total += n
+		self[stack[strings.LastIndexByte(stack, ';')+1:]] += n
+	}
+	if total == 0 {
+		return []string{"No samples, the process didn't run on a CPU"}
+	}
+
+	lines := []string{fmt.Sprintf("%d samples", total), "", "Hottest functions:"}
+	lines = append(lines, topCounts(self, total)...)
+	lines = append(lines, "", "Hottest stacks:")
+	return append(lines, topCounts(stacks, total)...)
+}
+
+// topCounts returns the profileLines keys with the highest counts, with
+// their percentage of the total.
+func topCounts(counts map[string]int, total int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] == counts[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return counts[keys[i]] > counts[keys[j]]
+	})
+	if len(keys) > profileLines {
+		keys = keys[:profileLines]
+	}
+
+	var lines []string
+	for _, key := range keys {
+		percent := 100 * float64(counts[key]) / float64(total)
+		lines = append(lines, fmt.Sprintf("  %5.1f%% %6d  %s", percent, counts[key], key))
+	}
+	return lines
+}
+
+// firstLine returns the first line of the output of a command.
+func firstLine(output []byte) string {
+	return strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+}
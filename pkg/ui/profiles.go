@@ -0,0 +1,347 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// Profile is a named set of options for a troubleshooting context, such as
+// the columns and the filter to use on database hosts. Options are the long
+// names of the flags of jtop and their values.
+type Profile struct {
+	Name    string
+	Options []ProfileOption
+}
+
+// ProfileOption is an option of a Profile. The Value of boolean options is
+// "true" when it's omitted.
+type ProfileOption struct {
+	Name  string
+	Value string
+}
+
+// Profiles are the profiles that can be switched to while jtop is running.
+var Profiles []*Profile
+
+// startupProfile is the name the layout jtop started with is listed as by the
+// profiles screen.
+const startupProfile = "startup"
+
+// ReadProfiles reads a profiles file, where each profile starts with its name
+// between brackets and is followed by an option per line:
+//
+//	[db-host]
+//	columns PID,USER,%CPU,RSS,DISK_R/s,DISK_W/s,COMMAND
+//	sort DISK_W/s
+//	filter rss > 1G
+//	header Memory,Disk,Pressure
+//
+// Lines starting with # are comments.
+func ReadProfiles(path string) ([]*Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var profiles []*Profile
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" || name == startupProfile {
+				return nil, fmt.Errorf("%s:%d: invalid profile name %q", path, n, name)
+			}
+			for _, p := range profiles {
+				if p.Name == name {
+					return nil, fmt.Errorf("%s:%d: profile %s defined more than once", path, n, name)
+				}
+			}
+			profiles = append(profiles, &Profile{Name: name})
+			continue
+		}
+		if len(profiles) == 0 {
+			return nil, fmt.Errorf("%s:%d: option outside of a profile", path, n)
+		}
+		fields := strings.SplitN(line, " ", 2)
+		option := ProfileOption{Name: strings.TrimPrefix(fields[0], "--"), Value: "true"}
+		if len(fields) == 2 {
+			option.Value = strings.TrimSpace(fields[1])
+		}
+		p := profiles[len(profiles)-1]
+		p.Options = append(p.Options, option)
+	}
+	return profiles, scanner.Err()
+}
+
+// ProfileByName returns the Profile of Profiles with the passed in name, or
+// nil if there is no such profile.
+func ProfileByName(name string) *Profile {
+	for _, p := range Profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// layout is what switching profiles changes while jtop is running. The other
+// options of profiles only apply when starting with them.
+type layout struct {
+	columns   []*Column
+	sortKey   proc.SortKey
+	reverse   bool
+	tree      bool
+	aggregate proc.Grouping
+	filter    *proc.Filter
+	command   *regexp.Regexp
+	header    map[*HeaderSection]bool
+}
+
+func (ui *UI) currentLayout() *layout {
+	l := &layout{
+		columns:   append([]*Column{}, Columns...),
+		sortKey:   ui.monitor.SortKey,
+		reverse:   ui.monitor.Reverse,
+		tree:      ui.monitor.Tree,
+		aggregate: Aggregate,
+		filter:    Filter,
+		header:    make(map[*HeaderSection]bool),
+	}
+	if !CommandPatternDisabled {
+		l.command = CommandPattern
+	}
+	for _, section := range HeaderSections {
+		l.header[section] = section.Enabled
+	}
+	return l
+}
+
+func (ui *UI) setLayout(l *layout) {
+	Columns = append([]*Column{}, l.columns...)
+	ui.monitor.SortKey, ui.monitor.Reverse, ui.monitor.Tree = l.sortKey, l.reverse, l.tree
+	Aggregate = l.aggregate
+	Filter = l.filter
+	CommandPattern, CommandPatternDisabled = l.command, false
+	for section, enabled := range l.header {
+		section.Enabled = enabled
+	}
+	ui.monitor.Sort()
+	ui.HandleSelectFirst()
+}
+
+// set sets an option of a Profile, and returns false if it isn't part of the
+// layout.
+func (l *layout) set(option ProfileOption) (bool, error) {
+	var err error
+	switch option.Name {
+	case "columns":
+		l.columns, err = ParseColumns(option.Value)
+	case "sort":
+		column := ColumnByTitle(option.Value)
+		if column == nil || column.Sort == proc.SortByNone {
+			return true, fmt.Errorf("%s is not a valid sort column", option.Value)
+		}
+		l.sortKey = column.Sort
+	case "reverse":
+		l.reverse, err = strconv.ParseBool(option.Value)
+	case "tree":
+		l.tree, err = strconv.ParseBool(option.Value)
+	case "aggregate", "aggregate-units":
+		var group bool
+		if group, err = strconv.ParseBool(option.Value); err == nil {
+			l.aggregate = proc.Ungrouped
+			if group && option.Name == "aggregate" {
+				l.aggregate = proc.GroupByName
+			} else if group {
+				l.aggregate = proc.GroupByUnit
+			}
+		}
+	case "filter":
+		l.filter = nil
+		if option.Value != "" {
+			l.filter, err = proc.ParseFilter(option.Value)
+		}
+	case "command":
+		l.command = nil
+		if option.Value != "" {
+			l.command, err = regexp.Compile(option.Value)
+		}
+	case "header":
+		var enabled []*HeaderSection
+		if enabled, err = ParseHeaderSections(option.Value); err == nil {
+			for section := range l.header {
+				l.header[section] = false
+			}
+			for _, section := range enabled {
+				l.header[section] = true
+			}
+		}
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("invalid %s: %s", option.Name, err)
+	}
+	return true, nil
+}
+
+// ParseHeaderSections parses a comma-separated list of names of header
+// sections, ignoring case.
+func ParseHeaderSections(s string) ([]*HeaderSection, error) {
+	var sections []*HeaderSection
+	for _, name := range strings.Split(s, ",") {
+		var found *HeaderSection
+		for _, section := range HeaderSections {
+			if strings.EqualFold(section.Name, strings.TrimSpace(name)) {
+				found = section
+			}
+		}
+		if found == nil {
+			var names []string
+			for _, section := range HeaderSections {
+				names = append(names, section.Name)
+			}
+			return nil, fmt.Errorf("unknown header section %s (%s)", name, strings.Join(names, ", "))
+		}
+		sections = append(sections, found)
+	}
+	return sections, nil
+}
+
+// SetHeaderSections displays the header sections of a comma-separated list
+// of names, and hides the others.
+func SetHeaderSections(s string) error {
+	enabled, err := ParseHeaderSections(s)
+	if err != nil {
+		return err
+	}
+	for _, section := range HeaderSections {
+		section.Enabled = false
+	}
+	for _, section := range enabled {
+		section.Enabled = true
+	}
+	return nil
+}
+
+// switchProfile changes the layout to the one jtop started with, changed by
+// the layout options of a Profile. p is nil for the startup layout.
+func (ui *UI) switchProfile(p *Profile) {
+	l := *ui.startLayout
+	l.columns = append([]*Column{}, l.columns...)
+	l.header = make(map[*HeaderSection]bool)
+	for section, enabled := range ui.startLayout.header {
+		l.header[section] = enabled
+	}
+
+	name := startupProfile
+	var skipped []string
+	if p != nil {
+		name = p.Name
+		for _, option := range p.Options {
+			ok, err := l.set(option)
+			if err != nil {
+				ui.SetStatus(fmt.Sprintf(tr("Profile %s: %s"), p.Name, err))
+				return
+			}
+			if !ok {
+				skipped = append(skipped, option.Name)
+			}
+		}
+	}
+	ui.setLayout(&l)
+	ui.profile = name
+
+	if len(skipped) > 0 {
+		ui.SetStatus(fmt.Sprintf(tr("Switched to the %s profile (restart with -P %s for %s)"), name, name, strings.Join(skipped, ", ")))
+	} else {
+		ui.SetStatus(fmt.Sprintf(tr("Switched to the %s profile"), name))
+	}
+}
+
+// HandleProfiles displays the screen to switch to another profile.
+func (ui *UI) HandleProfiles() {
+	if len(Profiles) == 0 {
+		ui.SetStatus(tr("There are no profiles, see --profile"))
+		return
+	}
+	s := &profilesScreen{}
+	for i, p := range Profiles {
+		if p.Name == ui.profile {
+			s.selected = i + 1
+		}
+	}
+	ui.screen = s
+}
+
+// SetStartupProfile sets the name of the profile jtop started with, which
+// the profiles screen marks as the current one.
+func (ui *UI) SetStartupProfile(name string) {
+	ui.profile = name
+}
+
+// profilesScreen is the screen used to choose the profile switched to. The
+// startup layout is listed first.
+type profilesScreen struct {
+	selected int
+}
+
+func (s *profilesScreen) Draw(ui *UI) {
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	ui.writeLastColumn(tr("Switch profile - enter: switch, q: cancel"))
+	ui.y++
+
+	names := []string{startupProfile}
+	for _, p := range Profiles {
+		names = append(names, p.Name)
+	}
+	for i, name := range names {
+		ui.x = 0
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		if i == s.selected {
+			ui.fg, ui.bg = theme.SelectedFG, theme.SelectedBG
+		}
+		mark := "  "
+		if name == ui.profile || (i == 0 && ui.profile == "") {
+			mark = "* "
+		}
+		ui.writeLastColumn(mark + name)
+		ui.y++
+	}
+}
+
+func (s *profilesScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	switch {
+	case ev.Ch == 'q' || ev.Key == termbox.KeyEsc:
+		return false
+	case ev.Ch == 'j' || ev.Key == termbox.KeyArrowDown:
+		if s.selected < len(Profiles) {
+			s.selected++
+		}
+	case ev.Ch == 'k' || ev.Key == termbox.KeyArrowUp:
+		if s.selected > 0 {
+			s.selected--
+		}
+	case ev.Key == termbox.KeyEnter:
+		var p *Profile
+		if s.selected > 0 {
+			p = Profiles[s.selected-1]
+		}
+		ui.switchProfile(p)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// prompt is a line of text edited on the last row, in place of the status.
+type prompt struct {
+	label string
+	text  []rune
+	// done is called with the text when it's accepted with enter.
+	done func(ui *UI, text string)
+}
+
+func (p *prompt) String() string {
+	return p.label + string(p.text)
+}
+
+// Prompting returns whether or not a line of text is being edited, in which
+// case key events go to HandlePromptKey.
+func (ui *UI) Prompting() bool {
+	return ui.prompt != nil
+}
+
+// HandlePromptKey edits the line of text being prompted for.
+func (ui *UI) HandlePromptKey(ev termbox.Event) {
+	p := ui.prompt
+	switch {
+	case ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyCtrlC:
+		ui.prompt = nil
+	case ev.Key == termbox.KeyEnter:
+		ui.prompt = nil
+		p.done(ui, string(p.text))
+	case ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2:
+		if len(p.text) > 0 {
+			p.text = p.text[:len(p.text)-1]
+		}
+	case ev.Key == termbox.KeyCtrlU:
+		p.text = nil
+	case ev.Key == termbox.KeySpace:
+		p.text = append(p.text, ' ')
+	case ev.Ch != 0:
+		p.text = append(p.text, ev.Ch)
+	}
+}
+
+// drawCursor shows the cursor at the end of the prompt, or hides it.
+func (ui *UI) drawCursor() {
+	if ui.prompt == nil {
+		terminal.HideCursor()
+		return
+	}
+	terminal.SetCursor(stringWidth(ui.prompt.String()), ui.height-statusRows)
+}
+
+// HandleFilter prompts for the Filter expression, see proc.ParseFilter. An
+// empty expression shows every process.
+func (ui *UI) HandleFilter() {
+	p := &prompt{label: tr("Filter: "), done: func(ui *UI, text string) {
+		if text == "" {
+			Filter = nil
+			return
+		}
+		filter, err := proc.ParseFilter(text)
+		if err != nil {
+			ui.SetStatus(fmt.Sprintf(tr("Invalid filter: %s"), err))
+			return
+		}
+		Filter = filter
+		ui.HandleSelectFirst()
+	}}
+	if Filter != nil {
+		p.text = []rune(Filter.String())
+	}
+	ui.prompt = p
+}
+
+// HandleCommandPattern prompts for the CommandPattern. An empty pattern shows
+// every process.
+func (ui *UI) HandleCommandPattern() {
+	p := &prompt{label: tr("Command: "), done: func(ui *UI, text string) {
+		if text == "" {
+			CommandPattern = nil
+			return
+		}
+		re, err := regexp.Compile(text)
+		if err != nil {
+			ui.SetStatus(fmt.Sprintf(tr("Invalid pattern: %s"), err))
+			return
+		}
+		CommandPattern, CommandPatternDisabled = re, false
+		ui.HandleSelectFirst()
+	}}
+	if CommandPattern != nil {
+		p.text = []rune(CommandPattern.String())
+	}
+	ui.prompt = p
+}
+
+// HandleToggleCommandPattern stops or starts hiding the processes that don't
+// match the CommandPattern, or prompts for it if there isn't one.
+func (ui *UI) HandleToggleCommandPattern() {
+	if CommandPattern == nil {
+		ui.HandleCommandPattern()
+		return
+	}
+	CommandPatternDisabled = !CommandPatternDisabled
+}
@@ -0,0 +1,336 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// readerTop is the number of processes listed by the top command of the
+// ScreenReader when it isn't passed a number.
+const readerTop = 5
+
+// ScreenReaderHelp lists the commands of the ScreenReader.
+var ScreenReaderHelp = []string{
+	"Commands:",
+	"summary or s: CPU, memory and load of the system",
+	"top or t, followed by an optional count: the first processes in the sort order",
+	"next or n, previous or p, first: select another process and describe it",
+	"select followed by a PID or a name: select a process",
+	"details or d: everything about the selected process",
+	"sort followed by a column, reverse: change the sort order",
+	"filter followed by an expression, or nothing to clear it",
+	"announce on or off: say the summary after every update",
+	"help or h, quit or q",
+}
+
+// readerLabels are the spoken labels of the columns whose titles are
+// abbreviations. The other columns are labeled with their title.
+var readerLabels = map[*Column]string{
+	PidColumn:        "PID",
+	UserColumn:       "user",
+	RSSColumn:        "resident memory",
+	MemPercentColumn: "memory percent",
+	CPUPercentColumn: "CPU percent",
+	CPUTimeColumn:    "CPU time",
+	StartColumn:      "started",
+	StateColumn:      "state",
+	NiceColumn:       "nice",
+	CommandColumn:    "command",
+	DiskReadColumn:   "disk reads per second",
+	DiskWriteColumn:  "disk writes per second",
+	ContainerColumn:  "container",
+	NetRxColumn:      "network received per second",
+	NetTxColumn:      "network sent per second",
+	GPUPercentColumn: "GPU percent",
+	GPUMemoryColumn:  "GPU memory",
+	WakeupsColumn:    "wakeups per second",
+	SwapColumn:       "swap",
+	MajfltColumn:     "major faults per second",
+	MinfltColumn:     "minor faults per second",
+	SecurityColumn:   "security context",
+	PidNSColumn:      "PID namespace",
+	NetNSColumn:      "network namespace",
+	MntNSColumn:      "mount namespace",
+	UnitColumn:       "unit",
+	ThreadsColumn:    "threads",
+	VCSWColumn:       "voluntary switches per second",
+	NVCSWColumn:      "involuntary switches per second",
+	CPUWaitColumn:    "CPU wait percent",
+	IOWaitColumn:     "I/O wait percent",
+	NumaColumn:       "NUMA",
+	OOMColumn:        "OOM score",
+	IOPriorityColumn: "I/O priority",
+	FDColumn:         "open files",
+	CgroupMemColumn:  "cgroup memory percent",
+	ThrottledColumn:  "throttled percent",
+}
+
+// ScreenReader is the line oriented interface of --screen-reader, for the
+// operators using jtop with a terminal screen reader. Unlike the UI it never
+// moves the cursor or draws boxes: it answers the commands typed at a prompt
+// with a few lines of labeled text, and only speaks between two commands when
+// Announce is set.
+type ScreenReader struct {
+	// Announce makes Updated return the summary of the system after every
+	// update.
+	Announce bool
+
+	monitor  *proc.Monitor
+	selected uint64
+	mode     CommandMode
+}
+
+// NewScreenReader returns a ScreenReader describing the processes of a
+// Monitor, displaying their commands in a CommandMode.
+func NewScreenReader(m *proc.Monitor, mode CommandMode) *ScreenReader {
+	return &ScreenReader{monitor: m, mode: mode}
+}
+
+// Updated returns the lines to print after the Monitor was updated.
+func (r *ScreenReader) Updated() []string {
+	if !r.Announce {
+		return nil
+	}
+	return r.Summary()
+}
+
+// Summary returns the usage of the CPU and the memory, the load and the
+// number of processes.
+func (r *ScreenReader) Summary() []string {
+	m := r.monitor
+	lines := []string{
+		fmt.Sprintf("CPU %.1f percent used.", m.SystemCPUPercent()),
+		fmt.Sprintf("Memory %s used of %s, %.1f percent.",
+			formatBytes(m.MemTotal-m.MemAvailable), formatBytes(m.MemTotal), m.MemPercent()),
+		fmt.Sprintf("Load average %.2f, %.2f, %.2f.", m.LoadAvg[0], m.LoadAvg[1], m.LoadAvg[2]),
+		fmt.Sprintf("%d processes, sorted by %s.", len(processList(m)), r.sortLabel()),
+	}
+	if n := idleHidden(m); n > 0 {
+		lines = append(lines, fmt.Sprintf("%d idle processes hidden.", n))
+	}
+	for _, p := range m.Pressure {
+		if p.Some >= pressureWarning {
+			lines = append(lines, fmt.Sprintf("%s pressure %.1f percent.", p.Resource, p.Some))
+		}
+	}
+	return lines
+}
+
+// HandleCommand runs a command typed at the prompt, and returns the lines to
+// print and whether or not jtop should quit.
+func (r *ScreenReader) HandleCommand(command string) ([]string, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	arg := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(command), fields[0]))
+
+	switch fields[0] {
+	case "q", "quit":
+		return nil, true
+	case "h", "help", "?":
+		var lines []string
+		for _, line := range ScreenReaderHelp {
+			lines = append(lines, tr(line))
+		}
+		return lines, false
+	case "s", "summary":
+		return r.Summary(), false
+	case "t", "top":
+		n := readerTop
+		if arg != "" {
+			var err error
+			if n, err = strconv.Atoi(arg); err != nil || n <= 0 {
+				return []string{"The count must be a positive number."}, false
+			}
+		}
+		return r.top(n), false
+	case "n", "next":
+		return r.move(1), false
+	case "p", "previous":
+		return r.move(-1), false
+	case "first":
+		r.selected = 0
+		return r.move(0), false
+	case "select":
+		return r.selectProcess(arg), false
+	case "d", "details":
+		p := r.selectedProcess()
+		if p == nil {
+			return []string{"No process is selected."}, false
+		}
+		return readerDetails(r.monitor, p.Pid), false
+	case "sort":
+		column := ColumnByTitle(arg)
+		if column == nil {
+			column = readerColumn(arg)
+		}
+		if column == nil || column.Sort == proc.SortByNone {
+			return []string{"Unknown column " + arg + "."}, false
+		}
+		r.monitor.SortKey, r.monitor.Reverse = column.Sort, false
+		r.monitor.Sort()
+		return []string{"Sorted by " + r.sortLabel() + "."}, false
+	case "reverse":
+		r.monitor.Reverse = !r.monitor.Reverse
+		r.monitor.Sort()
+		return []string{"Sorted by " + r.sortLabel() + "."}, false
+	case "filter":
+		if arg == "" {
+			Filter = nil
+			return []string{"Filter cleared."}, false
+		}
+		filter, err := proc.ParseFilter(arg)
+		if err != nil {
+			return []string{"Invalid filter: " + err.Error() + "."}, false
+		}
+		Filter = filter
+		return []string{fmt.Sprintf("%d processes match.", len(processList(r.monitor)))}, false
+	case "announce":
+		switch arg {
+		case "on":
+			r.Announce = true
+		case "off":
+			r.Announce = false
+		default:
+			return []string{"Usage: announce on or announce off."}, false
+		}
+		return []string{"Announcing updates " + arg + "."}, false
+	}
+	return []string{"Unknown command " + fields[0] + ", type help for the commands."}, false
+}
+
+// top describes the first n processes in the sort order, on a line each.
+func (r *ScreenReader) top(n int) []string {
+	list := processList(r.monitor)
+	if n > len(list) {
+		n = len(list)
+	}
+	lines := []string{fmt.Sprintf("Top %d of %d processes by %s:", n, len(list), r.sortLabel())}
+	for i, p := range list[:n] {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, r.describe(p)))
+	}
+	return lines
+}
+
+// move selects the process delta rows away from the selected one, or the
+// first one if the selected process is gone, and describes it.
+func (r *ScreenReader) move(delta int) []string {
+	list := processList(r.monitor)
+	if len(list) == 0 {
+		return []string{"There are no processes."}
+	}
+	i := 0
+	for j, p := range list {
+		if p.Pid == r.selected {
+			i = j + delta
+		}
+	}
+	if i < 0 {
+		i = 0
+	} else if i >= len(list) {
+		i = len(list) - 1
+	}
+	r.selected = list[i].Pid
+	return []string{fmt.Sprintf("%d of %d. %s", i+1, len(list), r.describe(list[i]))}
+}
+
+// selectProcess selects the process with a PID, or the first one in the sort
+// order whose name contains s.
+func (r *ScreenReader) selectProcess(s string) []string {
+	if s == "" {
+		return []string{"Usage: select followed by a PID or a name."}
+	}
+	pid, err := strconv.ParseUint(s, 10, 64)
+	for _, p := range processList(r.monitor) {
+		if (err == nil && p.Pid == pid) || (err != nil && strings.Contains(p.Name, s)) {
+			r.selected = p.Pid
+			return r.move(0)
+		}
+	}
+	return []string{"No process matches " + s + "."}
+}
+
+func (r *ScreenReader) selectedProcess() *proc.Process {
+	for _, p := range processList(r.monitor) {
+		if p.Pid == r.selected {
+			return p
+		}
+	}
+	return nil
+}
+
+// describe returns the labeled values of the displayed columns of a process,
+// starting with its name.
+func (r *ScreenReader) describe(p *proc.Process) string {
+	parts := []string{p.Name}
+	if p.Restricted {
+		parts[0] = "unreadable process"
+	}
+	for _, column := range Columns {
+		value := column.Value(r.monitor, p)
+		if column == CommandColumn {
+			value = r.mode.value(r.monitor, p)
+		}
+		value = strings.TrimSpace(value)
+		if value == "" || value == "-" {
+			continue
+		}
+		parts = append(parts, readerLabel(column)+" "+value)
+	}
+	return plainText(strings.Join(parts, ", ")) + "."
+}
+
+func (r *ScreenReader) sortLabel() string {
+	label := "PID"
+	for _, column := range AllColumns {
+		if column.Sort == r.monitor.SortKey {
+			label = readerLabel(column)
+			break
+		}
+	}
+	if r.monitor.Reverse {
+		label += ", reversed"
+	}
+	return label
+}
+
+func readerLabel(column *Column) string {
+	if label, ok := readerLabels[column]; ok {
+		return label
+	}
+	return column.Title
+}
+
+// readerColumn returns the column with a spoken label, ignoring case.
+func readerColumn(label string) *Column {
+	for _, column := range AllColumns {
+		if strings.EqualFold(readerLabel(column), label) {
+			return column
+		}
+	}
+	return nil
+}
+
+// readerDetails returns the processDetails of a process without the
+// sparklines and the blank lines, which screen readers can't make sense of.
+func readerDetails(m *proc.Monitor, pid uint64) []string {
+	var lines []string
+	for _, line := range processDetails(m, pid, false) {
+		line = strings.Map(func(ch rune) rune {
+			for _, block := range sparkBlocks {
+				if ch == block {
+					return -1
+				}
+			}
+			return ch
+		}, line)
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, plainText(line))
+		}
+	}
+	return lines
+}
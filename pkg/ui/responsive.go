@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+const (
+	// minCommandWidth is the width the COMMAND column keeps on narrow
+	// terminals, where the other columns are dropped until it fits.
+	minCommandWidth = 20
+
+	// headerIndent is the width of the labels of the header lines, like
+	// "mem" or "psi cpu", which stacked fields are indented by.
+	headerIndent = 12
+)
+
+// columnPriorities are the priorities of the columns on narrow terminals,
+// where the columns with the lowest priority are dropped first, and the
+// rightmost of those with the same priority. The columns that aren't listed
+// have the priority 0, and PID, COMMAND and the sort column are never dropped.
+var columnPriorities = map[*Column]int{
+	CPUPercentColumn: 3,
+	MemPercentColumn: 3,
+	UserColumn:       2,
+	RSSColumn:        2,
+	StateColumn:      1,
+	CPUTimeColumn:    1,
+}
+
+// fitColumns returns the columns that fit in width cells, leaving at least
+// minCommandWidth cells to COMMAND, without the columns dropped by their
+// columnPriorities. A width of 0 fits every column.
+func fitColumns(columns []*Column, width int, sortKey proc.SortKey) []*Column {
+	if width <= 0 {
+		return columns
+	}
+	fitted := append([]*Column(nil), columns...)
+	for tableWidth(fitted) > width {
+		drop := -1
+		for i, column := range fitted {
+			if column == PidColumn || column == CommandColumn || column.Sort == sortKey {
+				continue
+			}
+			if drop < 0 || columnPriorities[column] <= columnPriorities[fitted[drop]] {
+				drop = i
+			}
+		}
+		if drop < 0 {
+			break
+		}
+		fitted = append(fitted[:drop], fitted[drop+1:]...)
+	}
+	return fitted
+}
+
+// tableWidth returns the width of a row of columns, counting minCommandWidth
+// for COMMAND.
+func tableWidth(columns []*Column) int {
+	width := 0
+	for _, column := range columns {
+		if column.Width < 0 {
+			width += minCommandWidth
+			continue
+		}
+		width += columnWidth(column) + 1 // separating space
+	}
+	return width
+}
+
+// columns returns the columns the table displays, which are the Columns that
+// fit in the terminal unless KeepColumns is set.
+func (ui *UI) columns() []*Column {
+	if ui.KeepColumns {
+		return Columns
+	}
+	return fitColumns(Columns, ui.width, ui.monitor.SortKey)
+}
+
+// headerField matches the start of the fields of a header line after the
+// first one, like "  avail" in "mem  used 1.2G / 7.7G  avail 5.9G": two
+// spaces or more followed by a label. Numbers padded with spaces don't start
+// a field.
+var headerField = regexp.MustCompile(` {2,}\pL`)
+
+// stackHeaderLines breaks the header lines wider than width cells before the
+// fields that don't fit, continuing them on the next lines indented by
+// headerIndent, so the meters are stacked rather than cut off on narrow
+// terminals. A width of 0 keeps the lines as they are.
+func stackHeaderLines(lines []headerLine, width int) []headerLine {
+	if width <= 0 {
+		return lines
+	}
+	var stacked []headerLine
+	for _, line := range lines {
+		if stringWidth(line.text) <= width {
+			stacked = append(stacked, line)
+			continue
+		}
+
+		var fields []string
+		start := 0
+		for _, match := range headerField.FindAllStringIndex(line.text, -1) {
+			// The spaces padding the label aren't between fields.
+			if match[0] < headerIndent {
+				continue
+			}
+			fields = append(fields, line.text[start:match[0]])
+			start = match[0] + strings.LastIndex(line.text[match[0]:match[1]], " ") + 1
+		}
+		fields = append(fields, line.text[start:])
+
+		text := fields[0]
+		for _, field := range fields[1:] {
+			if stringWidth(text)+2+stringWidth(field) <= width {
+				text += "  " + field
+				continue
+			}
+			stacked = append(stacked, headerLine{text, line.fg})
+			text = strings.Repeat(" ", headerIndent) + field
+		}
+		stacked = append(stacked, headerLine{text, line.fg})
+	}
+	return stacked
+}
+
+// meterLines returns the lines of the header, stacked to fit in the
+// terminal.
+func (ui *UI) meterLines() []headerLine {
+	return stackHeaderLines(headerLines(ui.monitor), ui.width)
+}
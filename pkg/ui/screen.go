@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"github.com/nsf/termbox-go"
+)
+
+// Screen is a view displayed in place of the process table, such as the
+// column setup or the process details.
+type Screen interface {
+	Draw(ui *UI)
+
+	// HandleKey handles a key event. It returns false when the screen
+	// should be closed.
+	HandleKey(ui *UI, ev termbox.Event) bool
+}
+
+// textScreen is a Screen that displays scrollable lines of text, which are
+// regenerated every time the screen is drawn.
+type textScreen struct {
+	title string
+	lines func() []string
+	start int
+
+	// color, if set, returns the foreground color of a line given its
+	// index in the lines returned by the last call of lines.
+	color func(i int) termbox.Attribute
+}
+
+func (s *textScreen) Draw(ui *UI) {
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	ui.writeLastColumn(s.title)
+	ui.y++
+
+	lines := s.lines()
+	s.clampStart(ui, len(lines))
+
+	ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+	for i, line := range lines[s.start:] {
+		if ui.y >= ui.height {
+			break
+		}
+		ui.x = 0
+		if s.color != nil {
+			ui.fg = s.color(s.start + i)
+		}
+		ui.writeLastColumn(line)
+		ui.y++
+	}
+}
+
+func (s *textScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	page := ui.height - headerRows
+
+	switch {
+	case ev.Ch == 'q' || ev.Key == termbox.KeyEsc:
+		return false
+	case ev.Ch == 'j' || ev.Key == termbox.KeyArrowDown:
+		s.start++
+	case ev.Ch == 'k' || ev.Key == termbox.KeyArrowUp:
+		s.start--
+	case ev.Ch == 'h' || ev.Key == termbox.KeyArrowLeft:
+		ui.HandleLeft()
+	case ev.Ch == 'l' || ev.Key == termbox.KeyArrowRight:
+		ui.HandleRight()
+	case ev.Ch == 'g':
+		s.start = 0
+	case ev.Ch == 'G':
+		s.start = len(s.lines())
+	case ev.Key == termbox.KeyCtrlD:
+		s.start += page / 2
+	case ev.Key == termbox.KeyCtrlU:
+		s.start -= page / 2
+	}
+	s.clampStart(ui, len(s.lines()))
+	return true
+}
+
+func (s *textScreen) clampStart(ui *UI, nLines int) {
+	if max := nLines - (ui.height - headerRows); s.start > max {
+		s.start = max
+	}
+	if s.start < 0 {
+		s.start = 0
+	}
+}
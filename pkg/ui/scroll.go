@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// columnWidth returns the width of a column in the table, which is at least
+// the width of its title.
+func columnWidth(column *Column) int {
+	width := column.Width
+	if titleWidth := stringWidth(column.Title); titleWidth > width {
+		width = titleWidth
+	}
+	return width
+}
+
+// commandStart returns the position of the COMMAND column in the table, or -1
+// if it isn't displayed.
+func (ui *UI) commandStart() int {
+	x := 0
+	for _, column := range ui.columns() {
+		if column == CommandColumn {
+			return x
+		}
+		if column.Width < 0 {
+			break
+		}
+		x += columnWidth(column) + 1 // separating space
+	}
+	return -1
+}
+
+// commandValue returns the value of the COMMAND column of a process, and the
+// tree prefix displayed before it.
+func (ui *UI) commandValue(p *proc.Process) (string, string) {
+	value := ui.Command.value(ui.monitor, p)
+	prefix := ""
+	if ui.monitor.Tree || Aggregate != proc.Ungrouped {
+		prefix = p.TreePrefix
+	}
+	return value, prefix
+}
+
+// snapPoints returns the positions the table snaps to when it's scrolled
+// horizontally, in order: the start of each column up to the COMMAND column,
+// and then the start of each word of the command of the selected process.
+func (ui *UI) snapPoints() []int {
+	var points []int
+	x := 0
+	for _, column := range ui.columns() {
+		points = append(points, x)
+		if column == CommandColumn {
+			break
+		}
+		if column.Width < 0 {
+			return points
+		}
+		x += columnWidth(column) + 1
+	}
+
+	p := ui.SelectedProcess()
+	if p == nil || !ColumnVisible(CommandColumn) {
+		return points
+	}
+	value, prefix := ui.commandValue(p)
+	x += stringWidth(prefix)
+	previous := ' '
+	for _, ch := range value {
+		if (previous == ' ' || previous == '/') && ch != ' ' && ch != '/' && x > points[len(points)-1] {
+			points = append(points, x)
+		}
+		x += runeWidth(ch)
+		previous = ch
+	}
+	return points
+}
+
+// HandleLeft scrolls the table left to the previous column or word of the
+// selected command, see snapPoints.
+func (ui *UI) HandleLeft() {
+	if ui.screen != nil {
+		ui.offset -= offsetStep
+	} else if !ui.Wrap {
+		points := ui.snapPoints()
+		target := 0
+		for _, x := range points {
+			if x < ui.offset {
+				target = x
+			}
+		}
+		// Past the last word, the table scrolls by steps.
+		if last := points[len(points)-1]; ui.offset > last && ui.offset-offsetStep > target {
+			target = ui.offset - offsetStep
+		}
+		ui.offset = target
+	}
+	if ui.offset < 0 {
+		ui.offset = 0
+	}
+}
+
+// HandleRight scrolls the table right to the next column or word of the
+// selected command, see snapPoints.
+func (ui *UI) HandleRight() {
+	if ui.screen != nil {
+		ui.offset += offsetStep
+		return
+	}
+	if ui.Wrap {
+		return
+	}
+	for _, x := range ui.snapPoints() {
+		if x > ui.offset {
+			ui.offset = x
+			return
+		}
+	}
+	ui.offset += offsetStep
+}
+
+// HandleToggleWrap wraps the commands that don't fit onto continuation rows,
+// or truncates them.
+func (ui *UI) HandleToggleWrap() {
+	ui.Wrap = !ui.Wrap
+	ui.offset = 0
+}
+
+// processRows returns the number of rows a process is displayed on, which is
+// more than one when Wrap is set and its command doesn't fit.
+func (ui *UI) processRows(p *proc.Process) int {
+	start := ui.commandStart()
+	if !ui.Wrap || start < 0 {
+		return 1
+	}
+	value, prefix := ui.commandValue(p)
+	available := ui.width - start - stringWidth(prefix)
+	width := stringWidth(value)
+	if available <= 0 || width <= available {
+		return 1
+	}
+	return (width + available - 1) / available
+}
+
+// writeWrappedCommand writes a command and its tree prefix, continuing on the
+// next rows if it doesn't fit, without writing below the table.
+func (ui *UI) writeWrappedCommand(command, prefix string) {
+	previous := ui.fg
+	ui.fg = theme.TreeFG
+	for _, ch := range prefix {
+		ui.setCell(ch)
+	}
+	ui.fg = previous
+
+	start := ui.x
+	for i := 0; ; {
+		width := 0
+		n := 0
+		for _, ch := range command {
+			if width+runeWidth(ch) > ui.width-start && width > 0 {
+				break
+			}
+			width += runeWidth(ch)
+			n += len(string(ch))
+		}
+		ui.writeCommand(command[:n], i)
+		command = command[n:]
+		i += n
+		if command == "" || ui.y+1 >= ui.tableEnd() {
+			return
+		}
+
+		ui.y++
+		ui.x = 0
+		for ui.x < start {
+			ui.setCell(' ')
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// HandleSearch prompts for a text to search the processes for, and selects
+// the first match from the selected process on. Unlike a filter, the
+// processes that don't match stay displayed. An empty text stops searching.
+func (ui *UI) HandleSearch() {
+	p := &prompt{label: tr("Search: "), done: func(ui *UI, text string) {
+		ui.search = text
+		if text != "" {
+			ui.selectMatch(0, 1)
+		}
+	}}
+	p.text = []rune(ui.search)
+	ui.prompt = p
+}
+
+// Searching returns whether or not the processes are being searched, in
+// which case n and N select the next and previous matches.
+func (ui *UI) Searching() bool {
+	return ui.search != ""
+}
+
+// HandleClearSearch stops searching.
+func (ui *UI) HandleClearSearch() {
+	ui.search = ""
+}
+
+// HandleNextMatch selects the next match of the search after the selected
+// process, or the previous one before it if delta is -1, wrapping around.
+func (ui *UI) HandleNextMatch(delta int) {
+	ui.selectMatch(delta, delta)
+}
+
+// selectMatch selects the first match from the process at offset from the
+// selected one, searching in the direction of delta.
+func (ui *UI) selectMatch(offset, delta int) {
+	list := processList(ui.monitor)
+	n := len(list)
+	for i, from := 0, ui.start+ui.selected+offset; i < n; i++ {
+		j := ((from+i*delta)%n + n) % n
+		if ui.matchesSearch(list[j]) {
+			ui.follow = 0
+			ui.selectIndex(j)
+			return
+		}
+	}
+	ui.SetStatus(fmt.Sprintf(tr("Not found: %s"), ui.search))
+}
+
+// matchesSearch returns whether or not the PID, the name or the displayed
+// command of a process contains the search, ignoring case.
+func (ui *UI) matchesSearch(p *proc.Process) bool {
+	search := strings.ToLower(ui.search)
+	command, _ := ui.commandValue(p)
+	return strconv.FormatUint(p.Pid, 10) == ui.search ||
+		strings.Contains(strings.ToLower(p.Name), search) ||
+		strings.Contains(strings.ToLower(command), search)
+}
+
+// searchStatus returns the position of the selected process among the
+// matches of the search, and their number.
+func (ui *UI) searchStatus() string {
+	matches, current := 0, 0
+	for i, p := range processList(ui.monitor) {
+		if !ui.matchesSearch(p) {
+			continue
+		}
+		matches++
+		if i == ui.start+ui.selected {
+			current = matches
+		}
+	}
+	if current == 0 {
+		return fmt.Sprintf(tr("/%s: %d matches"), ui.search, matches)
+	}
+	return fmt.Sprintf(tr("/%s: %d of %d matches"), ui.search, current, matches)
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"github.com/nsf/termbox-go"
+)
+
+// setupScreen is the screen used to choose which columns are displayed
+// and in what order.
+type setupScreen struct {
+	selected int
+}
+
+// setupColumns returns the visible columns in order followed by the hidden
+// columns.
+func setupColumns() []*Column {
+	columns := append([]*Column{}, Columns...)
+	for _, column := range AllColumns {
+		if !ColumnVisible(column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func (s *setupScreen) Draw(ui *UI) {
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	ui.writeLastColumn(tr("Column setup - space: show/hide, J/K: move, q: done"))
+	ui.y++
+
+	for i, column := range setupColumns() {
+		ui.x = 0
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		if i == s.selected {
+			ui.fg, ui.bg = theme.SelectedFG, theme.SelectedBG
+		}
+
+		mark := "[ ] "
+		if ColumnVisible(column) {
+			mark = "[x] "
+		}
+		ui.writeLastColumn(mark + column.Title)
+		ui.y++
+	}
+}
+
+func (s *setupScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	columns := setupColumns()
+	selected := columns[s.selected]
+
+	switch {
+	case ev.Ch == 'q' || ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyF2:
+		return false
+	case ev.Ch == 'j' || ev.Key == termbox.KeyArrowDown:
+		if s.selected < len(columns)-1 {
+			s.selected++
+		}
+	case ev.Ch == 'k' || ev.Key == termbox.KeyArrowUp:
+		if s.selected > 0 {
+			s.selected--
+		}
+	case ev.Key == termbox.KeySpace || ev.Key == termbox.KeyEnter:
+		s.toggleColumn(selected)
+	case ev.Ch == 'J':
+		s.moveColumn(selected, 1)
+	case ev.Ch == 'K':
+		s.moveColumn(selected, -1)
+	}
+	return true
+}
+
+func (s *setupScreen) toggleColumn(column *Column) {
+	if ColumnVisible(column) {
+		for i, c := range Columns {
+			if c == column {
+				Columns = append(Columns[:i], Columns[i+1:]...)
+				break
+			}
+		}
+	} else {
+		Columns = CommandLast(append(Columns, column))
+	}
+
+	// Keep the cursor on the column that was toggled.
+	for i, c := range setupColumns() {
+		if c == column {
+			s.selected = i
+		}
+	}
+}
+
+// moveColumn moves a visible column by delta positions. COMMAND always
+// stays last since it takes up the remaining width of the screen.
+func (s *setupScreen) moveColumn(column *Column, delta int) {
+	if column == CommandColumn {
+		return
+	}
+
+	for i, c := range Columns {
+		if c != column {
+			continue
+		}
+		j := i + delta
+		if j < 0 || j >= len(Columns) || Columns[j] == CommandColumn {
+			return
+		}
+		Columns[i], Columns[j] = Columns[j], Columns[i]
+		s.selected = j
+		return
+	}
+}
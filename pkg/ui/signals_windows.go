@@ -0,0 +1,21 @@
+package ui
+
+import "syscall"
+
+// Signals are the signals that can be sent from the signal screen, which all
+// terminate the process on Windows.
+var Signals = []struct {
+	Name   string
+	Signal syscall.Signal
+}{
+	{"SIGTERM", syscall.SIGTERM},
+	{"SIGKILL", syscall.SIGKILL},
+	{"SIGINT", syscall.SIGINT},
+}
+
+// Windows has neither SIGSTOP nor SIGCONT, which Process.Signal fails to send
+// there, so they're the signals of Linux.
+const (
+	sigStop = syscall.Signal(0x13)
+	sigCont = syscall.Signal(0x12)
+)
@@ -0,0 +1,234 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// socketsRefresh is how often the sockets screen reads the socket tables and
+// the file descriptors of every process again.
+const socketsRefresh = time.Second
+
+// socketRow is a TCP or UDP socket, and the process that has it open.
+type socketRow struct {
+	socket *proc.Socket
+	owner  *proc.Process
+}
+
+// socketsScreen lists the listening and connected TCP and UDP sockets with
+// the processes they belong to. Pressing Enter selects the process of the
+// selected socket in the process table.
+type socketsScreen struct {
+	monitor  *proc.Monitor
+	filter   string
+	rows     []socketRow
+	read     time.Time
+	start    int
+	selected int
+}
+
+func newSocketsScreen(m *proc.Monitor) *socketsScreen {
+	return &socketsScreen{monitor: m}
+}
+
+// refresh reads the sockets again if they were read more than socketsRefresh
+// ago, and keeps those matching the filter.
+func (s *socketsScreen) refresh() {
+	if time.Since(s.read) < socketsRefresh {
+		return
+	}
+	s.read = time.Now()
+
+	owners := proc.SocketOwners(s.monitor)
+	s.rows = s.rows[:0]
+	for inode, socket := range proc.ReadSockets() {
+		if socket.Proto == "unix" {
+			continue
+		}
+		row := socketRow{socket, owners[inode]}
+		if s.matches(row) {
+			s.rows = append(s.rows, row)
+		}
+	}
+	sort.Slice(s.rows, func(i, j int) bool {
+		s1, s2 := s.rows[i].socket, s.rows[j].socket
+		if l1, l2 := socketListening(s1), socketListening(s2); l1 != l2 {
+			return l1
+		}
+		if s1.LocalPort != s2.LocalPort {
+			return s1.LocalPort < s2.LocalPort
+		}
+		if s1.Proto != s2.Proto {
+			return s1.Proto < s2.Proto
+		}
+		return s1.Inode < s2.Inode
+	})
+}
+
+// socketListening returns whether or not a socket waits for connections or
+// datagrams, rather than being connected.
+func socketListening(s *proc.Socket) bool {
+	return s.State == "LISTEN" || s.State == "UNCONN"
+}
+
+// matches returns whether or not a socket matches every word of the filter:
+// a protocol like tcp or udp6, a state like listen, a local or remote port
+// like 8080 or :8080, or a part of the name of its process.
+func (s *socketsScreen) matches(row socketRow) bool {
+	for _, word := range strings.Fields(strings.ToLower(s.filter)) {
+		if !socketMatches(row, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func socketMatches(row socketRow, word string) bool {
+	socket := row.socket
+	if port, err := strconv.ParseUint(strings.TrimPrefix(word, ":"), 10, 16); err == nil {
+		return uint64(socket.LocalPort) == port || uint64(socket.RemotePort) == port
+	}
+	switch word {
+	case "tcp", "udp":
+		return strings.HasPrefix(socket.Proto, word)
+	case "tcp6", "udp6":
+		return socket.Proto == word
+	}
+	if strings.EqualFold(socket.State, word) {
+		return true
+	}
+	return row.owner != nil && strings.Contains(strings.ToLower(row.owner.Name), word)
+}
+
+func (s *socketsScreen) Draw(ui *UI) {
+	s.refresh()
+	s.clamp(ui)
+
+	title := tr("Sockets - enter: select process, /: filter, q: back")
+	if s.filter != "" {
+		title = fmt.Sprintf(tr("Sockets matching %q - enter: select process, /: filter, q: back"), s.filter)
+	}
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	ui.writeLastColumn(title)
+	ui.y++
+	ui.x = 0
+	ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+	ui.writeLastColumn(fmt.Sprintf("%-5s %-28s %-28s %-11s %7s  %s",
+		"PROTO", "LOCAL", "REMOTE", "STATE", "PID", "PROCESS"))
+	ui.y++
+
+	if len(s.rows) == 0 {
+		ui.x = 0
+		ui.writeLastColumn(tr("No sockets"))
+		return
+	}
+	for i := s.start; i < len(s.rows) && ui.y < ui.height; i++ {
+		ui.x = 0
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		if i == s.selected {
+			ui.fg, ui.bg = theme.SelectedFG, theme.SelectedBG
+		}
+		ui.writeLastColumn(socketLine(s.rows[i]))
+		ui.y++
+	}
+}
+
+// socketLine returns the row of a socket on the sockets screen.
+func socketLine(row socketRow) string {
+	socket := row.socket
+	remote := ""
+	if !socketListening(socket) || socket.RemotePort != 0 {
+		remote = socketAddress(socket.RemoteIP, socket.RemotePort)
+	}
+	pid, name := "", ""
+	if row.owner != nil {
+		pid, name = formatThousands(row.owner.Pid), row.owner.Name
+	}
+	return fmt.Sprintf("%-5s %-28s %-28s %-11s %7s  %s",
+		socket.Proto, socketAddress(socket.LocalIP, socket.LocalPort), remote, socket.State, pid, name)
+}
+
+func socketAddress(ip net.IP, port uint16) string {
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+}
+
+// socketsRows is the number of rows above the sockets.
+const socketsRows = 2
+
+func (s *socketsScreen) clamp(ui *UI) {
+	if s.selected >= len(s.rows) {
+		s.selected = len(s.rows) - 1
+	}
+	if s.selected < 0 {
+		s.selected = 0
+	}
+	page := ui.height - socketsRows
+	if s.selected < s.start {
+		s.start = s.selected
+	} else if page > 0 && s.selected >= s.start+page {
+		s.start = s.selected - page + 1
+	}
+}
+
+func (s *socketsScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	page := ui.height - socketsRows
+
+	switch {
+	case ev.Ch == 'q' || ev.Key == termbox.KeyEsc:
+		return false
+	case ev.Ch == 'j' || ev.Key == termbox.KeyArrowDown:
+		s.selected++
+	case ev.Ch == 'k' || ev.Key == termbox.KeyArrowUp:
+		s.selected--
+	case ev.Ch == 'g':
+		s.selected = 0
+	case ev.Ch == 'G':
+		s.selected = len(s.rows) - 1
+	case ev.Key == termbox.KeyCtrlD:
+		s.selected += page / 2
+	case ev.Key == termbox.KeyCtrlU:
+		s.selected -= page / 2
+	case ev.Ch == '/':
+		ui.prompt = &prompt{label: tr("Filter (e.g. tcp listen :8080): "), text: []rune(s.filter), done: func(ui *UI, text string) {
+			s.filter = text
+			s.read = time.Time{}
+			s.selected = 0
+		}}
+	case ev.Key == termbox.KeyEnter:
+		return !s.selectOwner(ui)
+	}
+	s.clamp(ui)
+	return true
+}
+
+// selectOwner selects the process of the selected socket in the process
+// table, and returns false if it can't.
+func (s *socketsScreen) selectOwner(ui *UI) bool {
+	if s.selected >= len(s.rows) {
+		return false
+	}
+	owner := s.rows[s.selected].owner
+	switch {
+	case owner == nil:
+		ui.SetStatus(tr("The process of the socket is unknown"))
+		return false
+	case !ui.selectPid(owner.Pid):
+		ui.SetStatus(fmt.Sprintf(tr("Process %s isn't displayed"), owner))
+		return false
+	}
+	ui.follow = 0
+	return true
+}
+
+// HandleSockets lists the TCP and UDP sockets and their processes.
+func (ui *UI) HandleSockets() {
+	ui.screen = newSocketsScreen(ui.monitor)
+}
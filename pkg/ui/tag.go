@@ -0,0 +1,300 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+// HandleTag tags the selected process, or untags it if it's already tagged,
+// and selects the next one.
+func (ui *UI) HandleTag() {
+	process := ui.SelectedProcess()
+	if process == nil {
+		return
+	}
+	if ui.tagged == nil {
+		ui.tagged = make(map[uint64]bool)
+	}
+	if ui.tagged[process.Pid] {
+		delete(ui.tagged, process.Pid)
+	} else {
+		ui.tagged[process.Pid] = true
+	}
+	ui.HandleDown()
+}
+
+// HandleUntagAll untags every process.
+func (ui *UI) HandleUntagAll() {
+	ui.tagged = nil
+}
+
+// pruneTagged untags the processes that exited, so their Pids being reused
+// doesn't tag other processes.
+func (ui *UI) pruneTagged() {
+	for pid := range ui.tagged {
+		if _, ok := ui.monitor.Map[pid]; !ok {
+			delete(ui.tagged, pid)
+		}
+	}
+}
+
+// targets returns the processes acted on: the tagged ones, or the selected
+// one if none are tagged. Aggregated processes stand for their Members.
+func (ui *UI) targets() []*proc.Process {
+	var tagged []*proc.Process
+	for _, process := range processList(ui.monitor) {
+		if ui.tagged[process.Pid] {
+			tagged = append(tagged, process)
+		}
+	}
+	if len(tagged) == 0 {
+		if process := ui.SelectedProcess(); process != nil {
+			tagged = append(tagged, process)
+		}
+	}
+
+	var processes []*proc.Process
+	seen := make(map[uint64]bool)
+	add := func(process *proc.Process) {
+		if !seen[process.Pid] {
+			seen[process.Pid] = true
+			processes = append(processes, process)
+		}
+	}
+	for _, process := range tagged {
+		if process.Members == nil {
+			add(process)
+		}
+		for _, member := range process.Members {
+			add(member)
+		}
+	}
+	return processes
+}
+
+// canControl returns whether or not the displayed processes are running on
+// this machine, and sets the status if they aren't.
+func (ui *UI) canControl() bool {
+	if ui.ReadOnly {
+		ui.SetStatus(tr("Processes of a recording or a remote machine can't be changed"))
+		return false
+	}
+	return true
+}
+
+// HandleSignal displays the screen to send a signal to the tagged processes,
+// or the selected one.
+func (ui *UI) HandleSignal() {
+	if ui.canControl() && len(ui.targets()) > 0 {
+		ui.screen = &signalScreen{}
+	}
+}
+
+// HandleRenice changes the nice value of the tagged processes, or the
+// selected one, by delta.
+func (ui *UI) HandleRenice(delta int) {
+	if !ui.canControl() {
+		return
+	}
+	targets := ui.targets()
+	var errs []error
+	for _, process := range targets {
+		if err := process.SetNice(process.Nice + delta); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", process, err))
+		}
+	}
+	ui.setActionStatus(tr("Reniced"), targets, errs)
+}
+
+// HandleOOMScoreAdj prompts for the adjustment of the OOM score of the
+// tagged processes, or the selected one, to protect them from the OOM killer
+// or to sacrifice them first.
+func (ui *UI) HandleOOMScoreAdj() {
+	if !ui.canControl() {
+		return
+	}
+	targets := ui.targets()
+	if len(targets) == 0 {
+		return
+	}
+	adj, err := targets[0].OOMScoreAdj()
+	if err != nil {
+		ui.SetStatus(fmt.Sprintf("%s: %v", targets[0], err))
+		return
+	}
+	label := fmt.Sprintf(tr("OOM score adjustment (%d never kills, %d kills first): "), proc.MinOOMScoreAdj, proc.MaxOOMScoreAdj)
+	ui.prompt = &prompt{label: label, text: []rune(strconv.Itoa(adj)), done: func(ui *UI, text string) {
+		adj, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			ui.SetStatus(fmt.Sprintf(tr("%q isn't a number"), text))
+			return
+		}
+		var errs []error
+		for _, process := range targets {
+			if err := process.SetOOMScoreAdj(adj); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", process, err))
+			}
+		}
+		ui.setActionStatus(fmt.Sprintf(tr("Set the OOM score adjustment to %d of"), adj), targets, errs)
+	}}
+}
+
+// HandleIOPriority prompts for the I/O scheduling class and level of the
+// tagged processes, or the selected one, like ionice.
+func (ui *UI) HandleIOPriority() {
+	if !ui.canControl() {
+		return
+	}
+	targets := ui.targets()
+	if len(targets) == 0 {
+		return
+	}
+	label := tr("I/O priority (rt/0-7, be/0-7, idle or none, 0 is the highest): ")
+	ui.prompt = &prompt{label: label, text: []rune(targets[0].IOPriority.String()), done: func(ui *UI, text string) {
+		prio, err := proc.ParseIOPriority(strings.TrimSpace(text))
+		if err != nil {
+			ui.SetStatus(err.Error())
+			return
+		}
+		var errs []error
+		for _, process := range targets {
+			if err := process.SetIOPriority(prio); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", process, err))
+			}
+		}
+		ui.setActionStatus(fmt.Sprintf(tr("Set the I/O priority to %s of"), prio), targets, errs)
+	}}
+}
+
+// HandleStop stops the tagged processes, or the selected one, with SIGSTOP,
+// or continues them with SIGCONT if they're all stopped already.
+func (ui *UI) HandleStop() {
+	if !ui.canControl() {
+		return
+	}
+	targets := ui.targets()
+	if len(targets) == 0 {
+		return
+	}
+	for _, process := range targets {
+		if process.State != 'T' && process.State != 't' {
+			ui.sendSignal("SIGSTOP", sigStop)
+			return
+		}
+	}
+	ui.sendSignal("SIGCONT", sigCont)
+}
+
+// HandleFreezeCgroup freezes the cgroups of the tagged processes, or of the
+// selected one, or thaws them if the first one is frozen already.
+func (ui *UI) HandleFreezeCgroup() {
+	if !ui.canControl() {
+		return
+	}
+	targets := ui.targets()
+	if len(targets) == 0 {
+		return
+	}
+	freeze := !targets[0].Frozen
+	var cgroups []string
+	seen := make(map[string]bool)
+	for _, process := range targets {
+		if !seen[process.Cgroup] {
+			seen[process.Cgroup] = true
+			cgroups = append(cgroups, process.Cgroup)
+		}
+	}
+
+	action := tr("Thawed")
+	if freeze {
+		action = tr("Froze")
+	}
+	for _, cgroup := range cgroups {
+		if err := ui.monitor.FreezeCgroup(cgroup, freeze); err != nil {
+			ui.SetStatus(fmt.Sprintf("%s: %v", cgroup, err))
+			return
+		}
+	}
+	if len(cgroups) == 1 {
+		ui.SetStatus(fmt.Sprintf("%s %s", action, cgroups[0]))
+	} else {
+		ui.SetStatus(fmt.Sprintf(tr("%s %d cgroups"), action, len(cgroups)))
+	}
+}
+
+func (ui *UI) sendSignal(name string, sig syscall.Signal) {
+	targets := ui.targets()
+	var errs []error
+	for _, process := range targets {
+		if err := process.Signal(sig); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", process, err))
+		}
+	}
+	ui.setActionStatus(fmt.Sprintf(tr("Sent %s to"), name), targets, errs)
+}
+
+// setActionStatus reports the result of acting on processes.
+func (ui *UI) setActionStatus(action string, targets []*proc.Process, errs []error) {
+	switch {
+	case len(errs) == 1 && len(targets) == 1:
+		ui.SetStatus(errs[0].Error())
+	case len(errs) > 0:
+		ui.SetStatus(fmt.Sprintf(tr("%s %d of %d processes, %s"), action, len(targets)-len(errs), len(targets), errs[0]))
+	case len(targets) == 1:
+		ui.SetStatus(fmt.Sprintf("%s %s", action, targets[0]))
+	default:
+		ui.SetStatus(fmt.Sprintf(tr("%s %d processes"), action, len(targets)))
+	}
+}
+
+// signalScreen is the screen used to choose the signal sent to processes.
+type signalScreen struct {
+	selected int
+}
+
+func (s *signalScreen) Draw(ui *UI) {
+	ui.y, ui.x = 0, 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	targets := ui.targets()
+	title := fmt.Sprintf(tr("Send signal to %d processes - enter: send, q: cancel"), len(targets))
+	if len(targets) == 1 {
+		title = fmt.Sprintf(tr("Send signal to %s - enter: send, q: cancel"), targets[0])
+	}
+	ui.writeLastColumn(title)
+	ui.y++
+
+	for i, signal := range Signals {
+		ui.x = 0
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+		if i == s.selected {
+			ui.fg, ui.bg = theme.SelectedFG, theme.SelectedBG
+		}
+		ui.writeLastColumn(fmt.Sprintf("%2d %s", int(signal.Signal), signal.Name))
+		ui.y++
+	}
+}
+
+func (s *signalScreen) HandleKey(ui *UI, ev termbox.Event) bool {
+	switch {
+	case ev.Ch == 'q' || ev.Key == termbox.KeyEsc:
+		return false
+	case ev.Ch == 'j' || ev.Key == termbox.KeyArrowDown:
+		if s.selected < len(Signals)-1 {
+			s.selected++
+		}
+	case ev.Ch == 'k' || ev.Key == termbox.KeyArrowUp:
+		if s.selected > 0 {
+			s.selected--
+		}
+	case ev.Key == termbox.KeyEnter:
+		signal := Signals[s.selected]
+		ui.sendSignal(signal.Name, signal.Signal)
+		return false
+	}
+	return true
+}
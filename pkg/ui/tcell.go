@@ -0,0 +1,225 @@
+//go:build tcell
+// +build tcell
+
+package ui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/nsf/termbox-go"
+)
+
+// The tcell Terminal is only built with the tcell build tag, since tcell
+// isn't vendored:
+//
+//	go build -tags tcell ./cmd/jtop
+//
+// It's then the default, and termbox is used when it can't be initialized.
+func init() {
+	Terminals["tcell"] = func() Terminal { return &tcellTerminal{} }
+	DefaultTerminal = "tcell"
+}
+
+// tcellTerminal draws with tcell, which unlike termbox supports bracketed
+// paste and reads the capabilities of the terminal from terminfo.
+type tcellTerminal struct {
+	screen tcell.Screen
+
+	// buttons are the mouse buttons that were pressed at the last mouse
+	// event, since tcell reports the buttons held down rather than
+	// clicks.
+	buttons tcell.ButtonMask
+
+	// pasting is set between the start and the end of a paste.
+	pasting bool
+}
+
+func (t *tcellTerminal) Init() error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	screen.EnableMouse()
+	screen.EnablePaste()
+	t.screen = screen
+	return nil
+}
+
+func (t *tcellTerminal) Close() {
+	if t.screen != nil {
+		t.screen.Fini()
+		t.screen = nil
+	}
+}
+
+func (t *tcellTerminal) Suspend() {
+	if t.screen != nil {
+		t.screen.Suspend()
+	}
+}
+
+func (t *tcellTerminal) Resume() error {
+	if t.screen == nil {
+		return t.Init()
+	}
+	return t.screen.Resume()
+}
+
+func (t *tcellTerminal) Size() (int, int) {
+	if t.screen == nil {
+		return 0, 0
+	}
+	return t.screen.Size()
+}
+
+func (t *tcellTerminal) Clear()             { t.screen.Clear() }
+func (t *tcellTerminal) SetCursor(x, y int) { t.screen.ShowCursor(x, y) }
+func (t *tcellTerminal) HideCursor()        { t.screen.HideCursor() }
+
+func (t *tcellTerminal) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	t.screen.SetContent(x, y, ch, nil, tcellStyle(fg, bg))
+}
+
+func (t *tcellTerminal) Flush() error {
+	t.screen.Show()
+	return nil
+}
+
+// tcellStyle returns the style of a cell drawn with termbox attributes. The
+// colors of termbox are the indexes of the palette plus one in both output
+// modes, and 0 is the default color.
+func tcellStyle(fg, bg termbox.Attribute) tcell.Style {
+	style := tcell.StyleDefault.
+		Foreground(tcellColor(fg)).
+		Background(tcellColor(bg))
+	attrs := fg | bg
+	if attrs&termbox.AttrBold != 0 {
+		style = style.Bold(true)
+	}
+	if attrs&termbox.AttrUnderline != 0 {
+		style = style.Underline(true)
+	}
+	if attrs&termbox.AttrReverse != 0 {
+		style = style.Reverse(true)
+	}
+	return style
+}
+
+func tcellColor(attr termbox.Attribute) tcell.Color {
+	color := attr &^ (termbox.AttrBold | termbox.AttrUnderline | termbox.AttrReverse)
+	if color == termbox.ColorDefault {
+		return tcell.ColorDefault
+	}
+	return tcell.PaletteColor(int(color) - 1)
+}
+
+// tcellKeys maps the keys of tcell that termbox has a different code for.
+// The control keys have the same codes in both.
+var tcellKeys = map[tcell.Key]termbox.Key{
+	tcell.KeyF1:     termbox.KeyF1,
+	tcell.KeyF2:     termbox.KeyF2,
+	tcell.KeyF3:     termbox.KeyF3,
+	tcell.KeyF4:     termbox.KeyF4,
+	tcell.KeyF5:     termbox.KeyF5,
+	tcell.KeyF6:     termbox.KeyF6,
+	tcell.KeyF7:     termbox.KeyF7,
+	tcell.KeyF8:     termbox.KeyF8,
+	tcell.KeyF9:     termbox.KeyF9,
+	tcell.KeyF10:    termbox.KeyF10,
+	tcell.KeyF11:    termbox.KeyF11,
+	tcell.KeyF12:    termbox.KeyF12,
+	tcell.KeyInsert: termbox.KeyInsert,
+	tcell.KeyDelete: termbox.KeyDelete,
+	tcell.KeyHome:   termbox.KeyHome,
+	tcell.KeyEnd:    termbox.KeyEnd,
+	tcell.KeyPgUp:   termbox.KeyPgup,
+	tcell.KeyPgDn:   termbox.KeyPgdn,
+	tcell.KeyUp:     termbox.KeyArrowUp,
+	tcell.KeyDown:   termbox.KeyArrowDown,
+	tcell.KeyLeft:   termbox.KeyArrowLeft,
+	tcell.KeyRight:  termbox.KeyArrowRight,
+}
+
+func (t *tcellTerminal) PollEvent() termbox.Event {
+	for {
+		screen := t.screen
+		if screen == nil {
+			return termbox.Event{Type: termbox.EventInterrupt}
+		}
+		if ev, ok := t.event(screen.PollEvent()); ok {
+			return ev
+		}
+	}
+}
+
+// event translates a tcell event, and returns false if it has no termbox
+// equivalent.
+func (t *tcellTerminal) event(ev tcell.Event) (termbox.Event, bool) {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		return t.keyEvent(ev)
+	case *tcell.EventMouse:
+		return t.mouseEvent(ev)
+	case *tcell.EventResize:
+		width, height := ev.Size()
+		return termbox.Event{Type: termbox.EventResize, Width: width, Height: height}, true
+	case *tcell.EventPaste:
+		t.pasting = ev.Start()
+	case nil:
+		// The screen was closed.
+		return termbox.Event{Type: termbox.EventInterrupt}, true
+	}
+	return termbox.Event{}, false
+}
+
+func (t *tcellTerminal) keyEvent(ev *tcell.EventKey) (termbox.Event, bool) {
+	e := termbox.Event{Type: termbox.EventKey}
+	if ev.Modifiers()&tcell.ModAlt != 0 {
+		e.Mod = termbox.ModAlt
+	}
+	switch key := ev.Key(); {
+	case key == tcell.KeyRune && ev.Rune() == ' ':
+		// termbox reports the space bar as a key without a rune.
+		e.Key = termbox.KeySpace
+	case key == tcell.KeyRune:
+		e.Ch = ev.Rune()
+	case t.pasting:
+		// Pasted text only types runes, so that a pasted newline
+		// doesn't submit a prompt or run a command.
+		return e, false
+	case key < 0x20 || key == tcell.KeyDEL:
+		e.Key = termbox.Key(key)
+	default:
+		k, ok := tcellKeys[key]
+		if !ok {
+			return e, false
+		}
+		e.Key = k
+	}
+	return e, true
+}
+
+func (t *tcellTerminal) mouseEvent(ev *tcell.EventMouse) (termbox.Event, bool) {
+	e := termbox.Event{Type: termbox.EventMouse}
+	e.MouseX, e.MouseY = ev.Position()
+	buttons := ev.Buttons()
+	pressed := buttons &^ t.buttons
+	t.buttons = buttons
+	switch {
+	case buttons&tcell.WheelUp != 0:
+		e.Key = termbox.MouseWheelUp
+	case buttons&tcell.WheelDown != 0:
+		e.Key = termbox.MouseWheelDown
+	case pressed&tcell.Button1 != 0:
+		e.Key = termbox.MouseLeft
+	case pressed&tcell.Button2 != 0:
+		e.Key = termbox.MouseRight
+	case pressed&tcell.Button3 != 0:
+		e.Key = termbox.MouseMiddle
+	default:
+		return e, false
+	}
+	return e, true
+}
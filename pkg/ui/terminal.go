@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Terminal is what the UI is drawn on and reads its events from. Every
+// implementation takes termbox's attributes and returns termbox's events,
+// which the rest of the UI is written against, translating them from and to
+// those of its library.
+type Terminal interface {
+	// Init initializes the terminal, with mouse support, and with 256
+	// colors if Supports256Colors.
+	Init() error
+	// Close restores the terminal. It does nothing if the terminal isn't
+	// initialized.
+	Close()
+
+	// Suspend restores the terminal for another program to use it, until
+	// Resume.
+	Suspend()
+	Resume() error
+
+	Size() (width, height int)
+	Clear()
+	SetCell(x, y int, ch rune, fg, bg termbox.Attribute)
+	SetCursor(x, y int)
+	HideCursor()
+	Flush() error
+
+	// PollEvent waits for an event.
+	PollEvent() termbox.Event
+}
+
+// Terminals contains the constructors of the Terminals jtop was built with,
+// by name.
+var Terminals = map[string]func() Terminal{
+	"termbox": func() Terminal { return termboxTerminal{} },
+}
+
+// DefaultTerminal is the name of the Terminal used when none is chosen,
+// which is tcell if jtop was built with it.
+var DefaultTerminal = "termbox"
+
+// TerminalNames returns the names of the Terminals, sorted.
+func TerminalNames() []string {
+	var names []string
+	for name := range Terminals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTerminal returns the Terminal with the passed in name.
+func NewTerminal(name string) (Terminal, error) {
+	newTerminal, ok := Terminals[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown terminal %s (%s)", name, strings.Join(TerminalNames(), ", "))
+	}
+	return newTerminal(), nil
+}
+
+// terminal is the Terminal the UI is currently drawn on.
+var terminal Terminal = termboxTerminal{}
+
+// SetTerminal sets the Terminal the UI is drawn on, termbox by default.
+func SetTerminal(t Terminal) {
+	terminal = t
+}
+
+// termboxTerminal draws with termbox-go.
+type termboxTerminal struct{}
+
+func (termboxTerminal) Init() error {
+	if err := termbox.Init(); err != nil {
+		return err
+	}
+	termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
+	if Supports256Colors() {
+		termbox.SetOutputMode(termbox.Output256)
+	}
+	return nil
+}
+
+func (termboxTerminal) Close() {
+	if termbox.IsInit {
+		termbox.Close()
+	}
+}
+
+// Suspend closes termbox, which can't leave the terminal otherwise, and
+// Resume initializes it again.
+func (t termboxTerminal) Suspend()      { t.Close() }
+func (t termboxTerminal) Resume() error { return t.Init() }
+
+func (termboxTerminal) Size() (int, int) { return termbox.Size() }
+func (termboxTerminal) Clear()           { termbox.Clear(termbox.ColorDefault, termbox.ColorDefault) }
+func (termboxTerminal) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(x, y, ch, fg, bg)
+}
+func (termboxTerminal) SetCursor(x, y int)       { termbox.SetCursor(x, y) }
+func (termboxTerminal) HideCursor()              { termbox.HideCursor() }
+func (termboxTerminal) Flush() error             { return termbox.Flush() }
+func (termboxTerminal) PollEvent() termbox.Event { return termbox.PollEvent() }
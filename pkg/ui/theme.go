@@ -0,0 +1,293 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Theme is the palette the UI is drawn with.
+type Theme struct {
+	TitleFG     termbox.Attribute
+	TitleBG     termbox.Attribute
+	TitleSortBG termbox.Attribute
+
+	SelectedFG termbox.Attribute
+	SelectedBG termbox.Attribute
+
+	StatusFG termbox.Attribute
+	StatusBG termbox.Attribute
+
+	// RunningFG is the color of the rows of running processes and TreeFG
+	// the color of the tree prefix of commands.
+	RunningFG termbox.Attribute
+	TreeFG    termbox.Attribute
+
+	// TaggedFG is the color of the rows of tagged processes.
+	TaggedFG termbox.Attribute
+
+	// KernelThreadFG is the color of the rows of kernel threads.
+	KernelThreadFG termbox.Attribute
+
+	// StoppedFG is the color of the rows of stopped processes, and of the
+	// processes of frozen cgroups.
+	StoppedFG termbox.Attribute
+
+	// ArgumentsFG is the color the arguments of highlighted command lines
+	// are dimmed to, unless it's the default color, see
+	// UI.HighlightProgram.
+	ArgumentsFG termbox.Attribute
+
+	// SpikeFG and SpikeBG are the colors of the rows of processes whose
+	// CPU usage just spiked.
+	SpikeFG termbox.Attribute
+	SpikeBG termbox.Attribute
+
+	// WarningFG and CriticalFG are the colors of header lines whose
+	// values are above their thresholds.
+	WarningFG  termbox.Attribute
+	CriticalFG termbox.Attribute
+
+	// AlertFG and AlertBG are the colors of the rows of alerting processes,
+	// and of the flashing title when there are some.
+	AlertFG termbox.Attribute
+	AlertBG termbox.Attribute
+
+	CPUGraphFG  termbox.Attribute
+	MemGraphFG  termbox.Attribute
+	SwapGraphFG termbox.Attribute
+
+	// Namespaces contains the colors of the namespace columns of processes
+	// that aren't in the namespaces of init, by inode number.
+	Namespaces []termbox.Attribute
+
+	// Gradient contains the colors of the %CPU and %MEM cells, from the
+	// lowest usage to the highest.
+	Gradient []termbox.Attribute
+
+	// Colors256 is used instead of the theme on terminals with 256
+	// colors, if it isn't nil.
+	Colors256 *Theme
+}
+
+// usageColor returns the color of a usage percentage, where 100% is the top
+// of the gradient.
+func (t *Theme) usageColor(percent float64) termbox.Attribute {
+	if len(t.Gradient) == 0 {
+		return termbox.ColorDefault
+	}
+	i := int(percent / 100 * float64(len(t.Gradient)))
+	if i < 0 {
+		i = 0
+	} else if i >= len(t.Gradient) {
+		i = len(t.Gradient) - 1
+	}
+	return t.Gradient[i]
+}
+
+// colorCube returns the attribute of a color of the 6x6x6 cube of the 256
+// color palette, where each component is between 0 and 5.
+func colorCube(r, g, b int) termbox.Attribute {
+	return color256(16 + 36*r + 6*g + b)
+}
+
+// greenYellowRed goes from green to yellow to red through the color cube.
+var greenYellowRed = []termbox.Attribute{
+	colorCube(0, 5, 0), colorCube(1, 5, 0), colorCube(2, 5, 0),
+	colorCube(3, 5, 0), colorCube(4, 5, 0), colorCube(5, 5, 0),
+	colorCube(5, 4, 0), colorCube(5, 3, 0), colorCube(5, 2, 0),
+	colorCube(5, 1, 0), colorCube(5, 0, 0),
+}
+
+// color256 returns the attribute of a color of the 256 color palette when
+// termbox is in Output256 mode.
+func color256(n int) termbox.Attribute {
+	return termbox.Attribute(n + 1)
+}
+
+var defaultTheme = &Theme{
+	TitleFG:     termbox.ColorBlack,
+	TitleBG:     termbox.ColorGreen,
+	TitleSortBG: termbox.ColorCyan,
+	SelectedFG:  termbox.ColorBlack,
+	SelectedBG:  termbox.ColorCyan,
+	StatusFG:    termbox.ColorWhite,
+	StatusBG:    termbox.ColorRed,
+	RunningFG:   termbox.ColorGreen,
+	TreeFG:      termbox.ColorBlack,
+	TaggedFG:    termbox.ColorYellow | termbox.AttrBold,
+	SpikeFG:     termbox.ColorBlack,
+	SpikeBG:     termbox.ColorYellow,
+	WarningFG:   termbox.ColorYellow,
+	CriticalFG:  termbox.ColorRed,
+	AlertFG:     termbox.ColorWhite | termbox.AttrBold,
+	AlertBG:     termbox.ColorRed,
+	CPUGraphFG:  termbox.ColorGreen,
+	MemGraphFG:  termbox.ColorYellow,
+	SwapGraphFG: termbox.ColorRed,
+
+	KernelThreadFG: termbox.ColorBlue,
+	StoppedFG:      termbox.ColorMagenta,
+	Namespaces: []termbox.Attribute{
+		termbox.ColorCyan, termbox.ColorMagenta, termbox.ColorYellow, termbox.ColorBlue, termbox.ColorGreen,
+	},
+	// Only high usage stands out with 8 colors.
+	Gradient: []termbox.Attribute{
+		termbox.ColorDefault, termbox.ColorDefault, termbox.ColorYellow, termbox.ColorRed,
+	},
+}
+
+func init() {
+	// The default theme only differs on 256 color terminals by its
+	// gradient, and by dimming arguments to gray.
+	t := *defaultTheme
+	t.Gradient = greenYellowRed
+	t.ArgumentsFG = color256(245)
+	defaultTheme.Colors256 = &t
+}
+
+// solarizedTheme uses the colors of Solarized, which terminals with a
+// Solarized palette display on 8 color terminals too.
+var solarizedTheme = &Theme{
+	TitleFG:     termbox.ColorBlack,
+	TitleBG:     termbox.ColorBlue,
+	TitleSortBG: termbox.ColorCyan,
+	SelectedFG:  termbox.ColorBlack,
+	SelectedBG:  termbox.ColorYellow,
+	StatusFG:    termbox.ColorWhite,
+	StatusBG:    termbox.ColorMagenta,
+	RunningFG:   termbox.ColorGreen,
+	TreeFG:      termbox.ColorBlue,
+	TaggedFG:    termbox.ColorYellow | termbox.AttrBold,
+	SpikeFG:     termbox.ColorBlack,
+	SpikeBG:     termbox.ColorRed,
+	WarningFG:   termbox.ColorYellow,
+	CriticalFG:  termbox.ColorRed,
+	AlertFG:     termbox.ColorWhite | termbox.AttrBold,
+	AlertBG:     termbox.ColorRed,
+	CPUGraphFG:  termbox.ColorBlue,
+	MemGraphFG:  termbox.ColorCyan,
+	SwapGraphFG: termbox.ColorMagenta,
+
+	KernelThreadFG: termbox.ColorCyan,
+	StoppedFG:      termbox.ColorMagenta,
+	Namespaces: []termbox.Attribute{
+		termbox.ColorCyan, termbox.ColorMagenta, termbox.ColorYellow, termbox.ColorBlue, termbox.ColorGreen,
+	},
+	Gradient: []termbox.Attribute{
+		termbox.ColorDefault, termbox.ColorDefault, termbox.ColorYellow, termbox.ColorRed,
+	},
+
+	// The closest colors of the 256 color palette.
+	Colors256: &Theme{
+		TitleFG:     color256(230),                    // base3
+		TitleBG:     color256(33),                     // blue
+		TitleSortBG: color256(37),                     // cyan
+		SelectedFG:  color256(234),                    // base03
+		SelectedBG:  color256(136),                    // yellow
+		StatusFG:    color256(230),                    // base3
+		StatusBG:    color256(125),                    // magenta
+		RunningFG:   color256(64),                     // green
+		TreeFG:      color256(240),                    // base01
+		TaggedFG:    color256(136) | termbox.AttrBold, // yellow
+		SpikeFG:     color256(230),                    // base3
+		SpikeBG:     color256(166),                    // orange
+		WarningFG:   color256(166),                    // orange
+		CriticalFG:  color256(160),                    // red
+		AlertFG:     color256(230) | termbox.AttrBold, // base3
+		AlertBG:     color256(160),                    // red
+		CPUGraphFG:  color256(33),                     // blue
+		MemGraphFG:  color256(37),                     // cyan
+		SwapGraphFG: color256(61),                     // violet
+
+		KernelThreadFG: color256(61),  // violet
+		StoppedFG:      color256(125), // magenta
+		ArgumentsFG:    color256(240), // base01
+		Namespaces: []termbox.Attribute{
+			color256(37), color256(125), color256(136), color256(33), color256(64), color256(61), // cyan, magenta, yellow, blue, green, violet
+		},
+		Gradient: []termbox.Attribute{
+			color256(64), color256(64), color256(136), color256(166), color256(160), // green, yellow, orange, red
+		},
+	},
+}
+
+// monochromeTheme only uses the default colors, with reverse video where
+// the other themes use a background color.
+var monochromeTheme = &Theme{
+	TitleFG:     termbox.AttrReverse,
+	TitleBG:     termbox.ColorDefault,
+	TitleSortBG: termbox.ColorDefault,
+	SelectedFG:  termbox.AttrReverse | termbox.AttrBold,
+	SelectedBG:  termbox.ColorDefault,
+	StatusFG:    termbox.AttrReverse | termbox.AttrBold,
+	StatusBG:    termbox.ColorDefault,
+	RunningFG:   termbox.AttrBold,
+	TreeFG:      termbox.ColorDefault,
+	TaggedFG:    termbox.AttrBold | termbox.AttrUnderline,
+	SpikeFG:     termbox.AttrUnderline | termbox.AttrBold,
+	SpikeBG:     termbox.ColorDefault,
+	WarningFG:   termbox.AttrBold,
+	CriticalFG:  termbox.AttrBold | termbox.AttrUnderline,
+	AlertFG:     termbox.AttrReverse | termbox.AttrUnderline,
+	AlertBG:     termbox.ColorDefault,
+	CPUGraphFG:  termbox.ColorDefault,
+	MemGraphFG:  termbox.ColorDefault,
+	SwapGraphFG: termbox.ColorDefault,
+	Namespaces:  []termbox.Attribute{termbox.AttrBold},
+
+	KernelThreadFG: termbox.ColorDefault,
+	StoppedFG:      termbox.AttrUnderline,
+	Gradient: []termbox.Attribute{
+		termbox.ColorDefault, termbox.ColorDefault, termbox.ColorDefault, termbox.AttrBold,
+	},
+}
+
+// Themes contains the themes that can be passed to SetTheme.
+var Themes = map[string]*Theme{
+	"default":    defaultTheme,
+	"solarized":  solarizedTheme,
+	"monochrome": monochromeTheme,
+}
+
+// theme is the Theme the UI is currently drawn with.
+var theme = defaultTheme
+
+// SetTheme sets the Theme the UI is drawn with. colors256 uses the 256 color
+// variant of the theme, which requires termbox to be in Output256 mode.
+func SetTheme(name string, colors256 bool) error {
+	t, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %s (%s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	if colors256 && t.Colors256 != nil {
+		t = t.Colors256
+	}
+	theme = t
+	return nil
+}
+
+// ThemeNames returns the names of the themes, sorted.
+func ThemeNames() []string {
+	var names []string
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Supports256Colors returns whether or not the terminal supports 256 colors,
+// according to $TERM and $COLORTERM.
+func Supports256Colors() bool {
+	return os.Getenv("COLORTERM") != "" || strings.Contains(os.Getenv("TERM"), "256color")
+}
+
+// NoColor returns whether or not the user asked for no colors by setting
+// $NO_COLOR, see https://no-color.org.
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
@@ -0,0 +1,1117 @@
+// Package ui displays the processes of a proc.Monitor in the terminal.
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+	"github.com/nsf/termbox-go"
+)
+
+const (
+	headerRows = 1
+
+	statusRows = 1
+
+	offsetStep = 5
+
+	frozenStatus = "Frozen (Z: resume)"
+	followStatus = "Following %d (f: stop)"
+
+	wheelStep = 3
+
+	// spikeThreshold is the rise in CPU percentage between two updates that
+	// highlights a row.
+	spikeThreshold = 25
+)
+
+type UI struct {
+	monitor *proc.Monitor
+	history *proc.SystemHistory
+
+	x int
+	y int
+
+	// offset is the number of cells the table is scrolled right by.
+	offset int
+
+	fg termbox.Attribute
+	bg termbox.Attribute
+
+	start    int
+	selected int
+
+	width  int
+	height int
+
+	// screen is displayed in place of the process table when set.
+	screen Screen
+
+	// search is the text the processes are searched for, see HandleSearch.
+	search string
+
+	// Command is what the COMMAND column displays of processes.
+	Command CommandMode
+
+	// HighlightProgram draws the program name of full command lines in
+	// bold and their arguments dimmed.
+	HighlightProgram bool
+
+	// programStart and programEnd are the indexes of the program name in
+	// the command being drawn, if it's highlighted.
+	programStart int
+	programEnd   int
+
+	// Wrap displays the commands that don't fit on several rows.
+	Wrap bool
+
+	// KeepColumns displays every column of Columns on narrow terminals,
+	// scrolling the ones that don't fit horizontally, rather than dropping
+	// the columns with the lowest priority.
+	KeepColumns bool
+
+	// showLog displays the log panel, see HandleToggleLog.
+	showLog bool
+
+	// status is displayed on the last row when set.
+	status string
+
+	// live is the Monitor being updated while the display is frozen, in
+	// which case monitor is a copy of it.
+	live *proc.Monitor
+
+	// follow is the Pid of the process the selection stays on as the list
+	// is updated and sorted, or 0.
+	follow uint64
+
+	// childrenOf is the Pid of the process whose children HandleNextChild
+	// cycles through, as long as child, the last one it selected, stays
+	// selected.
+	childrenOf uint64
+	child      uint64
+
+	// tagged contains the Pids of the tagged processes, which signals and
+	// nice values are applied to.
+	tagged map[uint64]bool
+
+	// baseline is the Snapshot marked to compare the processes with, see
+	// HandleMarkBaseline.
+	baseline *proc.Snapshot
+
+	// Alerter highlights the processes that alert, if it isn't nil.
+	Alerter *proc.Alerter
+
+	// prompt is the line of text being edited, if any.
+	prompt *prompt
+
+	// ReadOnly is set when the processes aren't running on this machine,
+	// so they can't be signaled or reniced.
+	ReadOnly bool
+
+	// startLayout is the layout jtop started with, which switching
+	// profiles starts from, and profile the name of the current Profile.
+	startLayout *layout
+	profile     string
+}
+
+func NewUI(monitor *proc.Monitor, history *proc.SystemHistory) *UI {
+	ui := &UI{
+		monitor: monitor,
+		history: history,
+	}
+	ui.width, ui.height = terminal.Size()
+	ui.startLayout = ui.currentLayout()
+	return ui
+}
+
+// SetMonitor changes the Monitor whose processes are displayed.
+func (ui *UI) SetMonitor(monitor *proc.Monitor) {
+	ui.live = nil
+	ui.baseline = nil
+	monitor.Tree = ui.monitor.Tree
+	monitor.SortKey = ui.monitor.SortKey
+	monitor.Reverse = ui.monitor.Reverse
+	monitor.KernelThreadsLast = ui.monitor.KernelThreadsLast
+	monitor.CPULifetime = ui.monitor.CPULifetime
+	monitor.CPUChildren = ui.monitor.CPUChildren
+	monitor.IRQs = ui.monitor.IRQs
+	monitor.Sort()
+	ui.monitor = monitor
+	ui.HandleSelectFirst()
+	ui.screen = nil
+}
+
+func (ui *UI) Draw() {
+	terminal.Clear()
+	if ui.screen != nil {
+		ui.screen.Draw(ui)
+		if ui.prompt != nil {
+			ui.drawStatus()
+		}
+		ui.drawCursor()
+		terminal.Flush()
+		return
+	}
+	ui.selectFollowed()
+	ui.pruneTagged()
+	ui.drawMeters()
+	ui.drawHeader()
+	ui.drawWatched()
+	for i, process := range ui.visibleProcesses() {
+		ui.drawProcess(i, process)
+	}
+	if ui.showLog {
+		ui.drawLog()
+	}
+	ui.drawStatus()
+	ui.drawCursor()
+	terminal.Flush()
+}
+
+// drawStatus draws the last row, which isn't scrolled with the table.
+func (ui *UI) drawStatus() {
+	offset := ui.offset
+	ui.offset = 0
+	defer func() { ui.offset = offset }()
+
+	status := ui.statusLine()
+	if status == "" {
+		ui.drawHelpBar()
+		return
+	}
+	ui.x, ui.y = 0, ui.height-statusRows
+	ui.fg, ui.bg = theme.StatusFG, theme.StatusBG
+	if ui.prompt != nil {
+		ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+	}
+	ui.writeLastColumn(status)
+}
+
+// statusLine returns the text of the last row, or "" to display the help bar.
+func (ui *UI) statusLine() string {
+	if ui.prompt != nil {
+		return ui.prompt.String()
+	}
+	var parts []string
+	if ui.live != nil {
+		parts = append(parts, tr(frozenStatus))
+	}
+	if ui.follow != 0 {
+		parts = append(parts, fmt.Sprintf(tr(followStatus), ui.follow))
+	}
+	if ui.search != "" {
+		parts = append(parts, ui.searchStatus())
+	}
+	if n := idleHidden(ui.monitor); n > 0 {
+		parts = append(parts, fmt.Sprintf(tr(idleStatus), n))
+	}
+	if alerts := ui.Alerter.Alerts(); len(alerts) == 1 {
+		parts = append(parts, fmt.Sprintf(tr("Alert: %s"), alerts[0]))
+	} else if len(alerts) > 1 {
+		parts = append(parts, fmt.Sprintf(tr("Alert: %s (%d more)"), alerts[0], len(alerts)-1))
+	}
+	if ui.status != "" {
+		parts = append(parts, ui.status)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// SetStatus sets the message displayed on the last row, such as an error
+// that occurred while updating. The help bar is displayed instead of an
+// empty message.
+func (ui *UI) SetStatus(status string) {
+	ui.status = status
+}
+
+func (ui *UI) drawMeters() {
+	ui.y = 0
+	ui.bg = termbox.ColorDefault
+	for _, line := range ui.meterLines() {
+		ui.x = 0
+		ui.fg = line.fg
+		ui.writeLastColumn(line.text)
+		ui.y++
+	}
+}
+
+func (ui *UI) drawHeader() {
+	ui.x = 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	// The title flashes every second while processes alert.
+	flash := len(ui.Alerter.Alerts()) > 0 && time.Now().Second()%2 == 0
+	if flash {
+		ui.fg, ui.bg = theme.AlertFG, theme.AlertBG
+	}
+
+	for _, column := range ui.columns() {
+		if !ui.monitor.Tree && !flash {
+			ui.bg = ui.bgForColumn(column)
+		}
+		ui.writeColumn(column.Title, column.Width, column.RightAlign)
+	}
+
+	if !flash {
+		ui.bg = theme.TitleBG
+	}
+	ui.writeLastColumn("")
+
+	ui.y++
+}
+
+func (ui *UI) drawProcess(i int, process *proc.Process) {
+	ui.x = 0
+	ui.fg, ui.bg = termbox.ColorDefault, termbox.ColorDefault
+	highlighted := true
+	switch {
+	case i == ui.selected:
+		ui.fg, ui.bg = theme.SelectedFG, theme.SelectedBG
+	case ui.tagged[process.Pid]:
+		ui.fg = theme.TaggedFG
+		highlighted = false
+	case ui.Alerter.Alerting(process.Pid):
+		ui.fg, ui.bg = theme.AlertFG, theme.AlertBG
+	case cpuSpiked(process):
+		ui.fg, ui.bg = theme.SpikeFG, theme.SpikeBG
+	case process.Stopped():
+		ui.fg = theme.StoppedFG
+		highlighted = false
+	case process.IsKernelThread():
+		ui.fg = theme.KernelThreadFG
+		highlighted = false
+	case process.State == 'R':
+		ui.fg = theme.RunningFG
+		highlighted = false
+	default:
+		highlighted = false
+	}
+
+	for _, column := range ui.columns() {
+		value := column.Value(ui.monitor, process)
+
+		switch column {
+		case CPUPercentColumn, MemPercentColumn:
+			tmpFG := ui.fg
+			if !highlighted {
+				ui.fg = theme.usageColor(ui.usagePercent(column, process))
+			}
+			ui.writeColumn(value, column.Width, column.RightAlign)
+			ui.fg = tmpFG
+		case PidNSColumn, NetNSColumn, MntNSColumn:
+			tmpFG := ui.fg
+			if !highlighted {
+				ui.fg = ui.namespaceColor(column, process)
+			}
+			ui.writeColumn(value, column.Width, column.RightAlign)
+			ui.fg = tmpFG
+		case FDColumn, CgroupMemColumn:
+			tmpFG := ui.fg
+			if !highlighted {
+				ui.fg = limitColor(column, process)
+			}
+			ui.writeColumn(value, column.Width, column.RightAlign)
+			ui.fg = tmpFG
+		case ThrottledColumn:
+			tmpFG := ui.fg
+			if !highlighted && process.CgroupThrottledDiff > 0 {
+				ui.fg = theme.WarningFG
+			}
+			ui.writeColumn(value, column.Width, column.RightAlign)
+			ui.fg = tmpFG
+		case CommandColumn:
+			command, prefix := ui.commandValue(process)
+			ui.programStart, ui.programEnd = 0, 0
+			if ui.HighlightProgram && !highlighted {
+				ui.programStart, ui.programEnd = ui.programSpan(process, command)
+			}
+			if ui.Wrap {
+				ui.writeWrappedCommand(command, prefix)
+			} else if ui.monitor.Tree || Aggregate != proc.Ungrouped {
+				ui.writeCommandWithPrefix(command, prefix)
+			} else {
+				ui.writeCommand(command, 0)
+			}
+		default:
+			ui.writeColumn(value, column.Width, column.RightAlign)
+		}
+	}
+
+	if !ColumnVisible(CommandColumn) {
+		ui.writeLastColumn("")
+	}
+
+	ui.y++
+}
+
+// limitColor returns the color of the FD or the CG_MEM column of a process,
+// depending on how close it is to the limit.
+func limitColor(column *Column, process *proc.Process) termbox.Attribute {
+	percent := process.FDPercent()
+	if column == CgroupMemColumn {
+		percent = process.CgroupMemoryPercent()
+	}
+	switch {
+	case percent >= LimitCritical:
+		return theme.CriticalFG
+	case percent >= LimitWarning:
+		return theme.WarningFG
+	}
+	return termbox.ColorDefault
+}
+
+// ScreenActive returns whether or not a Screen is displayed in place of the
+// process table.
+func (ui *UI) ScreenActive() bool {
+	return ui.screen != nil
+}
+
+// HandleScreenKey passes a key event to the displayed Screen.
+func (ui *UI) HandleScreenKey(ev termbox.Event) {
+	if !ui.screen.HandleKey(ui, ev) {
+		ui.screen = nil
+	}
+}
+
+func (ui *UI) HandleSetup() {
+	ui.screen = &setupScreen{}
+}
+
+// HandleDetails displays the details of the selected process.
+func (ui *UI) HandleDetails() {
+	process := ui.SelectedProcess()
+	switch {
+	case process == nil:
+	case process.Members != nil:
+		// The row of aggregated processes is expanded instead.
+		expandedGroups[process.Name] = !expandedGroups[process.Name]
+	default:
+		ui.screen = newDetailScreen(ui.monitor, process.Pid)
+	}
+}
+
+// HandleFiles displays the open files of the selected process.
+func (ui *UI) HandleFiles() {
+	if process := ui.SelectedProcess(); process != nil {
+		ui.screen = newFilesScreen(process.Pid)
+	}
+}
+
+// HandleCgroups displays the processes grouped by cgroup.
+func (ui *UI) HandleCgroups() {
+	ui.screen = newCgroupsScreen(ui.monitor)
+}
+
+// HandleUsers displays the usage of each user.
+func (ui *UI) HandleUsers() {
+	ui.screen = newUsersScreen(ui.monitor)
+}
+
+// HandleGraphs displays the graphs of the system history.
+func (ui *UI) HandleGraphs() {
+	ui.screen = &graphScreen{ui.history}
+}
+
+// HandleDashboard displays the summary of every remote machine, starting with
+// the current one. choose is called with the index of the remote machine
+// selected on the dashboard.
+func (ui *UI) HandleDashboard(remotes []*proc.Remote, current int, choose func(int)) {
+	if len(remotes) > 0 {
+		ui.screen = &dashboardScreen{remotes, choose, current}
+	}
+}
+
+// SelectedProcess returns the selected Process, or nil if there are no
+// processes.
+func (ui *UI) SelectedProcess() *proc.Process {
+	processes := ui.visibleProcesses()
+	if ui.selected < 0 || ui.selected >= len(processes) {
+		return nil
+	}
+	return processes[ui.selected]
+}
+
+// Frozen returns whether or not the display is frozen.
+func (ui *UI) Frozen() bool {
+	return ui.live != nil
+}
+
+// HandleFreeze freezes the display, or resumes it when it's frozen. While
+// frozen, a copy of the Monitor is displayed and the Monitor keeps being
+// updated, so the numbers are fresh once resumed.
+func (ui *UI) HandleFreeze() {
+	if ui.live == nil {
+		ui.live, ui.monitor = ui.monitor, ui.monitor.Clone()
+		return
+	}
+	live := ui.live
+	ui.live = nil
+	live.Tree = ui.monitor.Tree
+	live.SortKey = ui.monitor.SortKey
+	live.Reverse = ui.monitor.Reverse
+	live.KernelThreadsLast = ui.monitor.KernelThreadsLast
+	live.CPULifetime = ui.monitor.CPULifetime
+	live.CPUChildren = ui.monitor.CPUChildren
+	live.Sort()
+	ui.monitor = live
+}
+
+// HandleFollow keeps the selection on the selected process as the list is
+// updated and sorted, until the selection is moved or the process exits. If
+// a process is already followed, it stops following it.
+func (ui *UI) HandleFollow() {
+	if ui.follow != 0 {
+		ui.follow = 0
+		return
+	}
+	if process := ui.SelectedProcess(); process != nil {
+		ui.follow = process.Pid
+	}
+}
+
+// selectFollowed selects the followed process, scrolling to it if needed.
+func (ui *UI) selectFollowed() {
+	if ui.follow == 0 {
+		return
+	}
+	if !ui.selectPid(ui.follow) {
+		ui.follow = 0 // exited
+	}
+}
+
+// HandleSelectParent selects the parent of the selected process.
+func (ui *UI) HandleSelectParent() {
+	p := ui.SelectedProcess()
+	if p == nil {
+		return
+	}
+	if p.Ppid == 0 {
+		ui.SetStatus(fmt.Sprintf(tr("%d has no parent"), p.Pid))
+		return
+	}
+	ui.follow = 0
+	if !ui.selectPid(p.Ppid) {
+		ui.SetStatus(fmt.Sprintf(tr("The parent %d isn't displayed"), p.Ppid))
+	}
+}
+
+// HandleNextChild selects the first child of the selected process, and then
+// the next one every time it's called again, in the order they're displayed.
+// Once the selection is moved elsewhere, it starts again from the children of
+// the newly selected process. The children are found by their Ppid, since
+// the Parent and the Children of the processes are only kept up to date in
+// tree mode.
+func (ui *UI) HandleNextChild() {
+	p := ui.SelectedProcess()
+	if p == nil {
+		return
+	}
+	parent := p.Pid
+	if p.Pid == ui.child && p.Ppid == ui.childrenOf {
+		parent = p.Ppid
+	}
+
+	var children []*proc.Process
+	next := -1
+	for _, process := range processList(ui.monitor) {
+		if process.Ppid != parent || process.Pid == parent {
+			continue
+		}
+		if process == p {
+			next = len(children) + 1
+		}
+		children = append(children, process)
+	}
+	if len(children) == 0 {
+		ui.SetStatus(fmt.Sprintf(tr("%d has no displayed children"), parent))
+		return
+	}
+	if next < 0 || next == len(children) {
+		next = 0
+	}
+
+	ui.follow = 0
+	ui.childrenOf, ui.child = parent, children[next].Pid
+	ui.selectPid(ui.child)
+	ui.SetStatus(fmt.Sprintf(tr("Child %d/%d of %d"), next+1, len(children), parent))
+}
+
+// selectPid selects the process with the passed in Pid, and returns false
+// if it isn't displayed.
+func (ui *UI) selectPid(pid uint64) bool {
+	for i, process := range processList(ui.monitor) {
+		if process.Pid == pid {
+			ui.selectIndex(i)
+			return true
+		}
+	}
+	return false
+}
+
+// selectIndex selects the process at index i of the process list, scrolling
+// the table as little as possible to display it.
+func (ui *UI) selectIndex(i int) {
+	n := ui.numProcessesOnScreen()
+	if i < ui.start {
+		ui.start = i
+	} else if i >= ui.start+n {
+		ui.start = i - n + 1
+	}
+	ui.selected = i - ui.start
+}
+
+// HandleToggleTree shows or hides the process tree.
+func (ui *UI) HandleToggleTree() {
+	ui.monitor.Tree = !ui.monitor.Tree
+	ui.monitor.Sort()
+}
+
+// HandleToggleKernelThreadsLast lists the kernel threads after the other
+// processes, or sorts them together.
+func (ui *UI) HandleToggleKernelThreadsLast() {
+	ui.monitor.KernelThreadsLast = !ui.monitor.KernelThreadsLast
+	ui.monitor.Sort()
+}
+
+// HandleToggleCPULifetime switches %CPU between the usage since the last
+// update and the average usage since the processes started.
+func (ui *UI) HandleToggleCPULifetime() {
+	ui.monitor.CPULifetime = !ui.monitor.CPULifetime
+	ui.monitor.Sort()
+	if ui.monitor.CPULifetime {
+		ui.SetStatus(tr("%CPU: average since the processes started"))
+	} else {
+		ui.SetStatus(tr("%CPU: usage since the last update"))
+	}
+}
+
+// HandleToggleCPUChildren includes the CPU time of the children that exited
+// in %CPU, or excludes it.
+func (ui *UI) HandleToggleCPUChildren() {
+	ui.monitor.CPUChildren = !ui.monitor.CPUChildren
+	ui.monitor.Sort()
+	if ui.monitor.CPUChildren {
+		ui.SetStatus(tr("%CPU: including the children that exited"))
+	} else {
+		ui.SetStatus(tr("%CPU: excluding the children that exited"))
+	}
+}
+
+// HandleToggleAggregate groups the processes by name or by unit into a single
+// row, or ungroups them if they're already grouped that way.
+func (ui *UI) HandleToggleAggregate(by proc.Grouping) {
+	if Aggregate == by {
+		Aggregate = proc.Ungrouped
+	} else {
+		Aggregate = by
+	}
+	ui.HandleSelectFirst()
+}
+
+// HandleToggleSection shows or hides a section of the header.
+func (ui *UI) HandleToggleSection(section *HeaderSection) {
+	section.Enabled = !section.Enabled
+}
+
+func (ui *UI) HandleResize(width, height int) {
+	ui.width, ui.height = width, height
+}
+
+func (ui *UI) HandleDown() {
+	ui.follow = 0
+	if ui.shouldScrollDown() {
+		ui.scrollDown()
+		return
+	}
+
+	if !ui.bottomSelected() {
+		ui.down()
+	}
+}
+
+func (ui *UI) HandleUp() {
+	ui.follow = 0
+	if ui.shouldScrollUp() {
+		ui.scrollUp()
+		return
+	}
+
+	if !ui.topSelected() {
+		ui.up()
+	}
+}
+
+func (ui *UI) HandleResetOffset() {
+	ui.offset = 0
+}
+
+func (ui *UI) HandleSelectFirst() {
+	ui.follow = 0
+	ui.start = 0
+	ui.selected = 0
+}
+
+func (ui *UI) HandleSelectLast() {
+	ui.follow = 0
+	nProcs := len(processList(ui.monitor))
+	nProcsOnScreen := ui.numProcessesOnScreen()
+	if nProcs < nProcsOnScreen {
+		ui.start = 0
+		ui.selected = nProcs - 1
+	} else {
+		ui.start = nProcs - ui.numProcessesOnScreen()
+		ui.selected = nProcsOnScreen - 1
+	}
+}
+
+func (ui *UI) HandleCtrlD() {
+	halfPage := ui.numProcessesOnScreen() / 2
+	for i := 0; i < halfPage; i++ {
+		ui.HandleDown()
+	}
+}
+
+func (ui *UI) HandleCtrlU() {
+	halfPage := ui.numProcessesOnScreen() / 2
+	for i := 0; i < halfPage; i++ {
+		ui.HandleUp()
+	}
+}
+
+// HandleClick selects the process that was clicked on, or sorts by the column
+// whose title was clicked on. Clicking the title of the sort column reverses
+// the sort order.
+func (ui *UI) HandleClick(x, y int) {
+	titleRow := len(ui.meterLines())
+	switch {
+	case y == titleRow:
+		column := ui.columnAt(x)
+		if column == nil || column.Sort == proc.SortByNone {
+			return
+		}
+		if column.Sort == ui.monitor.SortKey {
+			ui.monitor.Reverse = !ui.monitor.Reverse
+		} else {
+			ui.monitor.SortKey = column.Sort
+			ui.monitor.Reverse = false
+		}
+		ui.monitor.Sort()
+	case y > titleRow:
+		row := y - titleRow - headerRows
+		watchRows := ui.watchRows()
+		if row < watchRows {
+			// Clicking a watched process keeps the selection on it.
+			if process := ui.watchedAt(row); process != nil {
+				ui.follow = process.Pid
+			}
+			return
+		}
+		row -= watchRows
+		for i, process := range ui.visibleProcesses() {
+			if row -= ui.processRows(process); row < 0 {
+				ui.follow = 0
+				ui.selected = i
+				break
+			}
+		}
+	}
+}
+
+// HandleReverseSort reverses the sort order.
+func (ui *UI) HandleReverseSort() {
+	ui.monitor.Reverse = !ui.monitor.Reverse
+	ui.monitor.Sort()
+}
+
+// HandleSortLeft sorts by the visible column to the left of the sort column.
+func (ui *UI) HandleSortLeft() {
+	ui.moveSortColumn(-1)
+}
+
+// HandleSortRight sorts by the visible column to the right of the sort
+// column.
+func (ui *UI) HandleSortRight() {
+	ui.moveSortColumn(1)
+}
+
+func (ui *UI) moveSortColumn(delta int) {
+	n := len(Columns)
+	if n == 0 {
+		return
+	}
+
+	// Moving left starts from the first column sorting by the sort key,
+	// and moving right from the last one, so the columns sharing a key
+	// like RSS and %MEM are a single step.
+	i := -1
+	for j, column := range Columns {
+		if column.Sort == ui.monitor.SortKey && (i == -1 || delta > 0) {
+			i = j
+		}
+	}
+
+	switch {
+	case i == -1 && delta > 0:
+		// The sort column isn't visible.
+		i = 0
+	case i == -1:
+		i = n - 1
+	default:
+		for step := 0; step < n && Columns[i].Sort == ui.monitor.SortKey; step++ {
+			i = (i + delta + n) % n
+		}
+	}
+
+	ui.monitor.SortKey = Columns[i].Sort
+	ui.monitor.Sort()
+}
+
+func (ui *UI) HandleWheelDown() {
+	for i := 0; i < wheelStep && ui.moreProcessesDown(); i++ {
+		ui.scrollDown()
+	}
+}
+
+func (ui *UI) HandleWheelUp() {
+	for i := 0; i < wheelStep && ui.moreProcessesUp(); i++ {
+		ui.scrollUp()
+	}
+}
+
+// columnAt returns the column displayed at the screen position x.
+func (ui *UI) columnAt(x int) *Column {
+	x += ui.offset
+	start := 0
+	for _, column := range ui.columns() {
+		if column.Width < 0 {
+			return column
+		}
+		end := start + columnWidth(column) + 1 // separating space
+		if x >= start && x < end {
+			return column
+		}
+		start = end
+	}
+	return nil
+}
+
+func (ui *UI) down() {
+	ui.selected++
+}
+
+func (ui *UI) up() {
+	ui.selected--
+}
+
+func (ui *UI) scrollDown() {
+	ui.start++
+}
+
+func (ui *UI) scrollUp() {
+	ui.start--
+}
+
+func (ui *UI) shouldScrollDown() bool {
+	return ui.bottomSelected() && ui.moreProcessesDown()
+}
+
+func (ui *UI) shouldScrollUp() bool {
+	return ui.topSelected() && ui.moreProcessesUp()
+}
+
+func (ui *UI) bottomSelected() bool {
+	n := len(processList(ui.monitor))
+	bottom := n - 1
+	if n > ui.numProcessesOnScreen() {
+		// Not all processes fit on the same screen
+		bottom = ui.numProcessesOnScreen() - 1
+	}
+	return ui.selected == bottom
+}
+
+func (ui *UI) topSelected() bool {
+	return ui.selected == 0
+}
+
+func (ui *UI) moreProcessesDown() bool {
+	return len(processList(ui.monitor))-ui.start > ui.numProcessesOnScreen()
+}
+
+func (ui *UI) moreProcessesUp() bool {
+	return ui.start > 0
+}
+
+func (ui *UI) numProcessesOnScreen() int {
+	// The last row is the status, or the help bar, below the log panel.
+	rows := ui.tableEnd() - headerRows - len(ui.meterLines()) - ui.watchRows()
+	if !ui.Wrap {
+		return rows
+	}
+
+	// Processes can take several rows, and at least one is displayed.
+	list := processList(ui.monitor)
+	n := 0
+	for i := ui.start; i < len(list); i++ {
+		if rows -= ui.processRows(list[i]); rows < 0 {
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+func (ui *UI) visibleProcesses() []*proc.Process {
+	list := processList(ui.monitor)
+
+	// When processes take several rows, the table is scrolled down until the
+	// selected one fits.
+	for ui.Wrap && ui.selected > 0 && ui.selected >= ui.numProcessesOnScreen() {
+		ui.start++
+		ui.selected--
+	}
+
+	// Maybe all processes will fit on the same screen
+	end := len(list)
+
+	// Maybe they won't
+	if end > ui.numProcessesOnScreen() {
+		end = ui.start + ui.numProcessesOnScreen()
+
+		// Maybe we need to scroll up because some process(es) died
+		if end > len(list) {
+			diff := end - len(list)
+			ui.start -= diff
+			end -= diff
+		}
+	} else {
+		// Maybe they do now that some died or were filtered out
+		ui.start = 0
+	}
+
+	// When bottom process is selected and a process dies, update selected
+	// to the new bottom process.
+	if ui.selected >= end-ui.start {
+		ui.selected = end - ui.start - 1
+	}
+
+	return list[ui.start:end]
+}
+
+// Filter hides the processes that don't match it, unless it's nil.
+var Filter *proc.Filter
+
+// CommandPattern hides the processes whose command line doesn't match it,
+// unless it's nil or CommandPatternDisabled is set.
+var (
+	CommandPattern         *regexp.Regexp
+	CommandPatternDisabled bool
+)
+
+// matchesFilters returns whether or not a process matches the Filter and the
+// CommandPattern.
+func matchesFilters(m *proc.Monitor, p *proc.Process) bool {
+	if CommandPattern != nil && !CommandPatternDisabled && !CommandPattern.MatchString(p.Command) {
+		return false
+	}
+	return Filter == nil || Filter.Match(m, p)
+}
+
+// Aggregate displays a single row for the processes with the same Name or
+// Unit, see proc.Monitor.Aggregate, followed by the processes when it's
+// expanded.
+var Aggregate proc.Grouping
+
+// expandedGroups contains the Names of the expanded rows of Aggregate.
+var expandedGroups = make(map[string]bool)
+
+// processList returns the processes of a Monitor that match the Filter, in
+// the displayed order.
+func processList(m *proc.Monitor) []*proc.Process {
+	if Aggregate != proc.Ungrouped {
+		return aggregatedList(m)
+	}
+	if !m.Tree {
+		return filterProcesses(m, m.List)
+	}
+	var treeList []*proc.Process
+	if init, ok := m.Map[proc.InitPid]; ok {
+		treeList = init.TreeList(0)
+	}
+	if kthreadd, ok := m.Map[proc.KthreaddPid]; ok && m.KernelThreads {
+		treeList = append(treeList, kthreadd.TreeList(0)...)
+	}
+	return filterProcesses(m, treeList)
+}
+
+// aggregatedList returns the aggregated processes of a Monitor that match the
+// Filter, each followed by its Members if it's expanded.
+func aggregatedList(m *proc.Monitor) []*proc.Process {
+	var list []*proc.Process
+	for _, p := range m.Aggregate(filterProcesses(m, m.List), Aggregate) {
+		p.TreePrefix = ""
+		list = append(list, p)
+		if !expandedGroups[p.Name] {
+			continue
+		}
+		for i, member := range p.Members {
+			member.TreePrefix = "├─ "
+			if i == len(p.Members)-1 {
+				member.TreePrefix = "└─ "
+			}
+			list = append(list, member)
+		}
+	}
+	return list
+}
+
+// filterProcesses returns the processes that match the Filter and the
+// CommandPattern, and that aren't idle if HideIdle is set. In the tree, the
+// ancestors of matching processes are kept too.
+func filterProcesses(m *proc.Monitor, list []*proc.Process) []*proc.Process {
+	return filterList(m, list, HideIdle)
+}
+
+func filterList(m *proc.Monitor, list []*proc.Process, hideIdle bool) []*proc.Process {
+	if Filter == nil && (CommandPattern == nil || CommandPatternDisabled) && !hideIdle {
+		return list
+	}
+	keep := make(map[*proc.Process]bool)
+	for _, p := range list {
+		if !matchesFilters(m, p) || (hideIdle && idle(m, p)) {
+			continue
+		}
+		keep[p] = true
+		if m.Tree && Aggregate == proc.Ungrouped {
+			for parent := p.Parent; parent != nil && !keep[parent]; parent = parent.Parent {
+				keep[parent] = true
+			}
+		}
+	}
+	var filtered []*proc.Process
+	for _, p := range list {
+		if keep[p] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+func (ui *UI) writeColumn(s string, columnWidth int, rightAlign bool) {
+	sWidth := stringWidth(s)
+	if sWidth > columnWidth && !rightAlign {
+		// Numbers are never truncated, text would shift the next columns.
+		s = runewidth.Truncate(s, columnWidth, "+")
+		sWidth = stringWidth(s)
+	}
+	if rightAlign {
+		for i := 0; i < columnWidth-sWidth; i++ {
+			ui.setCell(' ')
+		}
+	}
+
+	for _, ch := range s {
+		ui.setCell(ch)
+	}
+
+	if !rightAlign {
+		for i := 0; i < columnWidth-sWidth; i++ {
+			ui.setCell(' ')
+		}
+	}
+
+	ui.setCell(' ')
+}
+
+func (ui *UI) writeLastColumn(s string) {
+	for _, ch := range s {
+		ui.setCell(ch)
+	}
+
+	for ui.x < ui.width {
+		ui.setCell(' ')
+	}
+}
+
+func (ui *UI) writeCommandWithPrefix(command, prefix string) {
+	previous := ui.fg
+
+	ui.fg = theme.TreeFG
+	for _, ch := range prefix {
+		ui.setCell(ch)
+	}
+
+	ui.fg = previous
+	ui.writeCommand(command, 0)
+}
+
+// setCell draws a rune on as many cells as runeWidth returns.
+func (ui *UI) setCell(ch rune) {
+	width := runeWidth(ch)
+	switch {
+	case width == 0:
+		return
+	case unicode.IsControl(ch):
+		ch = '?'
+	case width == 2 && ui.x-ui.offset == ui.width-1:
+		// Half of the rune would be cut off by the edge of the screen.
+		ch = ' '
+	}
+	terminal.SetCell(ui.x-ui.offset, ui.y, ch, ui.fg, ui.bg)
+	ui.x += width
+}
+
+// cpuSpiked returns whether or not the CPU usage of a process rose by at
+// least spikeThreshold since the previous update, in which case its row is
+// highlighted until the next one.
+func cpuSpiked(p *proc.Process) bool {
+	if p.CPUHistory == nil {
+		return false
+	}
+	values := p.CPUHistory.Values()
+	n := len(values)
+	return n >= 2 && values[n-1]-values[n-2] >= spikeThreshold
+}
+
+// usagePercent returns the value of the %CPU or %MEM column of a process,
+// where 100% is one full CPU or all the memory.
+func (ui *UI) usagePercent(column *Column, p *proc.Process) float64 {
+	if column == CPUPercentColumn {
+		return ui.monitor.CPUPercent(p)
+	}
+	if ui.monitor.MemTotal == 0 {
+		return 0
+	}
+	return 100 * float64(p.RSS*ui.monitor.PageSize) / float64(ui.monitor.MemTotal)
+}
+
+// namespaceColor returns the color of a namespace column of a process, which
+// is the same for the processes sharing the namespace, unless it's a
+// namespace of init.
+func (ui *UI) namespaceColor(column *Column, p *proc.Process) termbox.Attribute {
+	i := 0
+	switch column {
+	case NetNSColumn:
+		i = 1
+	case MntNSColumn:
+		i = 2
+	}
+	inode := p.Namespaces[i]
+	init, ok := ui.monitor.Map[proc.InitPid]
+	if inode == 0 || len(theme.Namespaces) == 0 || ok && init.Namespaces[i] == inode {
+		return ui.fg
+	}
+	return theme.Namespaces[inode%uint64(len(theme.Namespaces))]
+}
+
+func (ui *UI) bgForColumn(column *Column) termbox.Attribute {
+	if column.Sort == ui.monitor.SortKey {
+		return theme.TitleSortBG
+	}
+	return theme.TitleBG
+}
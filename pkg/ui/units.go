@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// Units are the units sizes are displayed in.
+type Units int
+
+const (
+	// ShortUnits are powers of 1024 with a single letter, like 12K or
+	// 3.4M.
+	ShortUnits Units = iota
+	// BinaryUnits are powers of 1024 with IEC prefixes, like 12KiB or
+	// 3.4MiB.
+	BinaryUnits
+	// DecimalUnits are powers of 1000 with SI prefixes, like 12kB or
+	// 3.6MB.
+	DecimalUnits
+)
+
+var unitsNames = map[string]Units{
+	"short":   ShortUnits,
+	"binary":  BinaryUnits,
+	"decimal": DecimalUnits,
+}
+
+// sizeUnit is the size of a unit in bytes, and its suffix.
+type sizeUnit struct {
+	size   uint64
+	suffix string
+}
+
+var sizeUnits = map[Units][]sizeUnit{
+	ShortUnits: {
+		{proc.PB, "P"}, {proc.TB, "T"}, {proc.GB, "G"}, {proc.MB, "M"}, {proc.KB, "K"},
+	},
+	BinaryUnits: {
+		{proc.PB, "PiB"}, {proc.TB, "TiB"}, {proc.GB, "GiB"}, {proc.MB, "MiB"}, {proc.KB, "KiB"},
+	},
+	DecimalUnits: {
+		{1e15, "PB"}, {1e12, "TB"}, {1e9, "GB"}, {1e6, "MB"}, {1e3, "kB"},
+	},
+}
+
+var (
+	// SizeUnits are the Units sizes are displayed in, see SetUnits.
+	SizeUnits = ShortUnits
+
+	// ThousandsSeparator separates the groups of three digits of PIDs and
+	// counters, unless it's "".
+	ThousandsSeparator string
+
+	// sizeWidth is the width sizes are padded to in the header.
+	sizeWidth = 6
+)
+
+// UnitsNames returns the names of the Units that can be passed to SetUnits,
+// sorted.
+func UnitsNames() []string {
+	var names []string
+	for name := range unitsNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetUnits sets the units sizes are displayed in, and widens the columns of
+// sizes to fit their suffix.
+func SetUnits(name string) error {
+	units, ok := unitsNames[name]
+	if !ok {
+		return fmt.Errorf("unknown units %s (%s)", name, strings.Join(UnitsNames(), ", "))
+	}
+	extra := len(sizeUnits[units][0].suffix) - len(sizeUnits[SizeUnits][0].suffix)
+	for _, column := range []*Column{RSSColumn, SwapColumn, GPUMemoryColumn} {
+		column.Width += extra
+	}
+	sizeWidth += extra
+	SizeUnits = units
+	return nil
+}
+
+// SetThousandsSeparator separates the thousands of PIDs and counters like
+// the locale of $LC_ALL, $LC_NUMERIC or $LANG does, and widens the PID
+// column to fit.
+func SetThousandsSeparator() {
+	ThousandsSeparator = localeThousandsSeparator()
+	PidColumn.Width += 2
+}
+
+// localeThousandsSeparators contains the thousands separators of the
+// languages that don't use a comma, by language code.
+var localeThousandsSeparators = map[string]string{
+	"de": ".", "da": ".", "es": ".", "id": ".", "it": ".", "nl": ".", "pt": ".", "tr": ".",
+	"cs": " ", "fi": " ", "fr": " ", "nb": " ", "pl": " ", "ru": " ", "sk": " ", "sv": " ", "uk": " ",
+	"de_CH": "'",
+}
+
+func localeThousandsSeparator() string {
+	locale := ""
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if locale = os.Getenv(name); locale != "" {
+			break
+		}
+	}
+	locale = trimLocale(locale)
+	if separator, ok := localeThousandsSeparators[locale]; ok {
+		return separator
+	}
+	if separator, ok := localeThousandsSeparators[strings.SplitN(locale, "_", 2)[0]]; ok {
+		return separator
+	}
+	return ","
+}
+
+// formatThousands formats a number with the ThousandsSeparator.
+func formatThousands(n uint64) string {
+	s := strconv.FormatUint(n, 10)
+	if ThousandsSeparator == "" {
+		return s
+	}
+	var sb strings.Builder
+	for i, ch := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			sb.WriteString(ThousandsSeparator)
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
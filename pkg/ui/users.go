@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// newUsersScreen returns a Screen that displays the usage of each user,
+// along with their busiest process.
+func newUsersScreen(m *proc.Monitor) Screen {
+	return &textScreen{
+		title: tr("Processes by user - q: back"),
+		lines: func() []string { return userLines(m) },
+	}
+}
+
+// userUsage is the usage of the processes of a user.
+type userUsage struct {
+	name      string
+	cpu       float64
+	rss       uint64
+	processes int
+	top       *proc.Process
+	topCPU    float64
+}
+
+// byUserCPU sorts users by CPU usage, then RSS, from the highest.
+type byUserCPU []*userUsage
+
+func (u byUserCPU) Len() int      { return len(u) }
+func (u byUserCPU) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+func (u byUserCPU) Less(i, j int) bool {
+	if u[i].cpu != u[j].cpu {
+		return u[i].cpu > u[j].cpu
+	}
+	return u[i].rss > u[j].rss
+}
+
+func userLines(m *proc.Monitor) []string {
+	byUID := make(map[string]*userUsage)
+	var usages []*userUsage
+	for _, p := range m.List {
+		u, ok := byUID[p.User.Uid]
+		if !ok {
+			u = &userUsage{name: p.User.Username}
+			byUID[p.User.Uid] = u
+			usages = append(usages, u)
+		}
+		cpu := m.CPUPercent(p)
+		u.cpu += cpu
+		u.rss += p.RSS * m.PageSize
+		u.processes++
+		if u.top == nil || cpu > u.topCPU {
+			u.top, u.topCPU = p, cpu
+		}
+	}
+	sort.Stable(byUserCPU(usages))
+
+	lines := []string{fmt.Sprintf("%6s %6s %6s  %s  %s", "%CPU", "RSS", "PROCS", padRight("USER", userColumnWidth), "TOP PROCESS")}
+	for _, u := range usages {
+		name := padRight(runewidth.Truncate(u.name, userColumnWidth, "+"), userColumnWidth)
+		lines = append(lines, fmt.Sprintf("%6.1f %6s %6d  %s  %s (%d, %.1f%%)",
+			u.cpu, formatBytes(u.rss), u.processes, name, u.top.Name, u.top.Pid, u.topCPU))
+	}
+	return lines
+}
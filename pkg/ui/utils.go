@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// formatBytes returns a short human readable representation of a number of
+// bytes in the SizeUnits, e.g. "512", "12K", "3.4M".
+func formatBytes(b uint64) string {
+	for _, unit := range sizeUnits[SizeUnits] {
+		if b >= unit.size {
+			value := float64(b) / float64(unit.size)
+			if value < 10 {
+				return fmt.Sprintf("%.1f%s", value, unit.suffix)
+			}
+			return fmt.Sprintf("%d%s", b/unit.size, unit.suffix)
+		}
+	}
+	return strconv.FormatUint(b, 10)
+}
+
+// formatCount returns a short human readable representation of a number of
+// events, e.g. "7", "12k", "3.4M".
+func formatCount(n float64) string {
+	units := []struct {
+		size   float64
+		suffix string
+	}{
+		{1e9, "G"},
+		{1e6, "M"},
+		{1e3, "k"},
+	}
+	for _, unit := range units {
+		if n >= unit.size {
+			value := n / unit.size
+			if value < 10 {
+				return fmt.Sprintf("%.1f%s", value, unit.suffix)
+			}
+			return fmt.Sprintf("%.0f%s", value, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%.0f", n)
+}
+
+// runeWidth returns the number of cells a rune is drawn on by setCell:
+// control characters are drawn as ?, and combining characters, which termbox
+// can't draw, aren't drawn.
+func runeWidth(ch rune) int {
+	if unicode.IsControl(ch) {
+		return 1
+	}
+	return runewidth.RuneWidth(ch)
+}
+
+// stringWidth returns the number of cells a string is drawn on by setCell.
+func stringWidth(s string) int {
+	width := 0
+	for _, ch := range s {
+		width += runeWidth(ch)
+	}
+	return width
+}
+
+func padLeft(s string, width int) string {
+	if n := width - stringWidth(s); n > 0 {
+		return strings.Repeat(" ", n) + s
+	}
+	return s
+}
+
+func padRight(s string, width int) string {
+	if n := width - stringWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
+
+// sparkline renders values as a line of block characters scaled so max is
+// a full block.
+func sparkline(values []float64, max float64) string {
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > 0 {
+			i = int(v / max * float64(len(sparkBlocks)-1))
+		}
+		if i < 0 {
+			i = 0
+		} else if i >= len(sparkBlocks) {
+			i = len(sparkBlocks) - 1
+		}
+		sb.WriteRune(sparkBlocks[i])
+	}
+	return sb.String()
+}
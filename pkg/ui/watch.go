@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// watchMaxRows is the number of watched processes displayed above the table
+// at most, so they don't push the rest of the processes off the screen.
+const watchMaxRows = 5
+
+// The watched processes are pinned above the process table whatever the sort
+// order and the filters, see HandleWatch and HandleWatchCommand. PIDs can be
+// reused by other processes after a reboot, so command patterns are better
+// suited to services.
+var (
+	WatchPids     = make(map[uint64]bool)
+	WatchCommands []*regexp.Regexp
+
+	// WatchFile is where the watched PIDs and commands are saved when
+	// they change, unless it's empty.
+	WatchFile string
+)
+
+// ReadWatchFile adds the PIDs and the command patterns of a file to the
+// watched processes. Its lines are "pid <PID>" or "command <regexp>", and
+// the ones starting with # are comments.
+func ReadWatchFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) < 2 {
+			return fmt.Errorf("%s:%d: missing PID or pattern", path, n)
+		}
+		value := strings.TrimSpace(fields[1])
+		switch fields[0] {
+		case "pid":
+			pid, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s:%d: invalid PID %s", path, n, value)
+			}
+			WatchPids[pid] = true
+		case "command":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %s", path, n, err)
+			}
+			WatchCommands = append(WatchCommands, re)
+		default:
+			return fmt.Errorf("%s:%d: unknown entry %s (pid or command)", path, n, fields[0])
+		}
+	}
+	return scanner.Err()
+}
+
+// saveWatchFile writes the watched PIDs and commands to the WatchFile, in the
+// format of ReadWatchFile.
+func saveWatchFile() error {
+	if WatchFile == "" {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("# Processes pinned above the table by jtop (J and Ctrl-W)\n")
+	var pids []uint64
+	for pid := range WatchPids {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	for _, pid := range pids {
+		fmt.Fprintf(&b, "pid %d\n", pid)
+	}
+	for _, re := range WatchCommands {
+		fmt.Fprintf(&b, "command %s\n", re)
+	}
+	if err := os.MkdirAll(filepath.Dir(WatchFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(WatchFile, []byte(b.String()), 0644)
+}
+
+// watching returns whether or not a process is watched.
+func watching(p *proc.Process) bool {
+	if WatchPids[p.Pid] {
+		return true
+	}
+	for _, re := range WatchCommands {
+		if re.MatchString(p.Command) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedProcesses returns the watched processes of a Monitor, in the sort
+// order.
+func watchedProcesses(m *proc.Monitor) []*proc.Process {
+	if len(WatchPids) == 0 && len(WatchCommands) == 0 {
+		return nil
+	}
+	var watched []*proc.Process
+	for _, p := range m.List {
+		if watching(p) {
+			watched = append(watched, p)
+		}
+	}
+	return watched
+}
+
+// watchRows returns the number of rows of the watched section, including its
+// title below the processes, or 0 if no process is watched.
+func (ui *UI) watchRows() int {
+	n := len(watchedProcesses(ui.monitor))
+	if n == 0 {
+		return 0
+	}
+	if n > watchMaxRows {
+		n = watchMaxRows
+	}
+	return n + 1
+}
+
+// drawWatched draws the watched processes below the column titles, and a
+// title separating them from the rest of the table.
+func (ui *UI) drawWatched() {
+	watched := watchedProcesses(ui.monitor)
+	if len(watched) == 0 {
+		return
+	}
+	shown := watched
+	if len(shown) > watchMaxRows {
+		shown = shown[:watchMaxRows]
+	}
+
+	// The section has a row per process, and isn't highlighted like the
+	// selection.
+	wrap := ui.Wrap
+	ui.Wrap = false
+	for _, process := range shown {
+		ui.drawProcess(-1, process)
+	}
+	ui.Wrap = wrap
+
+	title := fmt.Sprintf(tr("%d watched processes - J: unwatch, Ctrl-W: watch commands"), len(watched))
+	if len(watched) > len(shown) {
+		title = fmt.Sprintf(tr("%d of %d watched processes - J: unwatch, Ctrl-W: watch commands"), len(shown), len(watched))
+	}
+	ui.x = 0
+	ui.fg, ui.bg = theme.TitleFG, theme.TitleBG
+	ui.writeLastColumn(title)
+	ui.y++
+}
+
+// watchedAt returns the watched process drawn on a row of the watched
+// section, or nil.
+func (ui *UI) watchedAt(row int) *proc.Process {
+	watched := watchedProcesses(ui.monitor)
+	if row < 0 || row >= len(watched) || row >= watchMaxRows {
+		return nil
+	}
+	return watched[row]
+}
+
+// HandleWatch watches the tagged processes, or the selected one, or unwatches
+// them if the first one is watched by PID.
+func (ui *UI) HandleWatch() {
+	targets := ui.targets()
+	if len(targets) == 0 {
+		return
+	}
+	unwatch := WatchPids[targets[0].Pid]
+	for _, process := range targets {
+		if unwatch {
+			delete(WatchPids, process.Pid)
+		} else {
+			WatchPids[process.Pid] = true
+		}
+	}
+	if !ui.saveWatched() {
+		return
+	}
+	if unwatch {
+		ui.setActionStatus(tr("Stopped watching"), targets, nil)
+	} else {
+		ui.setActionStatus(tr("Watching"), targets, nil)
+	}
+}
+
+// HandleWatchCommand prompts for a pattern of the command lines of processes
+// to watch, or stops watching them if it's already watched.
+func (ui *UI) HandleWatchCommand() {
+	ui.prompt = &prompt{label: tr("Watch commands matching: "), done: func(ui *UI, text string) {
+		if text == "" {
+			return
+		}
+		for i, re := range WatchCommands {
+			if re.String() == text {
+				WatchCommands = append(WatchCommands[:i], WatchCommands[i+1:]...)
+				if ui.saveWatched() {
+					ui.SetStatus(fmt.Sprintf(tr("Stopped watching the commands matching %q"), text))
+				}
+				return
+			}
+		}
+		re, err := regexp.Compile(text)
+		if err != nil {
+			ui.SetStatus(fmt.Sprintf(tr("Invalid pattern: %s"), err))
+			return
+		}
+		WatchCommands = append(WatchCommands, re)
+		if ui.saveWatched() {
+			ui.SetStatus(fmt.Sprintf(tr("Watching the commands matching %q"), text))
+		}
+	}}
+}
+
+// saveWatched saves the watched processes to the WatchFile, and sets the
+// status and returns false if it fails.
+func (ui *UI) saveWatched() bool {
+	if err := saveWatchFile(); err != nil {
+		ui.SetStatus(fmt.Sprintf(tr("The watched processes can't be saved: %s"), err))
+		return false
+	}
+	return true
+}
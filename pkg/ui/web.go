@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mayhewj/gtop/pkg/proc"
+)
+
+// WebServer serves a live view of the process table to browsers, which
+// receive every update over a WebSocket. Like the Exporter, it only serves
+// the updates rendered by Update, so browsers can't change what's
+// monitored.
+type WebServer struct {
+	// Command is what the COMMAND column displays.
+	Command CommandMode
+
+	mu      sync.Mutex
+	latest  []byte
+	clients map[chan []byte]bool
+}
+
+// webFrame is an update of the web view.
+type webFrame struct {
+	Header  []string
+	Columns []webColumn
+	Rows    [][]string
+}
+
+type webColumn struct {
+	Title      string
+	RightAlign bool
+}
+
+// webWriteTimeout is how long a browser has to receive an update before
+// it's disconnected.
+const webWriteTimeout = 10 * time.Second
+
+// NewWebServer returns a WebServer without any update to serve yet.
+func NewWebServer() *WebServer {
+	return &WebServer{clients: make(map[chan []byte]bool)}
+}
+
+// Update renders the header and the process table of the Monitor and sends
+// them to the connected browsers. It must be called from the goroutine that
+// updates the Monitor.
+func (s *WebServer) Update(m *proc.Monitor) {
+	var frame webFrame
+	for _, line := range headerLines(m) {
+		frame.Header = append(frame.Header, line.text)
+	}
+	for _, column := range Columns {
+		frame.Columns = append(frame.Columns, webColumn{column.Title, column.RightAlign})
+	}
+	for _, p := range processList(m) {
+		row := make([]string, len(Columns))
+		for i, column := range Columns {
+			if column != CommandColumn {
+				row[i] = column.Value(m, p)
+				continue
+			}
+			row[i] = s.Command.value(m, p)
+			if m.Tree || Aggregate != proc.Ungrouped {
+				row[i] = p.TreePrefix + row[i]
+			}
+		}
+		frame.Rows = append(frame.Rows, row)
+	}
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest = data
+	for client := range s.clients {
+		// A browser that hasn't received the previous update yet
+		// skips it.
+		select {
+		case <-client:
+		default:
+		}
+		client <- data
+	}
+}
+
+func (s *WebServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(webPage))
+	case "/ws":
+		s.serveWebsocket(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveWebsocket sends the latest update and then every following one to a
+// browser, until it disconnects.
+func (s *WebServer) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebsocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	client := make(chan []byte, 1)
+	s.mu.Lock()
+	if s.latest != nil {
+		client <- s.latest
+	}
+	s.clients[client] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		conn.waitClose()
+		close(closed)
+	}()
+	for {
+		select {
+		case data := <-client:
+			conn.conn.SetWriteDeadline(time.Now().Add(webWriteTimeout))
+			if err := conn.WriteText(data); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// webPage displays the updates of the WebServer. The values are set as
+// text rather than HTML, since the commands of the processes are arbitrary.
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>jtop</title>
+<style>
+body { background: #1c1c1c; color: #d0d0d0; font: 13px monospace; margin: 8px; }
+pre { margin: 0 0 8px; }
+table { border-collapse: collapse; }
+th { background: #5f875f; color: #000; padding: 0 4px; font-weight: normal; }
+td { padding: 0 4px; white-space: pre; }
+tr:hover td { background: #303030; }
+.right { text-align: right; }
+.left { text-align: left; }
+#status { color: #d75f5f; }
+</style>
+</head>
+<body>
+<pre id="header"></pre>
+<div id="status">Connecting...</div>
+<table><thead><tr id="titles"></tr></thead><tbody id="rows"></tbody></table>
+<script>
+function cell(tag, text, right) {
+	var element = document.createElement(tag);
+	element.textContent = text;
+	element.className = right ? "right" : "left";
+	return element;
+}
+
+function render(frame) {
+	document.getElementById("header").textContent = (frame.Header || []).join("\n");
+	var titles = document.getElementById("titles");
+	titles.replaceChildren();
+	frame.Columns.forEach(function(column) {
+		titles.appendChild(cell("th", column.Title, column.RightAlign));
+	});
+	var rows = document.getElementById("rows");
+	rows.replaceChildren();
+	(frame.Rows || []).forEach(function(values) {
+		var row = document.createElement("tr");
+		values.forEach(function(value, i) {
+			row.appendChild(cell("td", value, frame.Columns[i].RightAlign));
+		});
+		rows.appendChild(row);
+	});
+}
+
+function connect() {
+	var scheme = location.protocol == "https:" ? "wss://" : "ws://";
+	var socket = new WebSocket(scheme + location.host + "/ws");
+	var status = document.getElementById("status");
+	socket.onopen = function() { status.textContent = ""; };
+	socket.onmessage = function(event) { render(JSON.parse(event.data)); };
+	socket.onclose = function() {
+		status.textContent = "Disconnected, reconnecting...";
+		setTimeout(connect, 2000);
+	};
+}
+
+connect();
+</script>
+</body>
+</html>
+`